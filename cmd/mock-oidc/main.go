@@ -0,0 +1,36 @@
+// Command mock-oidc is a minimal OpenID Connect provider for trying out and
+// testing OIDC login locally. It signs every user in without asking, as the
+// email passed in login_hint or as -email.
+//
+//	go run ./cmd/mock-oidc -addr :9000 -client-id crypto-tracker
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/michaelwongycn/crypto-tracker/lib/oidc/oidctest"
+)
+
+var (
+	addr          = flag.String("addr", ":9000", "address to listen on")
+	issuer        = flag.String("issuer", "http://localhost:9000", "issuer URL, must match the oidc.issuer configured in the app")
+	clientId      = flag.String("client-id", "crypto-tracker", "client id the app is configured with")
+	email         = flag.String("email", "oidc-user@example.com", "email of the signed in user when no login_hint is given")
+	emailVerified = flag.Bool("email-verified", true, "value of the email_verified claim")
+)
+
+func main() {
+	flag.Parse()
+
+	p, err := oidctest.NewProvider(*issuer, *clientId)
+	if err != nil {
+		log.Fatalf("Error generating key: %v", err)
+	}
+	p.Email = *email
+	p.EmailVerified = *emailVerified
+
+	log.Printf("mock OIDC provider %s listening on %s", *issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, p))
+}
@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/domain/response"
+	"github.com/michaelwongycn/crypto-tracker/usecase/user"
+)
+
+const (
+	userNotFoundErrorMsg         = "User not found"
+	cannotModifySelfErrorMsg     = "You can't disable your own account"
+	unableToGetUserDataErrorMsg  = "Unable to get user data"
+	failedToUpdateUserErrorMsg   = "Failed to update user"
+	invalidPaginationErrorMsg    = "limit and offset must be numbers"
+	invalidLockoutKeyErrorMsg    = "key must be an email or IP address"
+	failedToClearLockoutErrorMsg = "Failed to clear login lockout"
+)
+
+func getUserIdParam(r *http.Request) (int, error) {
+	return strconv.Atoi(chi.URLParam(r, "userId"))
+}
+
+func (c *controllerImpl) AdminSearchUsers(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	query := r.URL.Query()
+	limit, offset := 0, 0
+	var err error
+	if query.Get("limit") != "" {
+		limit, err = strconv.Atoi(query.Get("limit"))
+	}
+	if err == nil && query.Get("offset") != "" {
+		offset, err = strconv.Atoi(query.Get("offset"))
+	}
+	if err != nil {
+		response.Message = invalidPaginationErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	users, err := c.userUsecase.SearchUsers(ctx, query.Get("q"), limit, offset)
+	if err != nil {
+		response.Message = unableToGetUserDataErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Message = ""
+	response.Data = toAdminUserResponses(*users)
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) AdminShowUser(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		response.Message = userNotFoundErrorMsg
+		setResponse(w, http.StatusNotFound, response)
+		return
+	}
+
+	user, err := c.userUsecase.GetUser(ctx, userId)
+	if err != nil {
+		setAdminErrorResponse(w, response, err, unableToGetUserDataErrorMsg)
+		return
+	}
+
+	response.Message = ""
+	response.Data = toAdminUserResponse(*user)
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) AdminShowUserAsset(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		response.Message = userNotFoundErrorMsg
+		setResponse(w, http.StatusNotFound, response)
+		return
+	}
+
+	_, err = c.userUsecase.GetUser(ctx, userId)
+	if err != nil {
+		setAdminErrorResponse(w, response, err, unableToGetUserDataErrorMsg)
+		return
+	}
+
+	assets, err := c.userUsecase.GetUserAssetsByUserId(ctx, userId, r.URL.Query().Get("currency"))
+	if err != nil {
+		setUserAssetErrorResponse(w, response, err)
+		return
+	}
+
+	response.Message = ""
+	response.Data = assets
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) AdminDisableUser(w http.ResponseWriter, r *http.Request) {
+	c.setUserDisabled(w, r, true)
+}
+
+func (c *controllerImpl) AdminEnableUser(w http.ResponseWriter, r *http.Request) {
+	c.setUserDisabled(w, r, false)
+}
+
+func (c *controllerImpl) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		response.Message = userNotFoundErrorMsg
+		setResponse(w, http.StatusNotFound, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	adminId := int(claims["sub"].(float64))
+	err = c.userUsecase.SetUserDisabled(ctx, adminId, userId, disabled, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		setAdminErrorResponse(w, response, err, failedToUpdateUserErrorMsg)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) AdminRevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		response.Message = userNotFoundErrorMsg
+		setResponse(w, http.StatusNotFound, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	adminId := int(claims["sub"].(float64))
+	err = c.userUsecase.ForceLogoutUser(ctx, adminId, userId, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		setAdminErrorResponse(w, response, err, failedToRevokeSessionErrorMsg)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) AdminClearLoginLockout(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	key, err := url.PathUnescape(chi.URLParam(r, "key"))
+	if err != nil || strings.TrimSpace(key) == "" {
+		response.Message = invalidLockoutKeyErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	adminId := int(claims["sub"].(float64))
+	err = c.userUsecase.AdminClearLoginLockout(ctx, adminId, strings.TrimSpace(key), r.UserAgent(), getClientIP(r))
+	if err != nil {
+		setAdminErrorResponse(w, response, err, failedToClearLockoutErrorMsg)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func setAdminErrorResponse(w http.ResponseWriter, response response.ReadResponse, err error, fallbackMsg string) {
+	if errors.Is(err, user.ErrUserNotFound) {
+		response.Message = userNotFoundErrorMsg
+		setResponse(w, http.StatusNotFound, response)
+		return
+	} else if errors.Is(err, user.ErrCannotModifySelf) {
+		response.Message = cannotModifySelfErrorMsg
+		setResponse(w, http.StatusConflict, response)
+		return
+	}
+	response.Message = fallbackMsg
+	setResponse(w, http.StatusInternalServerError, response)
+}
+
+func toAdminUserResponse(user model.User) response.AdminUserResponse {
+	return response.AdminUserResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
+		Disabled:      user.Disabled,
+		Currency:      user.Currency,
+	}
+}
+
+func toAdminUserResponses(users []model.User) []response.AdminUserResponse {
+	data := make([]response.AdminUserResponse, 0, len(users))
+	for _, user := range users {
+		data = append(data, toAdminUserResponse(user))
+	}
+	return data
+}
+
+func (c *controllerImpl) AdminShowProviders(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	response.Message = ""
+	response.Data = c.priceUsecase.GetProviderHealth(ctx)
+	setResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/domain/response"
+	"github.com/michaelwongycn/crypto-tracker/usecase/asset"
+)
+
+const (
+	emptySearchQueryErrorMsg     = "q is required"
+	invalidSearchLimitErrorMsg   = "limit must be a positive number"
+	unableToSearchAssetsErrorMsg = "Unable to search assets"
+	invalidAssetFieldsErrorMsg   = "fields must be a comma separated list of symbol, name, rank, price, change_percent_24h, market_cap, volume_24h, currency, source, price_updated_at or price_age"
+
+	assetIdField = "assetId"
+)
+
+// assetFields are the fields of a portfolio asset ?fields= can select from,
+// assetId is always included.
+var assetFields = []string{
+	"symbol",
+	"name",
+	"rank",
+	"price",
+	"change_percent_24h",
+	"market_cap",
+	"volume_24h",
+	"currency",
+	"source",
+	"price_updated_at",
+	"price_age",
+}
+
+func (c *controllerImpl) SearchAssets(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	query := r.URL.Query()
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			response.Message = invalidSearchLimitErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+			return
+		}
+	}
+
+	assets, err := c.assetUsecase.SearchAssets(ctx, query.Get("q"), limit)
+	if err != nil {
+		if errors.Is(err, asset.ErrEmptyQuery) {
+			response.Message = emptySearchQueryErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+		} else {
+			response.Message = unableToSearchAssetsErrorMsg
+			setResponse(w, http.StatusInternalServerError, response)
+		}
+		return
+	}
+
+	response.Message = ""
+	response.Data = assets
+	setResponse(w, http.StatusOK, response)
+}
+
+// parseAssetFields reads a comma separated list of asset fields. An empty list
+// selects every field and is returned as nil.
+func parseAssetFields(value string) ([]string, bool) {
+	if strings.TrimSpace(value) == "" {
+		return nil, true
+	}
+
+	fields := []string{}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == assetIdField {
+			continue
+		}
+		if !slices.Contains(assetFields, field) {
+			return nil, false
+		}
+		fields = append(fields, field)
+	}
+	return fields, true
+}
+
+// selectAssetFields keeps only the given fields of each asset and its id.
+func selectAssetFields(assets []model.Asset, fields []string) ([]map[string]json.RawMessage, error) {
+	data := make([]map[string]json.RawMessage, 0, len(assets))
+	for _, asset := range assets {
+		encoded, err := json.Marshal(asset)
+		if err != nil {
+			return nil, err
+		}
+
+		var all map[string]json.RawMessage
+		err = json.Unmarshal(encoded, &all)
+		if err != nil {
+			return nil, err
+		}
+
+		selected := make(map[string]json.RawMessage, len(fields)+1)
+		selected[assetIdField] = all[assetIdField]
+		for _, field := range fields {
+			selected[field] = all[field]
+		}
+		data = append(data, selected)
+	}
+	return data, nil
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/usecase/user"
+	"github.com/shopspring/decimal"
+)
+
+// fakeUserAssets returns the same portfolio for every user.
+type fakeUserAssets struct {
+	user.UserUsecase
+	assets []model.Asset
+}
+
+func (u *fakeUserAssets) GetUserAssetsByUserId(ctx context.Context, userId int, currency string) (*[]model.Asset, error) {
+	return &u.assets, nil
+}
+
+// showTestUserAssets requests the portfolio with query and returns the
+// status and the decoded assets.
+func showTestUserAssets(t *testing.T, query string) (int, []map[string]json.RawMessage) {
+	t.Helper()
+
+	c := &controllerImpl{userUsecase: &fakeUserAssets{assets: []model.Asset{
+		{AssetId: "bitcoin", Symbol: "BTC", Name: "Bitcoin", Rank: 1, Price: decimal.NewNullDecimal(decimal.NewFromInt(60000)), Currency: "united-states-dollar"},
+		{AssetId: "delisted", Currency: "united-states-dollar"},
+	}}}
+
+	r := httptest.NewRequest(http.MethodGet, "/crypto?"+query, nil)
+	r = r.WithContext(context.WithValue(r.Context(), "claims", jwt.MapClaims{"sub": float64(1)}))
+	w := httptest.NewRecorder()
+	c.ShowUserAsset(w, r)
+
+	var body struct {
+		Data []map[string]json.RawMessage `json:"data"`
+	}
+	err := json.NewDecoder(w.Body).Decode(&body)
+	if err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return w.Code, body.Data
+}
+
+func assetKeys(asset map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(asset))
+	for key := range asset {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestShowUserAssetSelectsFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantKeys []string
+	}{
+		{"selected fields", "fields=symbol,price", []string{"assetId", "price", "symbol"}},
+		{"spaces around fields", "fields=+rank+,+name", []string{"assetId", "name", "rank"}},
+		{"asking for assetId", "fields=assetId,source", []string{"assetId", "source"}},
+		{"only assetId", "fields=assetId", []string{"assetId"}},
+	}
+
+	for _, tt := range tests {
+		status, assets := showTestUserAssets(t, tt.query)
+		if status != http.StatusOK || len(assets) != 2 {
+			t.Fatalf("%s: got %d with %d assets, want 200 with 2", tt.name, status, len(assets))
+		}
+		for _, asset := range assets {
+			if keys := assetKeys(asset); !slices.Equal(keys, tt.wantKeys) {
+				t.Errorf("%s: asset fields = %v, want %v", tt.name, keys, tt.wantKeys)
+			}
+		}
+	}
+}
+
+func TestShowUserAssetReturnsNullForUnpricedAssets(t *testing.T) {
+	status, assets := showTestUserAssets(t, "fields=price,market_cap")
+	if status != http.StatusOK || len(assets) != 2 {
+		t.Fatalf("got %d with %d assets, want 200 with 2", status, len(assets))
+	}
+
+	if string(assets[0]["price"]) != `"60000"` {
+		t.Fatalf("bitcoin price = %s, want \"60000\"", assets[0]["price"])
+	}
+	for _, field := range []string{"price", "market_cap"} {
+		if string(assets[1][field]) != "null" {
+			t.Fatalf("unpriced asset %s = %s, want null", field, assets[1][field])
+		}
+	}
+}
+
+func TestShowUserAssetReturnsEveryFieldByDefault(t *testing.T) {
+	for _, query := range []string{"", "fields=", "fields=+"} {
+		status, assets := showTestUserAssets(t, query)
+		if status != http.StatusOK || len(assets) != 2 {
+			t.Fatalf("%q: got %d with %d assets, want 200 with 2", query, status, len(assets))
+		}
+
+		wantKeys := append([]string{assetIdField}, assetFields...)
+		slices.Sort(wantKeys)
+		if keys := assetKeys(assets[0]); !slices.Equal(keys, wantKeys) {
+			t.Fatalf("%q: asset fields = %v, want %v", query, keys, wantKeys)
+		}
+	}
+}
+
+func TestShowUserAssetRejectsUnknownFields(t *testing.T) {
+	for _, query := range []string{"fields=symbol,owner", "fields=Symbol", "fields=symbol,,price"} {
+		status, _ := showTestUserAssets(t, query)
+		if status != http.StatusBadRequest {
+			t.Errorf("%q: got %d, want 400", query, status)
+		}
+	}
+}
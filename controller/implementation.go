@@ -1,39 +1,101 @@
 package controller
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
 	"github.com/michaelwongycn/crypto-tracker/domain/request"
 	"github.com/michaelwongycn/crypto-tracker/domain/response"
 	"github.com/michaelwongycn/crypto-tracker/lib/auth"
+	"github.com/michaelwongycn/crypto-tracker/usecase/asset"
+	"github.com/michaelwongycn/crypto-tracker/usecase/price"
 	"github.com/michaelwongycn/crypto-tracker/usecase/user"
 )
 
 const (
+	oidcStateCookie = "oidc_state"
+
 	invalidCredentialsErrorMsg      = "Invalid Credentials"
 	passwordNotMatchErrorMsg        = "Password doesn't match"
 	emailAlreadyRegisteredErrorMsg  = "Email already registered"
 	unableToParseTokenErrorMsg      = "Unable to parse token"
 	assetNotFoundErrorMsg           = "Asset not found"
+	currencyNotFoundErrorMsg        = "Currency not found"
 	assetAlreadyRegisteredErrorMsg  = "Asset already registered"
+	ambiguousAssetErrorMsg          = "Asset is ambiguous, use one of the candidate asset ids"
 	unableToGetAssetDataErrorMsg    = "Unable to get asset data"
 	failedToAddUserToDBErrorMsg     = "Failed to add user to the database"
 	failedToAddAssetToDBErrorMsg    = "Failed to add asset to the database"
 	failedToDeleteAssetToDBErrorMsg = "Failed to delete asset from the database"
+	sessionNotFoundErrorMsg         = "Session not found"
+	unableToGetSessionDataErrorMsg  = "Unable to get session data"
+	failedToRevokeSessionErrorMsg   = "Failed to revoke session"
+	refreshTokenReusedErrorMsg      = "Refresh token reuse detected, please login again"
+	invalidMFACodeErrorMsg          = "Invalid two-factor authentication code"
+	invalidEmailErrorMsg            = "Invalid email address"
+	invalidOrExpiredTokenErrorMsg   = "Invalid or expired token"
+	failedToSendEmailErrorMsg       = "Failed to send email"
+	tooManyLoginAttemptsErrorMsg    = "Too many failed login attempts, try again later"
+	mfaAlreadyEnabledErrorMsg       = "Two-factor authentication already enabled"
+	mfaNotEnrolledErrorMsg          = "Two-factor authentication not enrolled"
+	apiKeyNotFoundErrorMsg          = "API key not found"
+	invalidAPIKeyNameErrorMsg       = "API key name is required"
+	invalidScopeErrorMsg            = "Invalid scope"
+	unableToGetAPIKeyDataErrorMsg   = "Unable to get API key data"
+	failedToCreateAPIKeyErrorMsg    = "Failed to create API key"
+	failedToRevokeAPIKeyErrorMsg    = "Failed to revoke API key"
+	oidcDisabledErrorMsg            = "OpenID Connect login is not configured"
+	oidcProviderUnavailableErrorMsg = "OpenID Connect provider unavailable"
+	invalidOIDCStateErrorMsg        = "Invalid or expired login request"
+	oidcLoginFailedErrorMsg         = "OpenID Connect login failed"
+	oidcEmailNotVerifiedErrorMsg    = "OpenID Connect provider did not return a verified email"
+	oidcAccountNotLinkableErrorMsg  = "An account with this email exists but its email is not verified, sign in with the password and verify it first"
+	accountDisabledErrorMsg         = "Account disabled"
 	internalServerErrorMsg          = "Internal Server Error"
 )
 
+var errMissingClaims = errors.New("missing claims")
+
 type controllerImpl struct {
-	userUsecase user.UserUsecase
+	userUsecase  user.UserUsecase
+	priceUsecase price.PriceUsecase
+	assetUsecase asset.AssetUsecase
 }
 
-func NewControllerImpl(userUsecase user.UserUsecase) Controller {
+func NewControllerImpl(userUsecase user.UserUsecase, priceUsecase price.PriceUsecase, assetUsecase asset.AssetUsecase) Controller {
 	return &controllerImpl{
-		userUsecase: userUsecase,
+		userUsecase:  userUsecase,
+		priceUsecase: priceUsecase,
+		assetUsecase: assetUsecase,
+	}
+}
+
+func getClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// getClaims returns the claims middleware.Authenticate resolved for r, which
+// may belong to a session or to an API key.
+func getClaims(r *http.Request) (jwt.MapClaims, error) {
+	claims, ok := r.Context().Value("claims").(jwt.MapClaims)
+	if !ok {
+		return nil, errMissingClaims
 	}
+	return claims, nil
 }
 
 func setResponse(w http.ResponseWriter, statusCode int, response interface{}) {
@@ -46,6 +108,46 @@ func (c *controllerImpl) Ping(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Pong!"))
 }
 
+// Health reports ok while every market data provider's circuit breaker is
+// closed, degraded while at least one still takes requests and down, with a
+// 503, when none does.
+func (c *controllerImpl) Health(w http.ResponseWriter, r *http.Request) {
+	providers := c.priceUsecase.GetProviderHealth(r.Context())
+
+	health := response.HealthResponse{
+		Status:    "ok",
+		Providers: make([]response.ProviderStatusResponse, 0, len(providers)),
+	}
+
+	available := 0
+	for _, provider := range providers {
+		health.Providers = append(health.Providers, response.ProviderStatusResponse{
+			Name:  provider.Name,
+			State: provider.State,
+		})
+		if provider.State != "closed" {
+			health.Status = "degraded"
+		}
+		if provider.Available {
+			available++
+		}
+	}
+
+	statusCode := http.StatusOK
+	if available == 0 {
+		health.Status = "down"
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	setResponse(w, statusCode, health)
+}
+
+func (c *controllerImpl) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	setResponse(w, http.StatusOK, auth.JWKS())
+}
+
 func (c *controllerImpl) Login(w http.ResponseWriter, r *http.Request) {
 	requestTime := time.Now().Format(time.RFC3339)
 	ctx := r.Context()
@@ -60,8 +162,25 @@ func (c *controllerImpl) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, refreshToken, err := c.userUsecase.Login(ctx, credentials.Email, credentials.Password)
+	accessToken, refreshToken, err := c.userUsecase.Login(ctx, credentials.Email, credentials.Password, r.UserAgent(), getClientIP(r))
 	if err != nil {
+		var mfaRequiredErr *user.MFARequiredError
+		if errors.As(err, &mfaRequiredErr) {
+			response.Message = ""
+			response.Data = pendingResponse(mfaRequiredErr.MFAToken)
+			setResponse(w, http.StatusOK, response)
+			return
+		}
+		var loginLockedErr *user.LoginLockedError
+		if errors.As(err, &loginLockedErr) {
+			setLoginLockedResponse(w, response, loginLockedErr)
+			return
+		}
+		if errors.Is(err, user.ErrAccountDisabled) {
+			response.Message = accountDisabledErrorMsg
+			setResponse(w, http.StatusForbidden, response)
+			return
+		}
 		response.Message = invalidCredentialsErrorMsg
 		setResponse(w, http.StatusOK, response)
 		return
@@ -75,6 +194,172 @@ func (c *controllerImpl) Login(w http.ResponseWriter, r *http.Request) {
 	setResponse(w, http.StatusOK, response)
 }
 
+func (c *controllerImpl) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserMFALoginRequest
+	authResponse := response.AuthResponse{}
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	accessToken, refreshToken, err := c.userUsecase.LoginMFA(ctx, credentials.MFAToken, credentials.Code, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		var loginLockedErr *user.LoginLockedError
+		if errors.As(err, &loginLockedErr) {
+			setLoginLockedResponse(w, response, loginLockedErr)
+			return
+		}
+		if errors.Is(err, user.ErrAccountDisabled) {
+			response.Message = accountDisabledErrorMsg
+			setResponse(w, http.StatusForbidden, response)
+			return
+		}
+		response.Message = invalidCredentialsErrorMsg
+		setResponse(w, http.StatusOK, response)
+		return
+	}
+
+	authResponse.AccessToken = *accessToken
+	authResponse.RefreshToken = *refreshToken
+
+	response.Message = ""
+	response.Data = authResponse
+	setResponse(w, http.StatusOK, response)
+}
+
+func setLoginLockedResponse(w http.ResponseWriter, response response.ReadResponse, err *user.LoginLockedError) {
+	retryAfter := int(math.Ceil(err.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	response.Message = tooManyLoginAttemptsErrorMsg
+	setResponse(w, http.StatusTooManyRequests, response)
+}
+
+func pendingResponse(mfaToken string) response.MFAPendingResponse {
+	return response.MFAPendingResponse{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+	}
+}
+
+func (c *controllerImpl) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.WriteResponse{}
+	response.Time = requestTime
+
+	authURL, state, err := c.userUsecase.StartOIDCLogin(ctx)
+	if err != nil {
+		if errors.Is(err, user.ErrOIDCDisabled) {
+			response.Message = oidcDisabledErrorMsg
+			setResponse(w, http.StatusNotFound, response)
+			return
+		} else if errors.Is(err, user.ErrOIDCLoginFailed) {
+			response.Message = oidcProviderUnavailableErrorMsg
+			setResponse(w, http.StatusBadGateway, response)
+			return
+		}
+		response.Message = internalServerErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	// Ties the callback to the browser that started the login, so nobody can
+	// get a victim signed in to the attacker's account with a crafted link.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    *state,
+		Path:     "/oidc",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, *authURL, http.StatusFound)
+}
+
+func (c *controllerImpl) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	authResponse := response.AuthResponse{}
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		response.Message = providerErr
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		response.Message = invalidOIDCStateErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	accessToken, refreshToken, err := c.userUsecase.LoginOIDC(ctx, state, query.Get("code"), r.UserAgent(), getClientIP(r))
+	if err != nil {
+		var mfaRequiredErr *user.MFARequiredError
+		if errors.As(err, &mfaRequiredErr) {
+			response.Message = ""
+			response.Data = pendingResponse(mfaRequiredErr.MFAToken)
+			setResponse(w, http.StatusOK, response)
+			return
+		} else if errors.Is(err, user.ErrOIDCDisabled) {
+			response.Message = oidcDisabledErrorMsg
+			setResponse(w, http.StatusNotFound, response)
+			return
+		} else if errors.Is(err, user.ErrInvalidOIDCState) {
+			response.Message = invalidOIDCStateErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+			return
+		} else if errors.Is(err, user.ErrOIDCLoginFailed) {
+			response.Message = oidcLoginFailedErrorMsg
+			setResponse(w, http.StatusUnauthorized, response)
+			return
+		} else if errors.Is(err, user.ErrOIDCEmailNotVerified) || errors.Is(err, user.ErrInvalidEmail) {
+			response.Message = oidcEmailNotVerifiedErrorMsg
+			setResponse(w, http.StatusForbidden, response)
+			return
+		} else if errors.Is(err, user.ErrAccountDisabled) {
+			response.Message = accountDisabledErrorMsg
+			setResponse(w, http.StatusForbidden, response)
+			return
+		} else if errors.Is(err, user.ErrOIDCAccountNotLinkable) {
+			response.Message = oidcAccountNotLinkableErrorMsg
+			setResponse(w, http.StatusConflict, response)
+			return
+		}
+		response.Message = internalServerErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	authResponse.AccessToken = *accessToken
+	authResponse.RefreshToken = *refreshToken
+
+	response.Message = ""
+	response.Data = authResponse
+	setResponse(w, http.StatusOK, response)
+}
+
 func (c *controllerImpl) Register(w http.ResponseWriter, r *http.Request) {
 	requestTime := time.Now().Format(time.RFC3339)
 	ctx := r.Context()
@@ -96,6 +381,11 @@ func (c *controllerImpl) Register(w http.ResponseWriter, r *http.Request) {
 
 	err := c.userUsecase.Register(ctx, credentials.Email, credentials.Password)
 	if err != nil {
+		if errors.Is(err, user.ErrInvalidEmail) {
+			response.Message = invalidEmailErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+			return
+		}
 		if strings.Contains(err.Error(), "UNIQUE constraint failed: users.email") {
 			response.Message = emailAlreadyRegisteredErrorMsg
 			setResponse(w, http.StatusConflict, response)
@@ -110,6 +400,118 @@ func (c *controllerImpl) Register(w http.ResponseWriter, r *http.Request) {
 	setResponse(w, http.StatusOK, response)
 }
 
+func (c *controllerImpl) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserEmailRequest
+	response := response.WriteResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	err := c.userUsecase.SendVerificationEmail(ctx, credentials.Email)
+	if err != nil {
+		response.Message = failedToSendEmailErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserTokenRequest
+	response := response.WriteResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	err := c.userUsecase.VerifyEmail(ctx, credentials.Token)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidActionToken) {
+			response.Message = invalidOrExpiredTokenErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+			return
+		}
+		response.Message = internalServerErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserEmailRequest
+	response := response.WriteResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	err := c.userUsecase.RequestPasswordReset(ctx, credentials.Email)
+	if err != nil {
+		response.Message = failedToSendEmailErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserResetPasswordRequest
+	response := response.WriteResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if credentials.Password != credentials.PasswordConfirmation {
+		response.Message = passwordNotMatchErrorMsg
+		setResponse(w, http.StatusOK, response)
+		return
+	}
+
+	err := c.userUsecase.ResetPassword(ctx, credentials.Token, credentials.Password)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidActionToken) {
+			response.Message = invalidOrExpiredTokenErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+			return
+		}
+		response.Message = internalServerErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
 func (c *controllerImpl) Logout(w http.ResponseWriter, r *http.Request) {
 	requestTime := time.Now().Format(time.RFC3339)
 	ctx := r.Context()
@@ -125,7 +527,8 @@ func (c *controllerImpl) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userId := int(claims["sub"].(float64))
-	err = c.userUsecase.Logout(ctx, accessToken, userId)
+	sessionId, _ := claims["sid"].(string)
+	err = c.userUsecase.Logout(ctx, accessToken, userId, sessionId)
 	if err != nil {
 		response.Message = internalServerErrorMsg
 		setResponse(w, http.StatusInternalServerError, response)
@@ -159,43 +562,165 @@ func (c *controllerImpl) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	claims, err = auth.ParseToken(credentials.RefreshToken)
+	newAccessToken, newRefreshToken, err := c.userUsecase.RefreshToken(ctx, credentials.RefreshToken, accessTokenUserId, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		if errors.Is(err, user.ErrRefreshTokenReused) {
+			response.Message = refreshTokenReusedErrorMsg
+			setResponse(w, http.StatusUnauthorized, response)
+			return
+		} else if errors.Is(err, user.ErrAccountDisabled) {
+			response.Message = accountDisabledErrorMsg
+			setResponse(w, http.StatusForbidden, response)
+			return
+		}
+		response.Message = invalidCredentialsErrorMsg
+		setResponse(w, http.StatusOK, response)
+		return
+	}
+
+	authResponse.AccessToken = *newAccessToken
+	authResponse.RefreshToken = *newRefreshToken
+
+	response.Message = ""
+	response.Data = authResponse
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) ShowAPIKeys(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	claims, err := getClaims(r)
 	if err != nil {
 		response.Message = unableToParseTokenErrorMsg
 		setResponse(w, http.StatusInternalServerError, response)
 		return
 	}
-	refreshTokenUserId := int(claims["sub"].(float64))
 
-	if refreshTokenUserId != accessTokenUserId {
-		response.Message = invalidCredentialsErrorMsg
-		setResponse(w, http.StatusOK, response)
+	userId := int(claims["sub"].(float64))
+
+	apiKeys, err := c.userUsecase.GetAPIKeys(ctx, userId)
+	if err != nil {
+		response.Message = unableToGetAPIKeyDataErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
 		return
 	}
 
-	newAccessToken, newRefreshToken, err := c.userUsecase.RefreshToken(ctx, credentials.RefreshToken, refreshTokenUserId)
+	response.Message = ""
+	response.Data = toAPIKeyResponses(*apiKeys)
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserCreateAPIKeyRequest
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	claims, err := getClaims(r)
 	if err != nil {
-		response.Message = invalidCredentialsErrorMsg
-		setResponse(w, http.StatusOK, response)
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
 		return
 	}
 
-	authResponse.AccessToken = *newAccessToken
-	authResponse.RefreshToken = *newRefreshToken
+	userId := int(claims["sub"].(float64))
+	apiKey, key, err := c.userUsecase.CreateAPIKey(ctx, userId, credentials.Name, credentials.Scopes, credentials.ExpiresInDays)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidAPIKeyName) {
+			response.Message = invalidAPIKeyNameErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+			return
+		} else if errors.Is(err, user.ErrInvalidScope) {
+			response.Message = invalidScopeErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+			return
+		}
+		response.Message = failedToCreateAPIKeyErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Message = ""
+	response.Data = toAPIKeyResponse(*apiKey, *key)
+	setResponse(w, http.StatusCreated, response)
+}
+
+func (c *controllerImpl) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.WriteResponse{}
+	response.Time = requestTime
+
+	apiKeyId, err := strconv.Atoi(chi.URLParam(r, "apiKeyId"))
+	if err != nil {
+		response.Message = apiKeyNotFoundErrorMsg
+		setResponse(w, http.StatusNotFound, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	err = c.userUsecase.RevokeAPIKey(ctx, userId, apiKeyId)
+	if err != nil {
+		if errors.Is(err, user.ErrAPIKeyNotFound) {
+			response.Message = apiKeyNotFoundErrorMsg
+			setResponse(w, http.StatusNotFound, response)
+			return
+		}
+		response.Message = failedToRevokeAPIKeyErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
 
 	response.Message = ""
-	response.Data = authResponse
 	setResponse(w, http.StatusOK, response)
 }
 
+func toAPIKeyResponses(apiKeys []model.UserAPIKey) []response.APIKeyResponse {
+	data := make([]response.APIKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		data = append(data, toAPIKeyResponse(apiKey, ""))
+	}
+	return data
+}
+
+// toAPIKeyResponse includes the plaintext key only when it was just created.
+func toAPIKeyResponse(apiKey model.UserAPIKey, key string) response.APIKeyResponse {
+	return response.APIKeyResponse{
+		ID:             apiKey.ID,
+		Name:           apiKey.Name,
+		Prefix:         apiKey.Prefix,
+		Key:            key,
+		Scopes:         apiKey.Scopes,
+		CreatedAt:      apiKey.CreatedAt,
+		ExpirationTime: apiKey.ExpirationTime,
+		LastUsedAt:     apiKey.LastUsedAt,
+	}
+}
+
 func (c *controllerImpl) ShowUserAsset(w http.ResponseWriter, r *http.Request) {
 	requestTime := time.Now().Format(time.RFC3339)
 	ctx := r.Context()
 	response := response.ReadResponse{}
 	response.Time = requestTime
 
-	accessToken := strings.Split(r.Header.Get("Authorization"), " ")[1]
-	claims, err := auth.ParseToken(accessToken)
+	claims, err := getClaims(r)
 	if err != nil {
 		response.Message = unableToParseTokenErrorMsg
 		setResponse(w, http.StatusInternalServerError, response)
@@ -203,24 +728,50 @@ func (c *controllerImpl) ShowUserAsset(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userId := int(claims["sub"].(float64))
+	query := r.URL.Query()
+
+	fields, ok := parseAssetFields(query.Get("fields"))
+	if !ok {
+		response.Message = invalidAssetFieldsErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
 
-	assets, err := c.userUsecase.GetUserAssetsByUserId(ctx, userId)
+	assets, err := c.userUsecase.GetUserAssetsByUserId(ctx, userId, query.Get("currency"))
 	if err != nil {
-		response.Message = unableToGetAssetDataErrorMsg
-		setResponse(w, http.StatusInternalServerError, response)
+		setUserAssetErrorResponse(w, response, err)
 		return
 	}
 
 	response.Message = ""
 	response.Data = assets
+	if fields != nil {
+		response.Data, err = selectAssetFields(*assets, fields)
+		if err != nil {
+			response.Message = unableToGetAssetDataErrorMsg
+			setResponse(w, http.StatusInternalServerError, response)
+			return
+		}
+	}
 	setResponse(w, http.StatusOK, response)
 }
 
+func setUserAssetErrorResponse(w http.ResponseWriter, response response.ReadResponse, err error) {
+	if errors.Is(err, user.ErrCurrencyNotFound) {
+		response.Message = currencyNotFoundErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	response.Message = unableToGetAssetDataErrorMsg
+	setResponse(w, http.StatusInternalServerError, response)
+}
+
 func (c *controllerImpl) InsertUserAsset(w http.ResponseWriter, r *http.Request) {
 	requestTime := time.Now().Format(time.RFC3339)
 	ctx := r.Context()
 	var credentials request.UserInsertAssetRequest
-	response := response.WriteResponse{}
+	response := response.ReadResponse{}
 	response.Time = requestTime
 
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
@@ -229,8 +780,7 @@ func (c *controllerImpl) InsertUserAsset(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	accessToken := strings.Split(r.Header.Get("Authorization"), " ")[1]
-	claims, err := auth.ParseToken(accessToken)
+	claims, err := getClaims(r)
 	if err != nil {
 		response.Message = unableToParseTokenErrorMsg
 		setResponse(w, http.StatusInternalServerError, response)
@@ -238,13 +788,19 @@ func (c *controllerImpl) InsertUserAsset(w http.ResponseWriter, r *http.Request)
 	}
 
 	userId := int(claims["sub"].(float64))
-	err = c.userUsecase.InsertUserAsset(ctx, userId, credentials.AssetID)
+	assetId, err := c.userUsecase.InsertUserAsset(ctx, userId, credentials.AssetID)
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed: user_assets.userId, user_assets.assetId") {
+		var ambiguous *user.AmbiguousAssetError
+		if errors.As(err, &ambiguous) {
+			response.Message = ambiguousAssetErrorMsg
+			response.Data = ambiguous.Candidates
+			setResponse(w, http.StatusConflict, response)
+			return
+		} else if strings.Contains(err.Error(), "UNIQUE constraint failed: user_assets.userId, user_assets.assetId") {
 			response.Message = assetAlreadyRegisteredErrorMsg
 			setResponse(w, http.StatusConflict, response)
 			return
-		} else if strings.Contains(err.Error(), "API request failed with status code: 404") {
+		} else if errors.Is(err, user.ErrAssetNotFound) {
 			response.Message = assetNotFoundErrorMsg
 			setResponse(w, http.StatusNotFound, response)
 			return
@@ -254,6 +810,7 @@ func (c *controllerImpl) InsertUserAsset(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	response.Message = ""
+	response.Data = toUserAssetResponse(*assetId)
 	setResponse(w, http.StatusOK, response)
 }
 
@@ -261,7 +818,7 @@ func (c *controllerImpl) DeleteUserAsset(w http.ResponseWriter, r *http.Request)
 	requestTime := time.Now().Format(time.RFC3339)
 	ctx := r.Context()
 	var credentials request.UserInsertAssetRequest
-	response := response.WriteResponse{}
+	response := response.ReadResponse{}
 	response.Time = requestTime
 
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
@@ -270,8 +827,7 @@ func (c *controllerImpl) DeleteUserAsset(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	accessToken := strings.Split(r.Header.Get("Authorization"), " ")[1]
-	claims, err := auth.ParseToken(accessToken)
+	claims, err := getClaims(r)
 	if err != nil {
 		response.Message = unableToParseTokenErrorMsg
 		setResponse(w, http.StatusInternalServerError, response)
@@ -281,7 +837,13 @@ func (c *controllerImpl) DeleteUserAsset(w http.ResponseWriter, r *http.Request)
 	userId := int(claims["sub"].(float64))
 	err = c.userUsecase.DeleteUserAsset(ctx, userId, credentials.AssetID)
 	if err != nil {
-		if strings.Contains(err.Error(), "API request failed with status code: 404") {
+		var ambiguous *user.AmbiguousAssetError
+		if errors.As(err, &ambiguous) {
+			response.Message = ambiguousAssetErrorMsg
+			response.Data = ambiguous.Candidates
+			setResponse(w, http.StatusConflict, response)
+			return
+		} else if errors.Is(err, user.ErrAssetNotFound) {
 			response.Message = assetNotFoundErrorMsg
 			setResponse(w, http.StatusNotFound, response)
 			return
@@ -294,3 +856,217 @@ func (c *controllerImpl) DeleteUserAsset(w http.ResponseWriter, r *http.Request)
 	response.Message = ""
 	setResponse(w, http.StatusOK, response)
 }
+
+func toUserAssetResponse(assetId string) response.UserAssetResponse {
+	return response.UserAssetResponse{AssetId: assetId}
+}
+
+func (c *controllerImpl) ShowUserSessions(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	sessionId, _ := claims["sid"].(string)
+
+	sessions, err := c.userUsecase.GetUserSessions(ctx, userId)
+	if err != nil {
+		response.Message = unableToGetSessionDataErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Message = ""
+	response.Data = toSessionResponses(*sessions, sessionId)
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) RevokeUserSession(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.WriteResponse{}
+	response.Time = requestTime
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	err = c.userUsecase.RevokeUserSession(ctx, userId, chi.URLParam(r, "sessionId"))
+	if err != nil {
+		if errors.Is(err, user.ErrSessionNotFound) {
+			response.Message = sessionNotFoundErrorMsg
+			setResponse(w, http.StatusNotFound, response)
+			return
+		}
+		response.Message = failedToRevokeSessionErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) RevokeAllUserSessions(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.WriteResponse{}
+	response.Time = requestTime
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	err = c.userUsecase.RevokeAllUserSessions(ctx, userId)
+	if err != nil {
+		response.Message = failedToRevokeSessionErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func toSessionResponses(sessions []model.UserSession, currentSessionId string) []response.SessionResponse {
+	data := make([]response.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		data = append(data, response.SessionResponse{
+			ID:             session.ID,
+			UserAgent:      session.UserAgent,
+			IPAddress:      session.IPAddress,
+			CreatedAt:      session.CreatedAt,
+			LastUsedAt:     session.LastUsedAt,
+			ExpirationTime: session.ExpirationTime,
+			Current:        session.ID == currentSessionId,
+		})
+	}
+	return data
+}
+
+func (c *controllerImpl) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	enrollment, err := c.userUsecase.EnrollMFA(ctx, userId)
+	if err != nil {
+		setMFAErrorResponse(w, response, err)
+		return
+	}
+
+	response.Message = ""
+	response.Data = enrollment
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserMFACodeRequest
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	recoveryCodes, err := c.userUsecase.ConfirmMFA(ctx, userId, credentials.Code)
+	if err != nil {
+		setMFAErrorResponse(w, response, err)
+		return
+	}
+
+	response.Message = ""
+	response.Data = recoveryCodesResponse(recoveryCodes)
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserMFACodeRequest
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	err = c.userUsecase.DisableMFA(ctx, userId, credentials.Code)
+	if err != nil {
+		setMFAErrorResponse(w, response, err)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func setMFAErrorResponse(w http.ResponseWriter, response response.ReadResponse, err error) {
+	switch {
+	case errors.Is(err, user.ErrInvalidMFACode):
+		response.Message = invalidMFACodeErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+	case errors.Is(err, user.ErrMFAAlreadyEnabled):
+		response.Message = mfaAlreadyEnabledErrorMsg
+		setResponse(w, http.StatusConflict, response)
+	case errors.Is(err, user.ErrMFANotEnrolled):
+		response.Message = mfaNotEnrolledErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+	default:
+		response.Message = internalServerErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+	}
+}
+
+func recoveryCodesResponse(recoveryCodes []string) response.RecoveryCodesResponse {
+	return response.RecoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	}
+}
@@ -4,12 +4,53 @@ import "net/http"
 
 type Controller interface {
 	Ping(w http.ResponseWriter, r *http.Request)
+	Health(w http.ResponseWriter, r *http.Request)
+	JWKS(w http.ResponseWriter, r *http.Request)
 	Login(w http.ResponseWriter, r *http.Request)
+	LoginMFA(w http.ResponseWriter, r *http.Request)
+	OIDCLogin(w http.ResponseWriter, r *http.Request)
+	OIDCCallback(w http.ResponseWriter, r *http.Request)
 	Register(w http.ResponseWriter, r *http.Request)
+	ResendVerificationEmail(w http.ResponseWriter, r *http.Request)
+	VerifyEmail(w http.ResponseWriter, r *http.Request)
+	ForgotPassword(w http.ResponseWriter, r *http.Request)
+	ResetPassword(w http.ResponseWriter, r *http.Request)
+	ConfirmEmailChange(w http.ResponseWriter, r *http.Request)
 	Logout(w http.ResponseWriter, r *http.Request)
 	RefreshToken(w http.ResponseWriter, r *http.Request)
 
+	ChangePassword(w http.ResponseWriter, r *http.Request)
+	ChangeEmail(w http.ResponseWriter, r *http.Request)
+	DeleteAccount(w http.ResponseWriter, r *http.Request)
+	ChangeCurrency(w http.ResponseWriter, r *http.Request)
+	ExportUserData(w http.ResponseWriter, r *http.Request)
+
+	ShowUserSessions(w http.ResponseWriter, r *http.Request)
+	RevokeUserSession(w http.ResponseWriter, r *http.Request)
+	RevokeAllUserSessions(w http.ResponseWriter, r *http.Request)
+
+	EnrollMFA(w http.ResponseWriter, r *http.Request)
+	ConfirmMFA(w http.ResponseWriter, r *http.Request)
+	DisableMFA(w http.ResponseWriter, r *http.Request)
+
+	ShowAPIKeys(w http.ResponseWriter, r *http.Request)
+	CreateAPIKey(w http.ResponseWriter, r *http.Request)
+	RevokeAPIKey(w http.ResponseWriter, r *http.Request)
+
 	ShowUserAsset(w http.ResponseWriter, r *http.Request)
 	InsertUserAsset(w http.ResponseWriter, r *http.Request)
 	DeleteUserAsset(w http.ResponseWriter, r *http.Request)
+	ShowAssetPriceHistory(w http.ResponseWriter, r *http.Request)
+	ShowAssetPriceCandles(w http.ResponseWriter, r *http.Request)
+	ShowCurrencies(w http.ResponseWriter, r *http.Request)
+	SearchAssets(w http.ResponseWriter, r *http.Request)
+
+	AdminSearchUsers(w http.ResponseWriter, r *http.Request)
+	AdminShowUser(w http.ResponseWriter, r *http.Request)
+	AdminShowUserAsset(w http.ResponseWriter, r *http.Request)
+	AdminDisableUser(w http.ResponseWriter, r *http.Request)
+	AdminEnableUser(w http.ResponseWriter, r *http.Request)
+	AdminRevokeUserSessions(w http.ResponseWriter, r *http.Request)
+	AdminClearLoginLockout(w http.ResponseWriter, r *http.Request)
+	AdminShowProviders(w http.ResponseWriter, r *http.Request)
 }
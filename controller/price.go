@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/domain/response"
+	"github.com/michaelwongycn/crypto-tracker/usecase/price"
+)
+
+const (
+	invalidTimeErrorMsg             = "from and to must be unix timestamps or RFC 3339 times"
+	invalidTimeRangeErrorMsg        = "from must be before to and the range must not have more than 2000 intervals"
+	invalidIntervalErrorMsg         = "interval must be one of 1m, 5m, 15m, 1h, 4h or 1d"
+	unableToGetPriceHistoryErrorMsg = "Unable to get price history"
+	invalidCandleIntervalErrorMsg   = "interval must be one of 1m, 5m, 1h or 1d"
+	invalidFormatErrorMsg           = "format must be json or csv"
+	unableToGetCandlesErrorMsg      = "Unable to get price candles"
+	invalidCurrencyTypeErrorMsg     = "type must be fiat or crypto"
+	unableToGetCurrenciesErrorMsg   = "Unable to get currencies"
+)
+
+// parseTimeParam accepts a unix timestamp in seconds or an RFC 3339 time. An
+// empty value gives the zero time.
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func (c *controllerImpl) ShowAssetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	query := r.URL.Query()
+	from, err := parseTimeParam(query.Get("from"))
+	if err != nil {
+		response.Message = invalidTimeErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	to, err := parseTimeParam(query.Get("to"))
+	if err != nil {
+		response.Message = invalidTimeErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	history, err := c.priceUsecase.GetPriceHistory(ctx, userId, chi.URLParam(r, "assetId"), from, to, query.Get("interval"), query.Get("currency"))
+	if err != nil {
+		if errors.Is(err, price.ErrInvalidInterval) {
+			response.Message = invalidIntervalErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+		} else if errors.Is(err, price.ErrInvalidTimeRange) {
+			response.Message = invalidTimeRangeErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+		} else if errors.Is(err, price.ErrCurrencyNotFound) {
+			response.Message = currencyNotFoundErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+		} else {
+			response.Message = unableToGetPriceHistoryErrorMsg
+			setResponse(w, http.StatusInternalServerError, response)
+		}
+		return
+	}
+
+	response.Message = ""
+	response.Data = history
+	setResponse(w, http.StatusOK, response)
+}
+
+// ShowAssetPriceCandles returns OHLC candles as JSON, or as a CSV file when
+// format=csv is given or the Accept header asks for text/csv.
+func (c *controllerImpl) ShowAssetPriceCandles(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		format = "csv"
+	}
+	if format != "" && format != "json" && format != "csv" {
+		response.Message = invalidFormatErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	from, err := parseTimeParam(query.Get("from"))
+	if err != nil {
+		response.Message = invalidTimeErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	to, err := parseTimeParam(query.Get("to"))
+	if err != nil {
+		response.Message = invalidTimeErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	candles, err := c.priceUsecase.GetPriceCandles(ctx, userId, chi.URLParam(r, "assetId"), from, to, query.Get("interval"), query.Get("currency"))
+	if err != nil {
+		if errors.Is(err, price.ErrInvalidInterval) {
+			response.Message = invalidCandleIntervalErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+		} else if errors.Is(err, price.ErrInvalidTimeRange) {
+			response.Message = invalidTimeRangeErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+		} else if errors.Is(err, price.ErrCurrencyNotFound) {
+			response.Message = currencyNotFoundErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+		} else {
+			response.Message = unableToGetCandlesErrorMsg
+			setResponse(w, http.StatusInternalServerError, response)
+		}
+		return
+	}
+
+	if format == "csv" {
+		data, err := candlesCSV(candles)
+		if err != nil {
+			response.Message = unableToGetCandlesErrorMsg
+			setResponse(w, http.StatusInternalServerError, response)
+			return
+		}
+
+		filename := fmt.Sprintf("%s-%s-%s.csv", candles.AssetId, candles.Currency, candles.Interval)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+		return
+	}
+
+	response.Message = ""
+	response.Data = candles
+	setResponse(w, http.StatusOK, response)
+}
+
+func candlesCSV(series *model.PriceCandleSeries) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	writer.Write([]string{"open_time", "close_time", "open", "high", "low", "close", "volume", "ticks", "complete"})
+	for _, candle := range series.Candles {
+		volume := ""
+		if candle.Volume.Valid {
+			volume = candle.Volume.Decimal.String()
+		}
+
+		writer.Write([]string{
+			strconv.FormatInt(candle.OpenTime, 10),
+			strconv.FormatInt(candle.CloseTime, 10),
+			candle.Open.String(),
+			candle.High.String(),
+			candle.Low.String(),
+			candle.Close.String(),
+			volume,
+			strconv.Itoa(candle.Ticks),
+			strconv.FormatBool(candle.Complete),
+		})
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+func (c *controllerImpl) ShowCurrencies(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	currencies, err := c.priceUsecase.GetCurrencies(ctx, r.URL.Query().Get("type"))
+	if err != nil {
+		if errors.Is(err, price.ErrInvalidCurrencyType) {
+			response.Message = invalidCurrencyTypeErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+		} else {
+			response.Message = unableToGetCurrenciesErrorMsg
+			setResponse(w, http.StatusInternalServerError, response)
+		}
+		return
+	}
+
+	response.Message = ""
+	response.Data = currencies
+	setResponse(w, http.StatusOK, response)
+}
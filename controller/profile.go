@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/request"
+	"github.com/michaelwongycn/crypto-tracker/domain/response"
+	"github.com/michaelwongycn/crypto-tracker/usecase/user"
+)
+
+const (
+	invalidPasswordErrorMsg       = "Invalid password"
+	failedToUpdateAccountErrorMsg = "Failed to update account"
+	failedToDeleteAccountErrorMsg = "Failed to delete account"
+	failedToExportDataErrorMsg    = "Failed to export data"
+)
+
+func (c *controllerImpl) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserChangePasswordRequest
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if credentials.Password != credentials.PasswordConfirmation {
+		response.Message = passwordNotMatchErrorMsg
+		setResponse(w, http.StatusOK, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	sessionId, _ := claims["sid"].(string)
+
+	err = c.userUsecase.ChangePassword(ctx, userId, sessionId, credentials.CurrentPassword, credentials.Password, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		setAccountErrorResponse(w, response, err, failedToUpdateAccountErrorMsg)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserChangeEmailRequest
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	err = c.userUsecase.RequestEmailChange(ctx, userId, credentials.Password, credentials.Email, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		setAccountErrorResponse(w, response, err, failedToSendEmailErrorMsg)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserTokenRequest
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	err := c.userUsecase.ConfirmEmailChange(ctx, credentials.Token)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidActionToken) {
+			response.Message = invalidOrExpiredTokenErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+			return
+		}
+		setAccountErrorResponse(w, response, err, failedToUpdateAccountErrorMsg)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserPasswordRequest
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	err = c.userUsecase.DeleteAccount(ctx, userId, credentials.Password, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		setAccountErrorResponse(w, response, err, failedToDeleteAccountErrorMsg)
+		return
+	}
+
+	response.Message = ""
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) ChangeCurrency(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	var credentials request.UserCurrencyRequest
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Message = err.Error()
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	currency, err := c.userUsecase.SetUserCurrency(ctx, userId, strings.TrimSpace(credentials.Currency))
+	if err != nil {
+		if errors.Is(err, user.ErrCurrencyNotFound) {
+			response.Message = currencyNotFoundErrorMsg
+			setResponse(w, http.StatusBadRequest, response)
+			return
+		}
+		response.Message = failedToUpdateAccountErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Message = ""
+	response.Data = toUserCurrencyResponse(*currency)
+	setResponse(w, http.StatusOK, response)
+}
+
+func (c *controllerImpl) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	requestTime := time.Now().Format(time.RFC3339)
+	ctx := r.Context()
+	response := response.ReadResponse{}
+	response.Time = requestTime
+
+	claims, err := getClaims(r)
+	if err != nil {
+		response.Message = unableToParseTokenErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	userId := int(claims["sub"].(float64))
+	archive, err := c.userUsecase.ExportUserData(ctx, userId, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		response.Message = failedToExportDataErrorMsg
+		setResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	filename := fmt.Sprintf("crypto-tracker-export-%s.zip", time.Now().UTC().Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(*archive)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(*archive)
+}
+
+func setAccountErrorResponse(w http.ResponseWriter, response response.ReadResponse, err error, fallbackMsg string) {
+	var loginLockedErr *user.LoginLockedError
+	if errors.As(err, &loginLockedErr) {
+		setLoginLockedResponse(w, response, loginLockedErr)
+		return
+	} else if errors.Is(err, user.ErrInvalidCredentials) {
+		response.Message = invalidPasswordErrorMsg
+		setResponse(w, http.StatusForbidden, response)
+		return
+	} else if errors.Is(err, user.ErrInvalidEmail) {
+		response.Message = invalidEmailErrorMsg
+		setResponse(w, http.StatusBadRequest, response)
+		return
+	} else if errors.Is(err, user.ErrEmailAlreadyRegistered) || strings.Contains(err.Error(), "UNIQUE constraint failed: users.email") {
+		response.Message = emailAlreadyRegisteredErrorMsg
+		setResponse(w, http.StatusConflict, response)
+		return
+	}
+	response.Message = fallbackMsg
+	setResponse(w, http.StatusInternalServerError, response)
+}
+
+func toUserCurrencyResponse(currency string) response.UserCurrencyResponse {
+	return response.UserCurrencyResponse{Currency: currency}
+}
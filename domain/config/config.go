@@ -3,10 +3,20 @@ package config
 import "time"
 
 type ApplicationConfig struct {
-	Port     PortConfig     `json:"port"`
-	Database DatabaseConfig `json:"database"`
-	Rest     RestConfig     `json:"rest"`
-	JWT      JWTConfig      `json:"jwt"`
+	Port       PortConfig       `json:"port"`
+	Database   DatabaseConfig   `json:"database"`
+	Rest       RestConfig       `json:"rest"`
+	JWT        JWTConfig        `json:"jwt"`
+	Password   PasswordConfig   `json:"password"`
+	Mailer     MailerConfig     `json:"mailer"`
+	Account    AccountConfig    `json:"account"`
+	Login      LoginConfig      `json:"login"`
+	OIDC       OIDCConfig       `json:"oidc"`
+	TokenStore TokenStoreConfig `json:"token_store"`
+	Cache      CacheConfig      `json:"cache"`
+	Price      PriceConfig      `json:"price"`
+	Catalog    CatalogConfig    `json:"catalog"`
+	Money      MoneyConfig      `json:"money"`
 }
 
 type PortConfig struct {
@@ -21,7 +31,21 @@ type DatabaseConfig struct {
 }
 
 type RestConfig struct {
-	Coincap CoincapConfig `json:"coincap"`
+	Providers         []string        `json:"providers"`
+	Timeout           time.Duration   `json:"timeout"`
+	Retry             RetryConfig     `json:"retry"`
+	FailureThreshold  int             `json:"failure_threshold"`
+	Cooldown          time.Duration   `json:"cooldown"`
+	RateCacheDuration time.Duration   `json:"rate_cache_duration"`
+	Coincap           CoincapConfig   `json:"coincap"`
+	CoinGecko         CoinGeckoConfig `json:"coingecko"`
+	Binance           BinanceConfig   `json:"binance"`
+}
+
+type RetryConfig struct {
+	MaxAttempts int           `json:"max_attempts"`
+	BaseDelay   time.Duration `json:"base_delay"`
+	MaxDelay    time.Duration `json:"max_delay"`
 }
 
 type CoincapConfig struct {
@@ -31,8 +55,134 @@ type CoincapConfig struct {
 	TargetCurrency string `json:"target_currency"`
 }
 
+type CoinGeckoConfig struct {
+	BaseURL        string            `json:"base_url"`
+	APIKey         string            `json:"api_key"`
+	TargetCurrency string            `json:"target_currency"`
+	AssetIds       map[string]string `json:"asset_ids"`
+	CurrencyIds    map[string]string `json:"currency_ids"`
+}
+
+type BinanceConfig struct {
+	BaseURL    string            `json:"base_url"`
+	QuoteAsset string            `json:"quote_asset"`
+	Symbols    map[string]string `json:"symbols"`
+}
+
 type JWTConfig struct {
-	AccessTokenDuration  time.Duration `json:"access_token_duration"`
-	RefreshTokenDuration time.Duration `json:"refresh_token_duration"`
-	SecretKey            string        `json:"secret_key"`
+	AccessTokenDuration  time.Duration  `json:"access_token_duration"`
+	RefreshTokenDuration time.Duration  `json:"refresh_token_duration"`
+	SecretKey            string         `json:"secret_key"`
+	SigningKeyID         string         `json:"signing_key_id"`
+	AcceptSecretKey      bool           `json:"accept_secret_key"`
+	Keys                 []JWTKeyConfig `json:"keys"`
+}
+
+type JWTKeyConfig struct {
+	ID             string `json:"id"`
+	Algorithm      string `json:"algorithm"`
+	PrivateKeyFile string `json:"private_key_file"`
+	PublicKeyFile  string `json:"public_key_file"`
+}
+
+type PasswordConfig struct {
+	Algorithm string       `json:"algorithm"`
+	Argon2    Argon2Config `json:"argon2"`
+	Bcrypt    BcryptConfig `json:"bcrypt"`
+}
+
+type Argon2Config struct {
+	Memory      uint32 `json:"memory"`
+	Iterations  uint32 `json:"iterations"`
+	Parallelism uint8  `json:"parallelism"`
+	SaltLength  uint32 `json:"salt_length"`
+	KeyLength   uint32 `json:"key_length"`
+}
+
+type BcryptConfig struct {
+	Cost int `json:"cost"`
+}
+
+type MailerConfig struct {
+	Driver    string     `json:"driver"`
+	From      string     `json:"from"`
+	Directory string     `json:"directory"`
+	SMTP      SMTPConfig `json:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type AccountConfig struct {
+	BaseURL                    string        `json:"base_url"`
+	VerifyEmailTokenDuration   time.Duration `json:"verify_email_token_duration"`
+	ResetPasswordTokenDuration time.Duration `json:"reset_password_token_duration"`
+}
+
+type LoginConfig struct {
+	MaxAccountFailures int           `json:"max_account_failures"`
+	MaxIPFailures      int           `json:"max_ip_failures"`
+	FailureWindow      time.Duration `json:"failure_window"`
+	BackoffBase        time.Duration `json:"backoff_base"`
+	BackoffMax         time.Duration `json:"backoff_max"`
+	LockoutDuration    time.Duration `json:"lockout_duration"`
+}
+
+type OIDCConfig struct {
+	Issuer        string        `json:"issuer"`
+	ClientID      string        `json:"client_id"`
+	ClientSecret  string        `json:"client_secret"`
+	RedirectURL   string        `json:"redirect_url"`
+	Scopes        []string      `json:"scopes"`
+	StateDuration time.Duration `json:"state_duration"`
+}
+
+type TokenStoreConfig struct {
+	CacheSize     int           `json:"cache_size"`
+	CacheDuration time.Duration `json:"cache_duration"`
+}
+
+type CacheConfig struct {
+	Driver string      `json:"driver"`
+	Redis  RedisConfig `json:"redis"`
+}
+
+type RedisConfig struct {
+	Address  string        `json:"address"`
+	Username string        `json:"username"`
+	Password string        `json:"password"`
+	DB       int           `json:"db"`
+	PoolSize int           `json:"pool_size"`
+	Timeout  time.Duration `json:"timeout"`
+}
+
+type PriceConfig struct {
+	PollInterval time.Duration      `json:"poll_interval"`
+	MaxAge       time.Duration      `json:"max_age"`
+	History      PriceHistoryConfig `json:"history"`
+}
+
+type PriceHistoryConfig struct {
+	RawRetentionDays    int `json:"raw_retention_days"`
+	HourlyRetentionDays int `json:"hourly_retention_days"`
+	DailyRetentionDays  int `json:"daily_retention_days"`
+}
+
+type CatalogConfig struct {
+	SyncInterval time.Duration `json:"sync_interval"`
+	MaxAssets    int           `json:"max_assets"`
+}
+
+type MoneyConfig struct {
+	Default    CurrencyConfig            `json:"default"`
+	Currencies map[string]CurrencyConfig `json:"currencies"`
+}
+
+type CurrencyConfig struct {
+	Precision *int32 `json:"precision"`
+	Rounding  string `json:"rounding"`
 }
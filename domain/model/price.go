@@ -0,0 +1,72 @@
+package model
+
+import "github.com/shopspring/decimal"
+
+// PriceHistory is a stored USD price of an asset.
+// Resolution is the number of seconds the price stands for, 0 for a price
+// recorded as it was fetched and more for an average over a downsampled or
+// backfilled period starting at Timestamp.
+type PriceHistory struct {
+	AssetId    string          `json:"assetId"`
+	Timestamp  int64           `json:"timestamp"`
+	Price      decimal.Decimal `json:"price"`
+	Resolution int64           `json:"resolution"`
+}
+
+type PriceHistorySeries struct {
+	AssetId  string       `json:"assetId"`
+	Currency string       `json:"currency"`
+	Interval string       `json:"interval"`
+	From     int64        `json:"from"`
+	To       int64        `json:"to"`
+	Points   []PricePoint `json:"points"`
+}
+
+type PricePoint struct {
+	Timestamp int64           `json:"timestamp"`
+	Price     decimal.Decimal `json:"price"`
+}
+
+type VolumePoint struct {
+	Timestamp int64           `json:"timestamp"`
+	Volume    decimal.Decimal `json:"volume"`
+}
+
+// PriceCandle is the open, high, low and close price of an asset in a
+// currency over the Resolution seconds starting at OpenTime, built from Ticks
+// stored prices. Volume is the value traded over the period, when a provider
+// has it.
+type PriceCandle struct {
+	AssetId    string              `json:"-"`
+	Currency   string              `json:"-"`
+	Resolution int64               `json:"-"`
+	OpenTime   int64               `json:"open_time"`
+	CloseTime  int64               `json:"close_time"`
+	Open       decimal.Decimal     `json:"open"`
+	High       decimal.Decimal     `json:"high"`
+	Low        decimal.Decimal     `json:"low"`
+	Close      decimal.Decimal     `json:"close"`
+	Volume     decimal.NullDecimal `json:"volume"`
+	Ticks      int                 `json:"ticks"`
+	Complete   bool                `json:"complete"`
+}
+
+type PriceCandleSeries struct {
+	AssetId  string        `json:"assetId"`
+	Currency string        `json:"currency"`
+	Interval string        `json:"interval"`
+	From     int64         `json:"from"`
+	To       int64         `json:"to"`
+	Candles  []PriceCandle `json:"candles"`
+}
+
+// CurrencyRate is a currency prices can be quoted in, with the USD value of
+// one unit of it. Currencies are named by Coincap's ids, such as
+// indonesian-rupiah, and Type is fiat or crypto.
+type CurrencyRate struct {
+	Id             string          `json:"id"`
+	Symbol         string          `json:"symbol"`
+	CurrencySymbol string          `json:"currency_symbol"`
+	Type           string          `json:"type"`
+	RateUSD        decimal.Decimal `json:"rate_usd"`
+}
@@ -0,0 +1,31 @@
+package model
+
+// AssetInfo is what a market data provider knows about an asset, under our
+// asset id. Rank is its market cap rank, 0 when unknown.
+type AssetInfo struct {
+	AssetId string `json:"assetId"`
+	Symbol  string `json:"symbol"`
+	Name    string `json:"name"`
+	Rank    int    `json:"rank"`
+	Logo    string `json:"logo"`
+}
+
+// ProviderHealth is the circuit breaker state of a market data provider and
+// its request counts since startup. An open provider is skipped until
+// UnavailableUntil, then a single request is let through while it is
+// half_open.
+type ProviderHealth struct {
+	Name                string `json:"name"`
+	Priority            int    `json:"priority"`
+	State               string `json:"state"`
+	Available           bool   `json:"available"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	LastError           string `json:"last_error,omitempty"`
+	LastSuccessAt       int64  `json:"last_success_at"`
+	LastFailureAt       int64  `json:"last_failure_at"`
+	UnavailableUntil    int64  `json:"unavailable_until"`
+	Requests            int64  `json:"requests"`
+	Failures            int64  `json:"failures"`
+	Retries             int64  `json:"retries"`
+	Rejected            int64  `json:"rejected"`
+}
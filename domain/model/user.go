@@ -1,25 +1,132 @@
 package model
 
+import "github.com/shopspring/decimal"
+
 type User struct {
-	ID       int    `json:"id"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	ID            int    `json:"id"`
+	Email         string `json:"email"`
+	Password      string `json:"password"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	Disabled      bool   `json:"disabled"`
+	Currency      string `json:"currency"`
+}
+
+type UserSession struct {
+	ID               string `json:"id"`
+	UserId           int    `json:"userId"`
+	AccessTokenHash  string `json:"access_token_hash"`
+	RefreshTokenHash string `json:"refresh_token_hash"`
+	UserAgent        string `json:"user_agent"`
+	IPAddress        string `json:"ip_address"`
+	CreatedAt        int64  `json:"created_at"`
+	LastUsedAt       int64  `json:"last_used_at"`
+	ExpirationTime   int64  `json:"expiration_time"`
+}
+
+type RefreshToken struct {
+	ID             string `json:"id"`
+	FamilyId       string `json:"family_id"`
+	UserId         int    `json:"userId"`
+	IssuedAt       int64  `json:"issued_at"`
+	ExpirationTime int64  `json:"expiration_time"`
+	RotatedAt      int64  `json:"rotated_at"`
+	RevokedAt      int64  `json:"revoked_at"`
+}
+
+type AuditEvent struct {
+	ID        int    `json:"id"`
+	UserId    int    `json:"userId"`
+	Event     string `json:"event"`
+	Detail    string `json:"detail"`
+	UserAgent string `json:"user_agent"`
+	IPAddress string `json:"ip_address"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type UserMFA struct {
+	UserId       int    `json:"userId"`
+	Secret       string `json:"secret"`
+	Enabled      bool   `json:"enabled"`
+	LastUsedStep int64  `json:"last_used_step"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
 }
 
-type UserToken struct {
+type UserActionToken struct {
+	ID             string `json:"id"`
 	UserId         int    `json:"userId"`
-	AccessToken    string `json:"access_token"`
-	RefreshToken   string `json:"refresh_token"`
+	Purpose        string `json:"purpose"`
+	ExpirationTime int64  `json:"expiration_time"`
+	UsedAt         int64  `json:"used_at"`
+}
+
+type LoginAttempt struct {
+	Key           string `json:"key"`
+	Failures      int    `json:"failures"`
+	LastFailureAt int64  `json:"last_failure_at"`
+	LockedUntil   int64  `json:"locked_until"`
+}
+
+type UserAPIKey struct {
+	ID             int      `json:"id"`
+	UserId         int      `json:"userId"`
+	Name           string   `json:"name"`
+	Prefix         string   `json:"prefix"`
+	KeyHash        string   `json:"key_hash"`
+	Scopes         []string `json:"scopes"`
+	CreatedAt      int64    `json:"created_at"`
+	ExpirationTime int64    `json:"expiration_time"`
+	LastUsedAt     int64    `json:"last_used_at"`
+	RevokedAt      int64    `json:"revoked_at"`
+}
+
+type UserIdentity struct {
+	ID        int    `json:"id"`
+	UserId    int    `json:"userId"`
+	Issuer    string `json:"issuer"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type OIDCState struct {
+	State          string `json:"state"`
+	Nonce          string `json:"nonce"`
+	CodeVerifier   string `json:"code_verifier"`
 	ExpirationTime int64  `json:"expiration_time"`
 }
 
+// UserDataFile is one file of a personal data export, Data is written out as
+// JSON.
+type UserDataFile struct {
+	Name string      `json:"name"`
+	Data interface{} `json:"data"`
+}
+
 type UserAsset struct {
 	ID      int    `json:"id"`
 	UserId  int    `json:"userId"`
 	AssetId string `json:"assetId"`
 }
 
+// Asset is a tracked asset with its market data from Source. The 24h change,
+// market cap and volume are null when the source doesn't have them.
 type Asset struct {
-	AssetId string  `json:"assetId"`
-	Price   float64 `json:"price"`
+	AssetId           string              `json:"assetId"`
+	Symbol            string              `json:"symbol"`
+	Name              string              `json:"name"`
+	Rank              int                 `json:"rank"`
+	Price             decimal.NullDecimal `json:"price"`
+	ChangePercent24Hr decimal.NullDecimal `json:"change_percent_24h"`
+	MarketCap         decimal.NullDecimal `json:"market_cap"`
+	Volume24Hr        decimal.NullDecimal `json:"volume_24h"`
+	Currency          string              `json:"currency"`
+	Source            string              `json:"source"`
+	PriceUpdatedAt    int64               `json:"price_updated_at"`
+	PriceAge          int64               `json:"price_age"`
 }
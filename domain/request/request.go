@@ -11,10 +11,58 @@ type UserAuthRequest struct {
 	Password string `json:"password"`
 }
 
+type UserEmailRequest struct {
+	Email string `json:"email"`
+}
+
+type UserTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type UserResetPasswordRequest struct {
+	Token                string `json:"token"`
+	Password             string `json:"password"`
+	PasswordConfirmation string `json:"passwordConfirmation"`
+}
+
+type UserPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+type UserChangePasswordRequest struct {
+	CurrentPassword      string `json:"currentPassword"`
+	Password             string `json:"password"`
+	PasswordConfirmation string `json:"passwordConfirmation"`
+}
+
+type UserChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type UserMFALoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type UserMFACodeRequest struct {
+	Code string `json:"code"`
+}
+
 type UserRefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type UserCreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+type UserCurrencyRequest struct {
+	Currency string `json:"currency"`
+}
+
 type UserInsertAssetRequest struct {
 	AssetID string `json:"assetId"`
 }
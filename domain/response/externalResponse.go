@@ -4,8 +4,11 @@ type AssetValidationDataResponse struct {
 	ID                string `json:"id"`
 	Symbol            string `json:"symbol"`
 	Name              string `json:"name"`
+	Rank              string `json:"rank"`
 	PriceUSD          string `json:"priceUsd"`
 	ChangePercent24Hr string `json:"changePercent24Hr"`
+	MarketCapUSD      string `json:"marketCapUsd"`
+	VolumeUSD24Hr     string `json:"volumeUsd24Hr"`
 }
 
 type CurrencyRateDataResponse struct {
@@ -15,3 +18,8 @@ type CurrencyRateDataResponse struct {
 	Type           string `json:"type"`
 	RateUSD        string `json:"rateUsd"`
 }
+
+type AssetHistoryDataResponse struct {
+	PriceUSD string `json:"priceUsd"`
+	Time     int64  `json:"time"`
+}
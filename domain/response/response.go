@@ -15,3 +15,60 @@ type AuthResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 }
+
+type MFAPendingResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type SessionResponse struct {
+	ID             string `json:"id"`
+	UserAgent      string `json:"user_agent"`
+	IPAddress      string `json:"ip_address"`
+	CreatedAt      int64  `json:"created_at"`
+	LastUsedAt     int64  `json:"last_used_at"`
+	ExpirationTime int64  `json:"expiration_time"`
+	Current        bool   `json:"current"`
+}
+
+type APIKeyResponse struct {
+	ID             int      `json:"id"`
+	Name           string   `json:"name"`
+	Prefix         string   `json:"prefix"`
+	Key            string   `json:"key,omitempty"`
+	Scopes         []string `json:"scopes"`
+	CreatedAt      int64    `json:"created_at"`
+	ExpirationTime int64    `json:"expiration_time"`
+	LastUsedAt     int64    `json:"last_used_at"`
+}
+
+type AdminUserResponse struct {
+	ID            int    `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	Disabled      bool   `json:"disabled"`
+	Currency      string `json:"currency"`
+}
+
+type UserAssetResponse struct {
+	AssetId string `json:"assetId"`
+}
+
+type UserCurrencyResponse struct {
+	Currency string `json:"currency"`
+}
+
+type HealthResponse struct {
+	Status    string                   `json:"status"`
+	Providers []ProviderStatusResponse `json:"providers"`
+}
+
+type ProviderStatusResponse struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
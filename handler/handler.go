@@ -10,15 +10,17 @@ import (
 	"github.com/go-chi/cors"
 	"github.com/michaelwongycn/crypto-tracker/controller"
 	"github.com/michaelwongycn/crypto-tracker/handler/middleware"
+	"github.com/michaelwongycn/crypto-tracker/lib/auth"
 )
 
 type handler struct {
 	timeout    time.Duration
 	controller controller.Controller
+	middleware *middleware.Middleware
 	cors       *cors.Cors
 }
 
-func NewHandler(timeout time.Duration, controller controller.Controller) *handler {
+func NewHandler(timeout time.Duration, controller controller.Controller, middleware *middleware.Middleware) *handler {
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
@@ -31,6 +33,7 @@ func NewHandler(timeout time.Duration, controller controller.Controller) *handle
 	return &handler{
 		timeout:    timeout,
 		controller: controller,
+		middleware: middleware,
 		cors:       c,
 	}
 }
@@ -40,18 +43,76 @@ func (h *handler) StartRoute() *http.Server {
 
 	r.Use(h.cors.Handler)
 	r.Get("/ping", h.controller.Ping)
+	r.Get("/health", h.controller.Health)
+	r.Get("/.well-known/jwks.json", h.controller.JWKS)
 
 	r.Post("/login", h.controller.Login)
+	r.Post("/login/mfa", h.controller.LoginMFA)
+	r.Get("/oidc/login", h.controller.OIDCLogin)
+	r.Get("/oidc/callback", h.controller.OIDCCallback)
 	r.Post("/register", h.controller.Register)
+	r.Post("/verify-email", h.controller.VerifyEmail)
+	r.Post("/verify-email/resend", h.controller.ResendVerificationEmail)
+	r.Post("/forgot-password", h.controller.ForgotPassword)
+	r.Post("/reset-password", h.controller.ResetPassword)
+	r.Post("/change-email", h.controller.ConfirmEmailChange)
 	r.Post("/logout", h.controller.Logout)
 	r.Post("/refresh-token", h.controller.RefreshToken)
 
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.Authenticate)
+		r.Use(h.middleware.Authenticate)
 
-		r.Get("/crypto", h.controller.ShowUserAsset)
-		r.Post("/crypto", h.controller.InsertUserAsset)
-		r.Delete("/crypto", h.controller.DeleteUserAsset)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireVerifiedEmail)
+
+			r.With(middleware.RequireScope(auth.ScopeAssetsRead)).Get("/crypto", h.controller.ShowUserAsset)
+			r.With(middleware.RequireScope(auth.ScopeAssetsWrite)).Post("/crypto", h.controller.InsertUserAsset)
+			r.With(middleware.RequireScope(auth.ScopeAssetsWrite)).Delete("/crypto", h.controller.DeleteUserAsset)
+			r.With(middleware.RequireScope(auth.ScopeAssetsRead)).Get("/crypto/{assetId}/history", h.controller.ShowAssetPriceHistory)
+			r.With(middleware.RequireScope(auth.ScopeAssetsRead)).Get("/crypto/{assetId}/candles", h.controller.ShowAssetPriceCandles)
+			r.With(middleware.RequireScope(auth.ScopeAssetsRead)).Get("/currencies", h.controller.ShowCurrencies)
+			r.With(middleware.RequireScope(auth.ScopeAssetsRead)).Get("/assets/search", h.controller.SearchAssets)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireSession)
+
+			r.Post("/me/password", h.controller.ChangePassword)
+			r.Post("/me/email", h.controller.ChangeEmail)
+			r.Delete("/me", h.controller.DeleteAccount)
+			r.Post("/me/currency", h.controller.ChangeCurrency)
+			r.Get("/me/export", h.controller.ExportUserData)
+
+			r.Get("/sessions", h.controller.ShowUserSessions)
+			r.Delete("/sessions", h.controller.RevokeAllUserSessions)
+			r.Delete("/sessions/{sessionId}", h.controller.RevokeUserSession)
+
+			r.Post("/mfa/enroll", h.controller.EnrollMFA)
+			r.Post("/mfa/confirm", h.controller.ConfirmMFA)
+			r.Delete("/mfa", h.controller.DisableMFA)
+
+			r.Get("/api-keys", h.controller.ShowAPIKeys)
+			r.Post("/api-keys", h.controller.CreateAPIKey)
+			r.Delete("/api-keys/{apiKeyId}", h.controller.RevokeAPIKey)
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(middleware.RequireRole(auth.RoleAdmin, auth.RoleSupport))
+
+				r.Get("/users", h.controller.AdminSearchUsers)
+				r.Get("/users/{userId}", h.controller.AdminShowUser)
+				r.Get("/users/{userId}/assets", h.controller.AdminShowUserAsset)
+				r.Get("/providers", h.controller.AdminShowProviders)
+
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.RequireRole(auth.RoleAdmin))
+
+					r.Post("/users/{userId}/disable", h.controller.AdminDisableUser)
+					r.Post("/users/{userId}/enable", h.controller.AdminEnableUser)
+					r.Delete("/users/{userId}/sessions", h.controller.AdminRevokeUserSessions)
+					r.Delete("/login-lockouts/{key}", h.controller.AdminClearLoginLockout)
+				})
+			})
+		})
 	})
 
 	srv := &http.Server{
@@ -5,11 +5,28 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/golang-jwt/jwt"
 	"github.com/michaelwongycn/crypto-tracker/lib/auth"
-	"github.com/michaelwongycn/crypto-tracker/lib/cache"
+	"github.com/michaelwongycn/crypto-tracker/lib/tokenstore"
+	"github.com/michaelwongycn/crypto-tracker/usecase/user"
 )
 
-func Authenticate(next http.Handler) http.Handler {
+type Middleware struct {
+	userUsecase user.UserUsecase
+	tokenStore  tokenstore.Store
+}
+
+func NewMiddleware(userUsecase user.UserUsecase, tokenStore tokenstore.Store) *Middleware {
+	return &Middleware{
+		userUsecase: userUsecase,
+		tokenStore:  tokenStore,
+	}
+}
+
+// Authenticate accepts either a session access token or a personal API key as
+// the bearer token. API keys get claims shaped like a session's so handlers
+// don't need to care which one was used, plus the key's id and scopes.
+func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -24,9 +41,33 @@ func Authenticate(next http.Handler) http.Handler {
 		}
 		accessToken := tokenParts[1]
 
-		cachedAccessToken := cache.GetCache(accessToken)
+		if user.IsAPIKey(accessToken) {
+			apiKey, owner, err := m.userUsecase.AuthenticateAPIKey(r.Context(), accessToken)
+			if err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			claims := jwt.MapClaims{
+				"sub":            float64(owner.ID),
+				"email_verified": owner.EmailVerified,
+				"api_key_id":     float64(apiKey.ID),
+				"scope":          strings.Join(apiKey.Scopes, " "),
+			}
+
+			ctx := context.WithValue(r.Context(), "claims", claims)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		ok, err := m.tokenStore.Contains(r.Context(), accessToken)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
 
-		if cachedAccessToken == nil {
+		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -42,3 +83,86 @@ func Authenticate(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireSession must run after Authenticate. It keeps API keys out of account
+// management routes, which only an interactive session may use.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value("claims").(jwt.MapClaims)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if _, ok := claims["sid"].(string); !ok {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope must run after Authenticate. Session tokens carry no scope and
+// always pass; API keys must have been granted scope.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value("claims").(jwt.MapClaims)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !auth.HasScope(claims, scope) {
+				http.Error(w, "Insufficient scope", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole must run after Authenticate. Only users holding one of roles
+// get through.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value("claims").(jwt.MapClaims)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			role, _ := claims["role"].(string)
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		})
+	}
+}
+
+// RequireVerifiedEmail must run after Authenticate. It keeps accounts that
+// haven't confirmed their email address out of the routes it wraps.
+func RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value("claims").(jwt.MapClaims)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if emailVerified, _ := claims["email_verified"].(bool); !emailVerified {
+			http.Error(w, "Email not verified", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
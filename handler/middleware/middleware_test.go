@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt"
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/lib/auth"
+	"github.com/michaelwongycn/crypto-tracker/lib/db"
+	"github.com/michaelwongycn/crypto-tracker/lib/mailer"
+	"github.com/michaelwongycn/crypto-tracker/lib/password"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoDB"
+	"github.com/michaelwongycn/crypto-tracker/usecase/user"
+)
+
+const (
+	testEmail    = "middleware@example.com"
+	testPassword = "Passw0rd!x"
+)
+
+type testServer struct {
+	router   http.Handler
+	users    user.UserUsecase
+	dbCrypto cryptoDB.CryptoDBInterface
+	database *sql.DB
+	userId   int
+
+	// claims holds the claims the last request reached a handler with.
+	claims jwt.MapClaims
+}
+
+// newTestServer routes /crypto and /sessions the way the handler does,
+// backed by the user usecase on a fresh database with one verified user.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	err := auth.SetAuthConfig(config.JWTConfig{SecretKey: "test-secret", AccessTokenDuration: 15, RefreshTokenDuration: 60})
+	if err != nil {
+		t.Fatalf("SetAuthConfig: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	name, err := filepath.Rel(wd, filepath.Join(t.TempDir(), "crypto"))
+	if err != nil {
+		t.Fatalf("Rel: %v", err)
+	}
+	database, err := db.Connect(5, name)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	hasher, err := password.NewHasher(config.PasswordConfig{Algorithm: password.Bcrypt, Bcrypt: config.BcryptConfig{Cost: 4}})
+	if err != nil {
+		t.Fatalf("NewHasher: %v", err)
+	}
+	logMailer, err := mailer.NewMailer(config.MailerConfig{Driver: mailer.Log})
+	if err != nil {
+		t.Fatalf("NewMailer: %v", err)
+	}
+
+	dbCrypto := cryptoDB.NewCryptoDBImpl(5, database)
+	tokenStore := cryptoDB.NewTokenStore(5, database)
+	users := user.NewUserImpl(dbCrypto, tokenStore, nil, nil, hasher, logMailer, config.AccountConfig{}, config.LoginConfig{}, nil, 0, 60)
+
+	ctx := context.Background()
+	err = users.Register(ctx, testEmail, testPassword)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	owner, err := dbCrypto.GetUserByEmail(ctx, testEmail)
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	err = dbCrypto.UpdateUserEmailVerified(ctx, owner.ID, true)
+	if err != nil {
+		t.Fatalf("UpdateUserEmailVerified: %v", err)
+	}
+
+	s := &testServer{users: users, dbCrypto: dbCrypto, database: database, userId: owner.ID}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		s.claims, _ = r.Context().Value("claims").(jwt.MapClaims)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	m := NewMiddleware(users, tokenStore)
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(m.Authenticate)
+
+		r.Group(func(r chi.Router) {
+			r.Use(RequireVerifiedEmail)
+
+			r.With(RequireScope(auth.ScopeAssetsRead)).Get("/crypto", ok)
+			r.With(RequireScope(auth.ScopeAssetsWrite)).Post("/crypto", ok)
+		})
+
+		r.With(RequireSession).Get("/sessions", ok)
+	})
+	s.router = r
+	return s
+}
+
+// do sends a request with authorization as the Authorization header and
+// returns the status.
+func (s *testServer) do(method, path, authorization string) int {
+	r := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	return w.Code
+}
+
+func (s *testServer) createAPIKey(t *testing.T, scopes ...string) (int, string) {
+	t.Helper()
+
+	apiKey, key, err := s.users.CreateAPIKey(context.Background(), s.userId, "test", scopes, 0)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	return apiKey.ID, *key
+}
+
+func TestAuthenticateRejectsMissingCredentials(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name          string
+		authorization string
+	}{
+		{"no header", ""},
+		{"malformed header", "Bearer"},
+		{"unknown api key", "Bearer ctk_unknown"},
+		{"not a token", "Bearer not-a-token"},
+	}
+
+	for _, tt := range tests {
+		if status := s.do(http.MethodGet, "/crypto", tt.authorization); status != http.StatusUnauthorized {
+			t.Errorf("%s: got %d, want 401", tt.name, status)
+		}
+	}
+}
+
+func TestSessionTokenHasNoScopeClaim(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+
+	accessToken, _, err := s.users.Login(ctx, testEmail, testPassword, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	authorization := "Bearer " + *accessToken
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/crypto"},
+		{http.MethodPost, "/crypto"},
+		{http.MethodGet, "/sessions"},
+	} {
+		if status := s.do(route.method, route.path, authorization); status != http.StatusOK {
+			t.Errorf("session %s %s: got %d, want 200", route.method, route.path, status)
+		}
+		if _, ok := s.claims["scope"]; ok {
+			t.Errorf("session claims have a scope: %v", s.claims)
+		}
+	}
+
+	// A logged out session no longer authenticates.
+	claims, err := auth.ParseToken(*accessToken)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	err = s.users.Logout(ctx, *accessToken, s.userId, claims["sid"].(string))
+	if err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if status := s.do(http.MethodGet, "/crypto", authorization); status != http.StatusUnauthorized {
+		t.Fatalf("logged out session: got %d, want 401", status)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	s := newTestServer(t)
+	_, readKey := s.createAPIKey(t, auth.ScopeAssetsRead)
+	_, writeKey := s.createAPIKey(t, auth.ScopeAssetsWrite)
+
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		want   int
+	}{
+		{"read key reads", readKey, http.MethodGet, "/crypto", http.StatusOK},
+		{"read key can't write", readKey, http.MethodPost, "/crypto", http.StatusForbidden},
+		{"write key writes", writeKey, http.MethodPost, "/crypto", http.StatusOK},
+		{"write key reads", writeKey, http.MethodGet, "/crypto", http.StatusOK},
+		{"read key outside /crypto", readKey, http.MethodGet, "/sessions", http.StatusForbidden},
+		{"write key outside /crypto", writeKey, http.MethodGet, "/sessions", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		if status := s.do(tt.method, tt.path, "Bearer "+tt.key); status != tt.want {
+			t.Errorf("%s: %s %s got %d, want %d", tt.name, tt.method, tt.path, status, tt.want)
+		}
+	}
+
+	s.do(http.MethodGet, "/crypto", "Bearer "+readKey)
+	if s.claims["sub"] != float64(s.userId) || s.claims["scope"] != auth.ScopeAssetsRead {
+		t.Fatalf("api key claims = %v, want the owner and the key's scope", s.claims)
+	}
+}
+
+func TestAPIKeyRejectedWhenExpiredRevokedOrDisabled(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+
+	expiredId, expiredKey := s.createAPIKey(t, auth.ScopeAssetsRead)
+	_, err := s.database.Exec("UPDATE user_api_keys SET expirationTime = ? WHERE ID = ?", time.Now().Add(-time.Minute).Unix(), expiredId)
+	if err != nil {
+		t.Fatalf("expiring api key: %v", err)
+	}
+	if status := s.do(http.MethodGet, "/crypto", "Bearer "+expiredKey); status != http.StatusUnauthorized {
+		t.Errorf("expired key: got %d, want 401", status)
+	}
+
+	revokedId, revokedKey := s.createAPIKey(t, auth.ScopeAssetsRead)
+	err = s.users.RevokeAPIKey(ctx, s.userId, revokedId)
+	if err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if status := s.do(http.MethodGet, "/crypto", "Bearer "+revokedKey); status != http.StatusUnauthorized {
+		t.Errorf("revoked key: got %d, want 401", status)
+	}
+
+	_, key := s.createAPIKey(t, auth.ScopeAssetsRead)
+	if status := s.do(http.MethodGet, "/crypto", "Bearer "+key); status != http.StatusOK {
+		t.Fatalf("key before disabling the account: got %d, want 200", status)
+	}
+	err = s.dbCrypto.UpdateUserDisabled(ctx, s.userId, true)
+	if err != nil {
+		t.Fatalf("UpdateUserDisabled: %v", err)
+	}
+	if status := s.do(http.MethodGet, "/crypto", "Bearer "+key); status != http.StatusUnauthorized {
+		t.Errorf("key of a disabled account: got %d, want 401", status)
+	}
+}
@@ -5,38 +5,120 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+)
+
+const (
+	issuer         = "crypto-tracker"
+	accessAudience = "crypto-tracker:crypto"
+	mfaAudience    = "crypto-tracker:mfa"
+
+	actionAudiencePrefix = "crypto-tracker:action:"
+
+	mfaTokenDuration = 5 * time.Minute
 )
 
 var secretKey = []byte("")
 var accessTokenDuration = time.Duration(0)
 var refreshTokenDuration = time.Duration(0)
 
-func SetAuthConfig(key string, newAccessTokenDuration, newRefreshTokenDuration time.Duration) {
-	secretKey = []byte(key)
-	accessTokenDuration = newAccessTokenDuration
-	refreshTokenDuration = newRefreshTokenDuration
+var signingKey *key
+var acceptSecretKey bool
+var keys = map[string]*key{}
+var keyIds []string
+
+func SetAuthConfig(cfg config.JWTConfig) error {
+	secretKey = []byte(cfg.SecretKey)
+	accessTokenDuration = cfg.AccessTokenDuration
+	refreshTokenDuration = cfg.RefreshTokenDuration
+
+	newKeys := map[string]*key{}
+	var newKeyIds []string
+	for _, keyCfg := range cfg.Keys {
+		k, err := loadKey(keyCfg)
+		if err != nil {
+			return err
+		}
+		if _, ok := newKeys[k.id]; ok {
+			return fmt.Errorf("duplicate key id: %s", k.id)
+		}
+		newKeys[k.id] = k
+		newKeyIds = append(newKeyIds, k.id)
+	}
+
+	var newSigningKey *key
+	if cfg.SigningKeyID != "" {
+		k, ok := newKeys[cfg.SigningKeyID]
+		if !ok {
+			return fmt.Errorf("signing key %s is not configured", cfg.SigningKeyID)
+		}
+		if k.privateKey == nil {
+			return fmt.Errorf("signing key %s has no private key", cfg.SigningKeyID)
+		}
+		newSigningKey = k
+	}
+	if newSigningKey == nil && cfg.SecretKey == "" {
+		return fmt.Errorf("secret_key is required when no signing_key_id is set")
+	}
+
+	keys = newKeys
+	keyIds = newKeyIds
+	signingKey = newSigningKey
+	acceptSecretKey = cfg.AcceptSecretKey
+	return nil
+}
+
+// newToken starts a token signed by the active key pair, or by the shared
+// secret when no key pair is configured.
+func newToken() *jwt.Token {
+	if signingKey == nil {
+		return jwt.New(jwt.SigningMethodHS256)
+	}
+
+	token := jwt.New(signingKey.method)
+	token.Header["kid"] = signingKey.id
+	return token
+}
+
+func signToken(token *jwt.Token) (string, error) {
+	if signingKey == nil {
+		return token.SignedString(secretKey)
+	}
+	return token.SignedString(signingKey.privateKey)
+}
+
+// AccessTokenExpiration returns when an access token issued at currTime
+// expires.
+func AccessTokenExpiration(currTime time.Time) time.Time {
+	return currTime.Add(time.Minute * accessTokenDuration)
 }
 
-func CreateToken(currTime time.Time, ID int) (string, string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+func CreateToken(currTime time.Time, user model.User, sessionId, refreshTokenId string) (string, string, error) {
+	token := newToken()
 	claims := token.Claims.(jwt.MapClaims)
-	claims["iss"] = "crypto-tracker"
-	claims["aud"] = "crypto-tracker:crypto"
-	claims["sub"] = ID
-	claims["exp"] = currTime.Add(time.Minute * accessTokenDuration).Unix()
+	claims["iss"] = issuer
+	claims["aud"] = accessAudience
+	claims["sub"] = user.ID
+	claims["sid"] = sessionId
+	claims["email_verified"] = user.EmailVerified
+	claims["role"] = user.Role
+	claims["exp"] = AccessTokenExpiration(currTime).Unix()
 	claims["iat"] = currTime.Unix()
 
-	accessTokenString, err := token.SignedString(secretKey)
+	accessTokenString, err := signToken(token)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken := jwt.New(jwt.SigningMethodHS256)
+	refreshToken := newToken()
 	rtClaims := refreshToken.Claims.(jwt.MapClaims)
-	rtClaims["sub"] = ID
+	rtClaims["sub"] = user.ID
+	rtClaims["sid"] = sessionId
+	rtClaims["jti"] = refreshTokenId
 	rtClaims["exp"] = currTime.Add(time.Minute * refreshTokenDuration).Unix()
 
-	refreshTokenString, err := refreshToken.SignedString(secretKey)
+	refreshTokenString, err := signToken(refreshToken)
 	if err != nil {
 		return "", "", err
 	}
@@ -44,13 +126,99 @@ func CreateToken(currTime time.Time, ID int) (string, string, error) {
 	return accessTokenString, refreshTokenString, nil
 }
 
-func ParseToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// MFATokenExpiration is when an MFA token created at currTime stops being
+// accepted.
+func MFATokenExpiration(currTime time.Time) time.Time {
+	return currTime.Add(mfaTokenDuration)
+}
+
+// CreateMFAToken issues the short-lived token a client holds between a
+// successful password check and the second factor. The caller is responsible
+// for recording tokenId so the token is only accepted once.
+func CreateMFAToken(currTime time.Time, ID int, tokenId string) (string, error) {
+	token := newToken()
+	claims := token.Claims.(jwt.MapClaims)
+	claims["iss"] = issuer
+	claims["aud"] = mfaAudience
+	claims["sub"] = ID
+	claims["jti"] = tokenId
+	claims["exp"] = MFATokenExpiration(currTime).Unix()
+	claims["iat"] = currTime.Unix()
+
+	return signToken(token)
+}
+
+func ParseMFAToken(tokenString string) (jwt.MapClaims, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.VerifyAudience(mfaAudience, true) {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
+
+// CreateActionToken issues a signed, expiring token that authorises a single
+// account action such as confirming an email address or resetting a password.
+// The caller is responsible for recording tokenId so the token is only
+// accepted once. newEmail is only set when the action moves the account to
+// another address.
+func CreateActionToken(currTime time.Time, ID int, purpose, tokenId, email, newEmail string, duration time.Duration) (string, error) {
+	token := newToken()
+	claims := token.Claims.(jwt.MapClaims)
+	claims["iss"] = issuer
+	claims["aud"] = actionAudiencePrefix + purpose
+	claims["sub"] = ID
+	claims["jti"] = tokenId
+	claims["email"] = email
+	if newEmail != "" {
+		claims["new_email"] = newEmail
+	}
+	claims["exp"] = currTime.Add(time.Minute * duration).Unix()
+	claims["iat"] = currTime.Unix()
+
+	return signToken(token)
+}
+
+func ParseActionToken(tokenString, purpose string) (jwt.MapClaims, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.VerifyAudience(actionAudiencePrefix+purpose, true) {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
+
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		// Tokens without a key id are signed with the shared secret, which is
+		// retired once a key pair signs, unless it is still accepted while the
+		// tokens it signed expire.
+		if signingKey != nil && !acceptSecretKey {
+			return nil, fmt.Errorf("tokens without a key id are no longer accepted")
+		}
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(secretKey) == 0 {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return secretKey, nil
-	})
+	}
+
+	k, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return k.publicKey, nil
+}
+
+func ParseToken(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, verificationKey)
 	if err != nil {
 		return nil, err
 	}
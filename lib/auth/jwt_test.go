@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+)
+
+func writeEdDSAKey(t *testing.T) string {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func setTestAuthConfig(t *testing.T, cfg config.JWTConfig) {
+	t.Helper()
+
+	cfg.AccessTokenDuration = 15
+	cfg.RefreshTokenDuration = 60
+	err := SetAuthConfig(cfg)
+	if err != nil {
+		t.Fatalf("SetAuthConfig: %v", err)
+	}
+	t.Cleanup(func() {
+		secretKey, signingKey, acceptSecretKey = []byte(""), nil, false
+		keys, keyIds = map[string]*key{}, nil
+	})
+}
+
+func createTestToken(t *testing.T) string {
+	t.Helper()
+
+	accessToken, _, err := CreateToken(time.Now(), model.User{ID: 1, Role: RoleUser}, "sid", "jti")
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	return accessToken
+}
+
+func TestSecretKeyRetiredBySigningKey(t *testing.T) {
+	keyFile := writeEdDSAKey(t)
+
+	setTestAuthConfig(t, config.JWTConfig{SecretKey: "secret"})
+	secretToken := createTestToken(t)
+
+	_, err := ParseToken(secretToken)
+	if err != nil {
+		t.Fatalf("ParseToken(secret token) without key pairs: %v", err)
+	}
+
+	keyPairConfig := config.JWTConfig{
+		SecretKey:    "secret",
+		SigningKeyID: "k1",
+		Keys:         []config.JWTKeyConfig{{ID: "k1", Algorithm: algorithmEdDSA, PrivateKeyFile: keyFile}},
+	}
+
+	keyPairConfig.AcceptSecretKey = true
+	setTestAuthConfig(t, keyPairConfig)
+	_, err = ParseToken(secretToken)
+	if err != nil {
+		t.Fatalf("ParseToken(secret token) while accept_secret_key is set: %v", err)
+	}
+
+	keyPairConfig.AcceptSecretKey = false
+	setTestAuthConfig(t, keyPairConfig)
+	_, err = ParseToken(secretToken)
+	if err == nil {
+		t.Fatal("ParseToken(secret token) succeeded after the secret was retired")
+	}
+
+	_, err = ParseToken(createTestToken(t))
+	if err != nil {
+		t.Fatalf("ParseToken(key pair token): %v", err)
+	}
+}
+
+func TestSetAuthConfigRequiresAKey(t *testing.T) {
+	err := SetAuthConfig(config.JWTConfig{})
+	if err == nil {
+		t.Fatal("SetAuthConfig accepted a config without a secret or signing key")
+	}
+}
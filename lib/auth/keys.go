@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+)
+
+const (
+	algorithmRS256 = "RS256"
+	algorithmEdDSA = "EdDSA"
+)
+
+type key struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey interface{}
+	publicKey  interface{}
+}
+
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// loadKey reads a PEM encoded key pair. Keys that are only kept around to
+// verify tokens issued before a rotation may omit the private key file.
+func loadKey(cfg config.JWTKeyConfig) (*key, error) {
+	k := &key{id: cfg.ID}
+
+	switch cfg.Algorithm {
+	case algorithmRS256:
+		k.method = jwt.SigningMethodRS256
+	case algorithmEdDSA:
+		k.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("key %s: unsupported algorithm %s", cfg.ID, cfg.Algorithm)
+	}
+
+	if cfg.PrivateKeyFile != "" {
+		pemBytes, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", cfg.ID, err)
+		}
+
+		switch cfg.Algorithm {
+		case algorithmRS256:
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", cfg.ID, err)
+			}
+			k.privateKey = privateKey
+			k.publicKey = &privateKey.PublicKey
+		case algorithmEdDSA:
+			privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", cfg.ID, err)
+			}
+			edPrivateKey, ok := privateKey.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("key %s: not an Ed25519 private key", cfg.ID)
+			}
+			k.privateKey = edPrivateKey
+			k.publicKey = edPrivateKey.Public()
+		}
+	}
+
+	if cfg.PublicKeyFile != "" {
+		pemBytes, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", cfg.ID, err)
+		}
+
+		switch cfg.Algorithm {
+		case algorithmRS256:
+			k.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+		case algorithmEdDSA:
+			k.publicKey, err = jwt.ParseEdPublicKeyFromPEM(pemBytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", cfg.ID, err)
+		}
+	}
+
+	if k.publicKey == nil {
+		return nil, fmt.Errorf("key %s: either private_key_file or public_key_file is required", cfg.ID)
+	}
+
+	return k, nil
+}
+
+func (k *key) jwk() JSONWebKey {
+	jwk := JSONWebKey{
+		Use: "sig",
+		Kid: k.id,
+		Alg: k.method.Alg(),
+	}
+
+	switch publicKey := k.publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	}
+
+	return jwk
+}
+
+// JWKS returns the public half of every configured asymmetric key, including
+// keys that no longer sign but may still verify tokens that haven't expired.
+func JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, id := range keyIds {
+		set.Keys = append(set.Keys, keys[id].jwk())
+	}
+	return set
+}
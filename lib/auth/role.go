@@ -0,0 +1,20 @@
+package auth
+
+// Roles decide which admin routes a user may reach. Support staff can look
+// at accounts but not change them.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+var Roles = []string{RoleUser, RoleSupport, RoleAdmin}
+
+func IsValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
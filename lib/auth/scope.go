@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+)
+
+// Scopes limit what an API key may do. Tokens issued to interactive sessions
+// carry no scope claim and are allowed everything.
+const (
+	ScopeAssetsRead  = "assets:read"
+	ScopeAssetsWrite = "assets:write"
+)
+
+var Scopes = []string{ScopeAssetsRead, ScopeAssetsWrite}
+
+func IsValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// impliedScopes are the scopes granted along with a scope. A key that may
+// change the portfolio may also read it.
+var impliedScopes = map[string][]string{
+	ScopeAssetsWrite: {ScopeAssetsRead},
+}
+
+// HasScope reports whether claims allow scope, directly or through a scope
+// that implies it.
+func HasScope(claims map[string]interface{}, scope string) bool {
+	granted, ok := claims["scope"].(string)
+	if !ok {
+		return true
+	}
+
+	for _, s := range strings.Fields(granted) {
+		if s == scope || slices.Contains(impliedScopes[s], scope) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package auth
+
+import "testing"
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		scope  string
+		want   bool
+	}{
+		{"session without scope claim reads", map[string]interface{}{"sid": "session"}, ScopeAssetsRead, true},
+		{"session without scope claim writes", map[string]interface{}{"sid": "session"}, ScopeAssetsWrite, true},
+		{"read key reads", map[string]interface{}{"scope": ScopeAssetsRead}, ScopeAssetsRead, true},
+		{"read key can't write", map[string]interface{}{"scope": ScopeAssetsRead}, ScopeAssetsWrite, false},
+		{"write key writes", map[string]interface{}{"scope": ScopeAssetsWrite}, ScopeAssetsWrite, true},
+		{"write key reads", map[string]interface{}{"scope": ScopeAssetsWrite}, ScopeAssetsRead, true},
+		{"key with both scopes", map[string]interface{}{"scope": ScopeAssetsRead + " " + ScopeAssetsWrite}, ScopeAssetsWrite, true},
+		{"key without scopes", map[string]interface{}{"scope": ""}, ScopeAssetsRead, false},
+		{"unknown scope", map[string]interface{}{"scope": ScopeAssetsWrite}, "admin", false},
+	}
+
+	for _, tt := range tests {
+		if got := HasScope(tt.claims, tt.scope); got != tt.want {
+			t.Errorf("%s: HasScope(%s) = %v, want %v", tt.name, tt.scope, got, tt.want)
+		}
+	}
+}
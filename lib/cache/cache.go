@@ -1,30 +1,60 @@
 package cache
 
 import (
+	"context"
+	"fmt"
 	"time"
 
-	"github.com/jellydator/ttlcache/v3"
 	"github.com/michaelwongycn/crypto-tracker/domain/config"
 )
 
-var cache = ttlcache.New(
-	ttlcache.WithTTL[string, string](0),
+const (
+	Memory = "memory"
+	Redis  = "redis"
 )
 
-func InitializeNewCache(cfg config.ApplicationConfig) *ttlcache.Cache[string, string] {
-	return ttlcache.New(
-		ttlcache.WithTTL[string, string](time.Minute * cfg.JWT.AccessTokenDuration),
-	)
+// Cache stores string values that expire after a TTL chosen per entry, a TTL
+// of 0 keeps the entry until it is deleted. The Redis backend lets several
+// instances of the app share one cache.
+type Cache interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
 }
 
-func SetCache(key string, value string) {
-	cache.Set(key, value, ttlcache.DefaultTTL)
+func NewCache(cfg config.CacheConfig) (Cache, error) {
+	switch cfg.Driver {
+	case "", Memory:
+		return newMemoryCache(), nil
+	case Redis:
+		return newRedisCache(cfg.Redis)
+	default:
+		return nil, fmt.Errorf("unsupported cache driver: %s", cfg.Driver)
+	}
 }
 
-func GetCache(key string) *ttlcache.Item[string, string] {
-	return cache.Get(key)
+type namespacedCache struct {
+	cache  Cache
+	prefix string
 }
 
-func DeleteCache(key string) {
-	cache.Delete(key)
+// Namespace returns a view of c that keeps its keys apart from those of
+// other namespaces sharing the same backend.
+func Namespace(c Cache, name string) Cache {
+	return &namespacedCache{
+		cache:  c,
+		prefix: name + ":",
+	}
+}
+
+func (c *namespacedCache) Get(ctx context.Context, key string) (string, bool, error) {
+	return c.cache.Get(ctx, c.prefix+key)
+}
+
+func (c *namespacedCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return c.cache.Set(ctx, c.prefix+key, value, ttl)
+}
+
+func (c *namespacedCache) Delete(ctx context.Context, key string) error {
+	return c.cache.Delete(ctx, c.prefix+key)
 }
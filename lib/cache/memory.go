@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+type memoryCache struct {
+	cache *ttlcache.Cache[string, string]
+}
+
+func newMemoryCache() *memoryCache {
+	cache := ttlcache.New[string, string](
+		ttlcache.WithDisableTouchOnHit[string, string](),
+	)
+	go cache.Start()
+
+	return &memoryCache{
+		cache: cache,
+	}
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string) (string, bool, error) {
+	item := c.cache.Get(key)
+	if item == nil {
+		return "", false, nil
+	}
+	return item.Value(), true, nil
+}
+
+func (c *memoryCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = ttlcache.NoTTL
+	}
+	c.cache.Set(key, value, ttl)
+	return nil
+}
+
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	c.cache.Delete(key)
+	return nil
+}
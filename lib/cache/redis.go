@@ -0,0 +1,311 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+)
+
+const (
+	defaultRedisPoolSize = 10
+	defaultRedisTimeout  = 5 * time.Second
+
+	// Bulk strings and arrays larger than this are treated as a protocol
+	// error rather than allocated.
+	maxRedisReplySize = 64 << 20
+)
+
+var errRedisProtocol = errors.New("redis: protocol error")
+
+// redisError is an error reply sent by the server. The connection is still
+// usable after one.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisCache talks RESP to a Redis compatible server, reusing up to PoolSize
+// idle connections. Connections are made on first use, so the server doesn't
+// need to be reachable at startup.
+type redisCache struct {
+	cfg     config.RedisConfig
+	timeout time.Duration
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func newRedisCache(cfg config.RedisConfig) (*redisCache, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("redis address is required")
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = defaultRedisPoolSize
+	}
+
+	timeout := time.Second * cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultRedisTimeout
+	}
+
+	return &redisCache{
+		cfg:     cfg,
+		timeout: timeout,
+	}, nil
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) (string, bool, error) {
+	reply, err := c.do(ctx, "GET", key)
+	if err != nil {
+		return "", false, err
+	}
+
+	if reply == nil {
+		return "", false, nil
+	}
+
+	value, ok := reply.(string)
+	if !ok {
+		return "", false, fmt.Errorf("%w: unexpected reply to GET", errRedisProtocol)
+	}
+	return value, true, nil
+}
+
+func (c *redisCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	var err error
+	if ttl > 0 {
+		_, err = c.do(ctx, "SET", key, value, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	} else {
+		_, err = c.do(ctx, "SET", key, value)
+	}
+	return err
+}
+
+func (c *redisCache) Delete(ctx context.Context, key string) error {
+	_, err := c.do(ctx, "DEL", key)
+	return err
+}
+
+// do sends one command and returns its reply: a string for simple and bulk
+// strings, an int64 for integers, a []interface{} for arrays and nil for null
+// replies.
+//
+// An idle connection may have been closed by the server in the meantime, a
+// command failing on one is retried once on a new connection. Every command
+// we send is safe to repeat.
+func (c *redisCache) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, pooled, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.doConn(ctx, conn, args...)
+	if err != nil && pooled && !isRedisError(err) && ctx.Err() == nil {
+		conn, err = c.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		reply, err = c.doConn(ctx, conn, args...)
+	}
+	return reply, err
+}
+
+func (c *redisCache) doConn(ctx context.Context, conn *redisConn, args ...string) (interface{}, error) {
+	reply, err := conn.do(c.deadline(ctx), args...)
+	if err != nil && !isRedisError(err) {
+		conn.conn.Close()
+		return nil, err
+	}
+
+	c.put(conn)
+	return reply, err
+}
+
+func isRedisError(err error) bool {
+	var replyErr redisError
+	return errors.As(err, &replyErr)
+}
+
+func (c *redisCache) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+	return deadline
+}
+
+// get returns an idle connection, or a new one when there is none. pooled
+// reports which.
+func (c *redisCache) get(ctx context.Context) (conn *redisConn, pooled bool, err error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn = c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, true, nil
+	}
+	c.mu.Unlock()
+
+	conn, err = c.dial(ctx)
+	return conn, false, err
+}
+
+func (c *redisCache) put(conn *redisConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.idle) >= c.cfg.PoolSize {
+		conn.conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+func (c *redisCache) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Deadline: c.deadline(ctx)}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{
+		conn: netConn,
+		r:    bufio.NewReader(netConn),
+		w:    bufio.NewWriter(netConn),
+	}
+
+	if c.cfg.Password != "" {
+		args := []string{"AUTH", c.cfg.Password}
+		if c.cfg.Username != "" {
+			args = []string{"AUTH", c.cfg.Username, c.cfg.Password}
+		}
+		_, err = conn.do(c.deadline(ctx), args...)
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	if c.cfg.DB != 0 {
+		_, err = conn.do(c.deadline(ctx), "SELECT", strconv.Itoa(c.cfg.DB))
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *redisConn) do(deadline time.Time, args ...string) (interface{}, error) {
+	err := c.conn.SetDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	err = c.w.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("%w: empty reply", errRedisProtocol)
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid integer %q", errRedisProtocol, line[1:])
+		}
+		return n, nil
+	case '$':
+		n, err := parseReplyLength(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+
+		buf := make([]byte, n+2)
+		_, err = io.ReadFull(c.r, buf)
+		if err != nil {
+			return nil, err
+		}
+		if buf[n] != '\r' || buf[n+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string not terminated by CRLF", errRedisProtocol)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := parseReplyLength(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := c.readReply()
+			if err != nil {
+				if !isRedisError(err) {
+					return nil, err
+				}
+				item = err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected reply type %q", errRedisProtocol, line[0])
+	}
+}
+
+func (c *redisConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("%w: line not terminated by CRLF", errRedisProtocol)
+	}
+	return line[:len(line)-2], nil
+}
+
+// parseReplyLength parses the length of a bulk string or array, -1 stands
+// for a null reply.
+func parseReplyLength(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < -1 || n > maxRedisReplySize {
+		return 0, fmt.Errorf("%w: invalid length %q", errRedisProtocol, s)
+	}
+	return n, nil
+}
@@ -0,0 +1,297 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+)
+
+// fakeRedis serves GET, SET and DEL over RESP from a map. Replies to commands
+// listed in errors are error replies instead.
+type fakeRedis struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu       sync.Mutex
+	values   map[string]string
+	commands [][]string
+	errors   map[string]string
+	conns    []net.Conn
+	accepted int
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	s := &fakeRedis{
+		t:        t,
+		listener: listener,
+		values:   map[string]string{},
+		errors:   map[string]string{},
+	}
+	t.Cleanup(func() {
+		listener.Close()
+		s.dropConns()
+	})
+
+	go s.serve()
+	return s
+}
+
+func (s *fakeRedis) cache(t *testing.T) *redisCache {
+	t.Helper()
+
+	c, err := newRedisCache(config.RedisConfig{Address: s.listener.Addr().String(), Timeout: 1})
+	if err != nil {
+		t.Fatalf("newRedisCache: %v", err)
+	}
+	return c
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.accepted++
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+// handle reads commands with the client's own reply parser, a command being
+// an array of bulk strings.
+func (s *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	client := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	for {
+		request, err := client.readReply()
+		if err != nil {
+			return
+		}
+
+		items, _ := request.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+
+		fmt.Fprint(client.w, s.reply(args))
+		if client.w.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedis) reply(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commands = append(s.commands, args)
+	if len(args) == 0 {
+		return "-ERR empty command\r\n"
+	}
+
+	command := strings.ToUpper(args[0])
+	if msg, ok := s.errors[command]; ok {
+		return "-" + msg + "\r\n"
+	}
+
+	switch {
+	case command == "GET" && len(args) == 2:
+		value, ok := s.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case command == "SET" && len(args) >= 3:
+		s.values[args[1]] = args[2]
+		return "+OK\r\n"
+	case command == "DEL" && len(args) == 2:
+		_, ok := s.values[args[1]]
+		delete(s.values, args[1])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+// dropConns closes every connection, as a server does with idle clients
+// after its timeout.
+func (s *fakeRedis) dropConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeRedis) lastCommand() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.commands) == 0 {
+		return nil
+	}
+	return s.commands[len(s.commands)-1]
+}
+
+func TestRedisCacheGetMissing(t *testing.T) {
+	c := newFakeRedis(t).cache(t)
+
+	value, ok, err := c.Get(context.Background(), "missing")
+	if err != nil || ok || value != "" {
+		t.Fatalf("Get(missing) = %q, %v, %v, want a miss", value, ok, err)
+	}
+}
+
+func TestRedisCacheSet(t *testing.T) {
+	server := newFakeRedis(t)
+	c := server.cache(t)
+	ctx := context.Background()
+
+	err := c.Set(ctx, "key", "value", 1500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := strings.Join(server.lastCommand(), " "); got != "SET key value PX 1500" {
+		t.Fatalf("Set sent %q", got)
+	}
+
+	err = c.Set(ctx, "key", "forever", 0)
+	if err != nil {
+		t.Fatalf("Set without a TTL: %v", err)
+	}
+	if got := strings.Join(server.lastCommand(), " "); got != "SET key forever" {
+		t.Fatalf("Set without a TTL sent %q", got)
+	}
+
+	value, ok, err := c.Get(ctx, "key")
+	if err != nil || !ok || value != "forever" {
+		t.Fatalf("Get = %q, %v, %v", value, ok, err)
+	}
+
+	err = c.Delete(ctx, "key")
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, ok, err = c.Get(ctx, "key")
+	if err != nil || ok {
+		t.Fatalf("Get after Delete = %v, %v, want a miss", ok, err)
+	}
+}
+
+func TestRedisCacheErrorReply(t *testing.T) {
+	server := newFakeRedis(t)
+	c := server.cache(t)
+	ctx := context.Background()
+
+	server.mu.Lock()
+	server.errors["GET"] = "WRONGTYPE Operation against a key holding the wrong kind of value"
+	server.mu.Unlock()
+
+	_, _, err := c.Get(ctx, "key")
+	var replyErr redisError
+	if !errors.As(err, &replyErr) || !strings.HasPrefix(string(replyErr), "WRONGTYPE") {
+		t.Fatalf("Get error = %v, want the server's error reply", err)
+	}
+
+	// An error reply leaves the connection usable, it is neither retried nor
+	// replaced.
+	err = c.Set(ctx, "key", "value", 0)
+	if err != nil {
+		t.Fatalf("Set after an error reply: %v", err)
+	}
+
+	server.mu.Lock()
+	accepted, commands := server.accepted, len(server.commands)
+	server.mu.Unlock()
+	if accepted != 1 || commands != 2 {
+		t.Fatalf("%d connections and %d commands, want 1 and 2", accepted, commands)
+	}
+}
+
+func TestRedisCacheReconnectsAfterIdleDrop(t *testing.T) {
+	server := newFakeRedis(t)
+	c := server.cache(t)
+	ctx := context.Background()
+
+	err := c.Set(ctx, "key", "value", 0)
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	server.dropConns()
+
+	value, ok, err := c.Get(ctx, "key")
+	if err != nil || !ok || value != "value" {
+		t.Fatalf("Get after the server dropped the connection = %q, %v, %v", value, ok, err)
+	}
+
+	server.mu.Lock()
+	accepted := server.accepted
+	server.mu.Unlock()
+	if accepted != 2 {
+		t.Fatalf("%d connections, want 2", accepted)
+	}
+}
+
+func TestNamespaceSeparatesKeys(t *testing.T) {
+	server := newFakeRedis(t)
+	c := server.cache(t)
+	ctx := context.Background()
+
+	quotes := Namespace(c, "quotes")
+	rates := Namespace(c, "rates")
+
+	err := quotes.Set(ctx, "bitcoin", "quote", 0)
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := server.lastCommand(); len(got) < 2 || got[1] != "quotes:bitcoin" {
+		t.Fatalf("Set sent %q, want the key prefixed with its namespace", got)
+	}
+
+	_, ok, err := rates.Get(ctx, "bitcoin")
+	if err != nil || ok {
+		t.Fatalf("Get from another namespace = %v, %v, want a miss", ok, err)
+	}
+
+	err = rates.Set(ctx, "bitcoin", "rate", 0)
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	err = quotes.Delete(ctx, "bitcoin")
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	value, ok, err := rates.Get(ctx, "bitcoin")
+	if err != nil || !ok || value != "rate" {
+		t.Fatalf("Get after deleting from another namespace = %q, %v, %v", value, ok, err)
+	}
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"time"
 
@@ -33,6 +34,54 @@ func createTableIfNotExists(db *sql.DB, tableName string, schema string) error {
 	return nil
 }
 
+// dropTableIfExists removes a table an older version created.
+func dropTableIfExists(db *sql.DB, tableName string) error {
+	exists, err := tableExists(db, tableName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		_, err := db.Exec(fmt.Sprintf("DROP TABLE %s", tableName))
+		if err != nil {
+			return err
+		}
+		log.Printf("Dropped Table %s\n", tableName)
+	}
+	return nil
+}
+
+func columnExists(db *sql.DB, tableName string, columnName string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name=?", tableName, columnName).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// addColumnIfNotExists brings tables created by an older version up to date.
+// backfill, when set, runs once right after the column is added.
+func addColumnIfNotExists(db *sql.DB, tableName string, columnName string, definition string, backfill string) error {
+	exists, err := columnExists(db, tableName, columnName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		_, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, columnName, definition))
+		if err != nil {
+			return err
+		}
+		if backfill != "" {
+			_, err = db.Exec(backfill)
+			if err != nil {
+				return err
+			}
+		}
+		log.Printf("Added Column %s.%s\n", tableName, columnName)
+	}
+	return nil
+}
+
 func Connect(timeout time.Duration, dbname string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", "./"+dbname+".db")
 	if err != nil {
@@ -56,7 +105,20 @@ func Connect(timeout time.Duration, dbname string) (*sql.DB, error) {
 	}{
 		{usersTable, usersTableSchema},
 		{userAssetsTable, userAssetsTableSchema},
-		{userTokensTable, userTokensTableSchema},
+		{userSessionsTable, userSessionsTableSchema},
+		{refreshTokensTable, refreshTokensTableSchema},
+		{auditEventsTable, auditEventsTableSchema},
+		{userMFATable, userMFATableSchema},
+		{userRecoveryCodesTable, userRecoveryCodesTableSchema},
+		{userActionTokensTable, userActionTokensTableSchema},
+		{loginAttemptsTable, loginAttemptsTableSchema},
+		{userAPIKeysTable, userAPIKeysTableSchema},
+		{userIdentitiesTable, userIdentitiesTableSchema},
+		{oidcStatesTable, oidcStatesTableSchema},
+		{accessTokensTable, accessTokensTableSchema},
+		{priceHistoryTable, priceHistoryTableSchema},
+		{priceCandlesTable, priceCandlesTableSchema},
+		{assetCatalogTable, assetCatalogTableSchema},
 	}
 
 	for _, table := range tables {
@@ -67,5 +129,37 @@ func Connect(timeout time.Duration, dbname string) (*sql.DB, error) {
 		}
 	}
 
+	for _, table := range []string{userTokensTable} {
+		err = dropTableIfExists(db, table)
+		if err != nil {
+			log.Printf("Error dropping table %s: %s\n", table, err)
+			return nil, err
+		}
+	}
+
+	columns := []struct {
+		table      string
+		name       string
+		definition string
+		backfill   string
+	}{
+		{usersTable, usersEmailVerifiedColumn, usersEmailVerifiedColumnDefinition, usersEmailVerifiedColumnBackfill},
+		{usersTable, usersRoleColumn, usersRoleColumnDefinition, ""},
+		{usersTable, usersDisabledColumn, usersDisabledColumnDefinition, ""},
+		{usersTable, usersCurrencyColumn, usersCurrencyColumnDefinition, ""},
+		{userSessionsTable, userSessionsAccessTokenHashColumn, userSessionsAccessTokenHashColumnDefinition, userSessionsAccessTokenHashColumnBackfill},
+		{userSessionsTable, userSessionsRefreshTokenHashColumn, userSessionsRefreshTokenHashColumnDefinition, ""},
+		{priceHistoryTable, priceHistoryCurrencyColumn, priceHistoryCurrencyColumnDefinition, priceHistoryCurrencyColumnBackfill},
+		{priceCandlesTable, priceCandlesVolumeColumn, priceCandlesVolumeColumnDefinition, ""},
+	}
+
+	for _, column := range columns {
+		err = addColumnIfNotExists(db, column.table, column.name, column.definition, column.backfill)
+		if err != nil {
+			log.Printf("Error adding column %s.%s: %s\n", column.table, column.name, err)
+			return nil, err
+		}
+	}
+
 	return db, nil
 }
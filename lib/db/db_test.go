@@ -0,0 +1,148 @@
+package db
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testDBName returns a database name in a temporary directory, relative to
+// the working directory as Connect expects.
+func testDBName(t *testing.T) string {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	name, err := filepath.Rel(wd, filepath.Join(t.TempDir(), "crypto"))
+	if err != nil {
+		t.Fatalf("Rel: %v", err)
+	}
+	return name
+}
+
+func TestConnectClearsPlaintextSessionTokens(t *testing.T) {
+	name := testDBName(t)
+
+	old, err := sql.Open("sqlite", "./"+name+".db")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE user_sessions (ID TEXT PRIMARY KEY, userId INTEGER, accessToken TEXT, refreshToken TEXT, userAgent TEXT, ipAddress TEXT, createdAt INTEGER, lastUsedAt INTEGER, expirationTime INTEGER)`,
+		accessTokensTableSchema,
+		`INSERT INTO user_sessions (ID, userId, accessToken, refreshToken) VALUES ('session', 1, 'access', 'refresh')`,
+		`INSERT INTO access_tokens (tokenHash, userId, expirationTime) VALUES ('hash', 1, 0)`,
+	} {
+		_, err = old.Exec(stmt)
+		if err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	old.Close()
+
+	database, err := Connect(5, name)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer database.Close()
+
+	var accessToken, refreshToken sql.NullString
+	err = database.QueryRow("SELECT accessToken, refreshToken FROM user_sessions WHERE ID = 'session'").Scan(&accessToken, &refreshToken)
+	if err != nil {
+		t.Fatalf("reading the session: %v", err)
+	}
+	if accessToken.Valid || refreshToken.Valid {
+		t.Fatalf("session still holds tokens %q and %q", accessToken.String, refreshToken.String)
+	}
+
+	var accessTokens int
+	err = database.QueryRow("SELECT COUNT(*) FROM access_tokens").Scan(&accessTokens)
+	if err != nil || accessTokens != 0 {
+		t.Fatalf("%d access tokens left (%v), want 0", accessTokens, err)
+	}
+
+	for _, column := range []string{userSessionsAccessTokenHashColumn, userSessionsRefreshTokenHashColumn} {
+		exists, err := columnExists(database, userSessionsTable, column)
+		if err != nil || !exists {
+			t.Fatalf("column %s was not added (%v)", column, err)
+		}
+	}
+}
+
+func TestConnectDropsUserTokens(t *testing.T) {
+	name := testDBName(t)
+
+	old, err := sql.Open("sqlite", "./"+name+".db")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE user_tokens (userId INTEGER PRIMARY KEY, accessToken TEXT, refreshToken TEXT, expirationTime INTEGER)`,
+		`INSERT INTO user_tokens (userId, accessToken, refreshToken, expirationTime) VALUES (1, 'access', 'refresh', 0)`,
+	} {
+		_, err = old.Exec(stmt)
+		if err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	old.Close()
+
+	database, err := Connect(5, name)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer database.Close()
+
+	exists, err := tableExists(database, userTokensTable)
+	if err != nil || exists {
+		t.Fatalf("user_tokens still exists (%v)", err)
+	}
+}
+
+func TestConnectClearsPriceHistoryOfUnknownCurrency(t *testing.T) {
+	name := testDBName(t)
+
+	old, err := sql.Open("sqlite", "./"+name+".db")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE price_history (assetId TEXT, timestamp INTEGER, price TEXT, resolution INTEGER DEFAULT 0, PRIMARY KEY (assetId, timestamp))`,
+		priceCandlesTableSchema,
+		`INSERT INTO price_history (assetId, timestamp, price) VALUES ('bitcoin', 1717200000, '1099312345.5')`,
+		`INSERT INTO price_candles (assetId, currency, resolution, openTime, open, high, low, close, ticks) VALUES ('bitcoin', 'indonesian-rupiah', 3600, 1717200000, '1', '1', '1', '1', 1)`,
+	} {
+		_, err = old.Exec(stmt)
+		if err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	old.Close()
+
+	database, err := Connect(5, name)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer database.Close()
+
+	for _, table := range []string{priceHistoryTable, priceCandlesTable} {
+		var count int
+		err = database.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count)
+		if err != nil || count != 0 {
+			t.Fatalf("%d rows left in %s (%v), want 0", count, table, err)
+		}
+	}
+
+	_, err = database.Exec(`INSERT INTO price_history (assetId, timestamp, price) VALUES ('bitcoin', 1717200000, '67512.35')`)
+	if err != nil {
+		t.Fatalf("inserting a price: %v", err)
+	}
+	var currency string
+	err = database.QueryRow("SELECT currency FROM price_history").Scan(&currency)
+	if err != nil || currency != "united-states-dollar" {
+		t.Fatalf("new price stored in %q (%v), want united-states-dollar", currency, err)
+	}
+}
@@ -1,10 +1,71 @@
 package db
 
 const (
-	usersTable            = "users"
-	usersTableSchema      = `CREATE TABLE users (ID INTEGER PRIMARY KEY, email TEXT UNIQUE, password TEXT)`
-	userAssetsTable       = "user_assets"
-	userAssetsTableSchema = `CREATE TABLE user_assets (ID INTEGER PRIMARY KEY, userId INTEGER, assetId INTEGER, FOREIGN KEY (userId) REFERENCES users(ID), CONSTRAINT unique_user_crypto UNIQUE (userId, assetId))`
-	userTokensTable       = "user_tokens"
-	userTokensTableSchema = `CREATE TABLE user_tokens (userId INTEGER PRIMARY KEY, accessToken TEXT, refreshToken TEXT, expirationTime INTEGER)`
+	usersTable                   = "users"
+	usersTableSchema             = `CREATE TABLE users (ID INTEGER PRIMARY KEY, email TEXT UNIQUE, password TEXT, emailVerified INTEGER DEFAULT 0, role TEXT DEFAULT 'user', disabled INTEGER DEFAULT 0, currency TEXT DEFAULT '')`
+	userAssetsTable              = "user_assets"
+	userAssetsTableSchema        = `CREATE TABLE user_assets (ID INTEGER PRIMARY KEY, userId INTEGER, assetId INTEGER, FOREIGN KEY (userId) REFERENCES users(ID), CONSTRAINT unique_user_crypto UNIQUE (userId, assetId))`
+	userSessionsTable            = "user_sessions"
+	userSessionsTableSchema      = `CREATE TABLE user_sessions (ID TEXT PRIMARY KEY, userId INTEGER, accessTokenHash TEXT, refreshTokenHash TEXT, userAgent TEXT, ipAddress TEXT, createdAt INTEGER, lastUsedAt INTEGER, expirationTime INTEGER, FOREIGN KEY (userId) REFERENCES users(ID))`
+	refreshTokensTable           = "refresh_tokens"
+	refreshTokensTableSchema     = `CREATE TABLE refresh_tokens (ID TEXT PRIMARY KEY, familyId TEXT, userId INTEGER, issuedAt INTEGER, expirationTime INTEGER, rotatedAt INTEGER DEFAULT 0, revokedAt INTEGER DEFAULT 0, FOREIGN KEY (userId) REFERENCES users(ID))`
+	auditEventsTable             = "audit_events"
+	auditEventsTableSchema       = `CREATE TABLE audit_events (ID INTEGER PRIMARY KEY, userId INTEGER, event TEXT, detail TEXT, userAgent TEXT, ipAddress TEXT, createdAt INTEGER)`
+	userMFATable                 = "user_mfa"
+	userMFATableSchema           = `CREATE TABLE user_mfa (userId INTEGER PRIMARY KEY, secret TEXT, enabled INTEGER DEFAULT 0, lastUsedStep INTEGER DEFAULT 0, createdAt INTEGER, FOREIGN KEY (userId) REFERENCES users(ID))`
+	userRecoveryCodesTable       = "user_recovery_codes"
+	userRecoveryCodesTableSchema = `CREATE TABLE user_recovery_codes (ID INTEGER PRIMARY KEY, userId INTEGER, codeHash TEXT, usedAt INTEGER DEFAULT 0, FOREIGN KEY (userId) REFERENCES users(ID))`
+	userActionTokensTable        = "user_action_tokens"
+	userActionTokensTableSchema  = `CREATE TABLE user_action_tokens (ID TEXT PRIMARY KEY, userId INTEGER, purpose TEXT, expirationTime INTEGER, usedAt INTEGER DEFAULT 0, FOREIGN KEY (userId) REFERENCES users(ID))`
+	loginAttemptsTable           = "login_attempts"
+	loginAttemptsTableSchema     = `CREATE TABLE login_attempts (key TEXT PRIMARY KEY, failures INTEGER, lastFailureAt INTEGER, lockedUntil INTEGER)`
+	accessTokensTable            = "access_tokens"
+	accessTokensTableSchema      = `CREATE TABLE access_tokens (tokenHash TEXT PRIMARY KEY, userId INTEGER, expirationTime INTEGER, FOREIGN KEY (userId) REFERENCES users(ID))`
+	priceHistoryTable            = "price_history"
+	priceHistoryTableSchema      = `CREATE TABLE price_history (assetId TEXT, timestamp INTEGER, price TEXT, resolution INTEGER DEFAULT 0, currency TEXT DEFAULT 'united-states-dollar', PRIMARY KEY (assetId, timestamp))`
+	priceCandlesTable            = "price_candles"
+	priceCandlesTableSchema      = `CREATE TABLE price_candles (assetId TEXT, currency TEXT, resolution INTEGER, openTime INTEGER, open TEXT, high TEXT, low TEXT, close TEXT, volume TEXT, ticks INTEGER, PRIMARY KEY (assetId, currency, resolution, openTime))`
+	assetCatalogTable            = "asset_catalog"
+	assetCatalogTableSchema      = `CREATE TABLE asset_catalog (assetId TEXT PRIMARY KEY, symbol TEXT, name TEXT, rank INTEGER DEFAULT 0, logo TEXT, syncedAt INTEGER)`
+	userAPIKeysTable             = "user_api_keys"
+	userAPIKeysTableSchema       = `CREATE TABLE user_api_keys (ID INTEGER PRIMARY KEY, userId INTEGER, name TEXT, prefix TEXT, keyHash TEXT UNIQUE, scopes TEXT, createdAt INTEGER, expirationTime INTEGER DEFAULT 0, lastUsedAt INTEGER DEFAULT 0, revokedAt INTEGER DEFAULT 0, FOREIGN KEY (userId) REFERENCES users(ID))`
+	userIdentitiesTable          = "user_identities"
+	userIdentitiesTableSchema    = `CREATE TABLE user_identities (ID INTEGER PRIMARY KEY, userId INTEGER, issuer TEXT, subject TEXT, email TEXT, createdAt INTEGER, UNIQUE (issuer, subject), FOREIGN KEY (userId) REFERENCES users(ID))`
+	oidcStatesTable              = "oidc_states"
+	oidcStatesTableSchema        = `CREATE TABLE oidc_states (state TEXT PRIMARY KEY, nonce TEXT, codeVerifier TEXT, expirationTime INTEGER)`
+)
+
+// Tables of older versions that are no longer used. user_tokens held every
+// user's last tokens in plaintext, sessions and refresh tokens replaced it.
+const (
+	userTokensTable = "user_tokens"
+)
+
+// Columns added after the table was first released. Accounts that existed
+// before email verification are treated as verified. Sessions used to keep
+// their tokens in plaintext, those are cleared along with the access tokens
+// they could revoke, and clients get new ones with their refresh token. Price
+// history used to be stored in whatever the target currency was at the time,
+// which isn't known anymore, so it is cleared along with the candles built
+// from it and has to be backfilled again.
+const (
+	usersEmailVerifiedColumn                     = "emailVerified"
+	usersEmailVerifiedColumnDefinition           = "INTEGER DEFAULT 0"
+	usersEmailVerifiedColumnBackfill             = "UPDATE users SET emailVerified = 1"
+	usersRoleColumn                              = "role"
+	usersRoleColumnDefinition                    = "TEXT DEFAULT 'user'"
+	usersDisabledColumn                          = "disabled"
+	usersDisabledColumnDefinition                = "INTEGER DEFAULT 0"
+	usersCurrencyColumn                          = "currency"
+	usersCurrencyColumnDefinition                = "TEXT DEFAULT ''"
+	userSessionsAccessTokenHashColumn            = "accessTokenHash"
+	userSessionsAccessTokenHashColumnDefinition  = "TEXT"
+	userSessionsAccessTokenHashColumnBackfill    = "UPDATE user_sessions SET accessToken = NULL, refreshToken = NULL; DELETE FROM access_tokens"
+	userSessionsRefreshTokenHashColumn           = "refreshTokenHash"
+	userSessionsRefreshTokenHashColumnDefinition = "TEXT"
+	priceHistoryCurrencyColumn                   = "currency"
+	priceHistoryCurrencyColumnDefinition         = "TEXT DEFAULT 'united-states-dollar'"
+	priceHistoryCurrencyColumnBackfill           = "DELETE FROM price_history; DELETE FROM price_candles"
+	priceCandlesVolumeColumn                     = "volume"
+	priceCandlesVolumeColumnDefinition           = "TEXT"
 )
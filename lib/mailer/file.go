@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/lib/random"
+)
+
+// fileMailer drops every message as an .eml file in a directory, so local
+// development and tests can read the links that would have been emailed.
+type fileMailer struct {
+	directory string
+	from      string
+}
+
+func newFileMailer(cfg config.MailerConfig) *fileMailer {
+	return &fileMailer{
+		directory: cfg.Directory,
+		from:      cfg.From,
+	}
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.directory, 0o755); err != nil {
+		return err
+	}
+
+	suffix, err := random.Hex(4)
+	if err != nil {
+		return err
+	}
+
+	currTime := time.Now()
+	fname := filepath.Join(m.directory, fmt.Sprintf("%s-%s.eml", currTime.Format("20060102T150405"), suffix))
+	return os.WriteFile(fname, encode(m.from, msg, currTime), 0o600)
+}
+
+// tokenPattern matches the single-use tokens in the links of a message body.
+var tokenPattern = regexp.MustCompile(`([?&]token=)[^\s&]+`)
+
+// logMailer prints every message to the log with the tokens of its links
+// redacted, as logs are often kept and read by more people than the mail.
+type logMailer struct {
+	from string
+}
+
+func newLogMailer(cfg config.MailerConfig) *logMailer {
+	return &logMailer{
+		from: cfg.From,
+	}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+	msg.Body = tokenPattern.ReplaceAllString(msg.Body, "${1}[redacted]")
+	log.Printf("Sending mail\n%s", encode(m.from, msg, time.Now()))
+	return nil
+}
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+)
+
+const (
+	SMTP = "smtp"
+	File = "file"
+	Log  = "log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer returns the mailer of cfg.Driver, which must be set so links
+// aren't sent somewhere by accident.
+func NewMailer(cfg config.MailerConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case SMTP:
+		return newSMTPMailer(cfg), nil
+	case File:
+		return newFileMailer(cfg), nil
+	case Log:
+		return newLogMailer(cfg), nil
+	case "":
+		return nil, fmt.Errorf("mailer driver is not set")
+	default:
+		return nil, fmt.Errorf("unsupported mailer driver: %s", cfg.Driver)
+	}
+}
+
+// encode renders msg as an RFC 5322 plain text message.
+func encode(from string, msg Message, currTime time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", currTime.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	return buf.Bytes()
+}
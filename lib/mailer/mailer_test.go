@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+)
+
+func TestNewMailerRequiresDriver(t *testing.T) {
+	_, err := NewMailer(config.MailerConfig{})
+	if err == nil {
+		t.Fatalf("NewMailer without a driver succeeded, want an error")
+	}
+}
+
+func TestLogMailerRedactsTokens(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	m, err := NewMailer(config.MailerConfig{Driver: Log})
+	if err != nil {
+		t.Fatalf("NewMailer: %v", err)
+	}
+
+	err = m.Send(context.Background(), Message{To: "user@example.com", Subject: "Reset", Body: "Open https://example.com/reset-password?token=secret.jwt.value to reset.\n"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "secret.jwt.value") || !strings.Contains(buf.String(), "reset-password?token=[redacted] to reset") {
+		t.Fatalf("logged message = %q, want the token redacted", buf.String())
+	}
+}
+
+func TestFileMailerWritesMessage(t *testing.T) {
+	directory := t.TempDir()
+	m, err := NewMailer(config.MailerConfig{Driver: File, Directory: directory, From: "from@example.com"})
+	if err != nil {
+		t.Fatalf("NewMailer: %v", err)
+	}
+
+	err = m.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "/verify-email?token=abc\n"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got files %v (%v), want 1", files, err)
+	}
+	message, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(message), "To: user@example.com\r\n") || !strings.HasSuffix(string(message), "/verify-email?token=abc\n") {
+		t.Fatalf("message = %q", message)
+	}
+}
@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+)
+
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func newSMTPMailer(cfg config.MailerConfig) *smtpMailer {
+	var auth smtp.Auth
+	if cfg.SMTP.Username != "" {
+		auth = smtp.PlainAuth("", cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.Host)
+	}
+
+	return &smtpMailer{
+		addr: fmt.Sprintf("%s:%d", cfg.SMTP.Host, cfg.SMTP.Port),
+		auth: auth,
+		from: cfg.From,
+	}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, encode(m.from, msg, time.Now()))
+}
@@ -0,0 +1,134 @@
+// Package money rounds decimal amounts to the precision configured for their
+// currency.
+package money
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	RoundHalfUp   = "half_up"
+	RoundHalfEven = "half_even"
+	RoundUp       = "up"
+	RoundDown     = "down"
+	RoundCeiling  = "ceiling"
+	RoundFloor    = "floor"
+
+	defaultPrecision = 8
+	defaultRounding  = RoundHalfEven
+
+	// Digits kept beyond a currency's precision when dividing, before the
+	// result is rounded with the currency's rounding mode.
+	divisionGuardDigits = 16
+)
+
+var ErrInvalidRate = errors.New("rate must be positive")
+
+type currency struct {
+	precision int32
+	rounding  string
+}
+
+var defaultCurrency = currency{precision: defaultPrecision, rounding: defaultRounding}
+var currencies = map[string]currency{}
+
+// SetMoneyConfig sets the precision and rounding mode of each currency,
+// currencies not listed use the default ones.
+func SetMoneyConfig(cfg config.MoneyConfig) error {
+	newDefault, err := toCurrency(cfg.Default, currency{precision: defaultPrecision, rounding: defaultRounding})
+	if err != nil {
+		return fmt.Errorf("default currency: %w", err)
+	}
+
+	newCurrencies := make(map[string]currency, len(cfg.Currencies))
+	for name, currencyCfg := range cfg.Currencies {
+		newCurrencies[name], err = toCurrency(currencyCfg, newDefault)
+		if err != nil {
+			return fmt.Errorf("currency %s: %w", name, err)
+		}
+	}
+
+	defaultCurrency = newDefault
+	currencies = newCurrencies
+	return nil
+}
+
+func toCurrency(cfg config.CurrencyConfig, fallback currency) (currency, error) {
+	c := fallback
+	if cfg.Precision != nil {
+		if *cfg.Precision < 0 {
+			return c, fmt.Errorf("precision must not be negative")
+		}
+		c.precision = *cfg.Precision
+	}
+	if cfg.Rounding != "" {
+		switch cfg.Rounding {
+		case RoundHalfUp, RoundHalfEven, RoundUp, RoundDown, RoundCeiling, RoundFloor:
+			c.rounding = cfg.Rounding
+		default:
+			return c, fmt.Errorf("unknown rounding mode: %s", cfg.Rounding)
+		}
+	}
+	return c, nil
+}
+
+func getCurrency(name string) currency {
+	if c, ok := currencies[name]; ok {
+		return c
+	}
+	return defaultCurrency
+}
+
+// Precision returns the number of decimal places amounts in a currency are
+// rounded to.
+func Precision(currencyName string) int32 {
+	return getCurrency(currencyName).precision
+}
+
+// Round rounds an amount to its currency's precision with its rounding mode.
+func Round(currencyName string, amount decimal.Decimal) decimal.Decimal {
+	c := getCurrency(currencyName)
+
+	switch c.rounding {
+	case RoundHalfUp:
+		return amount.Round(c.precision)
+	case RoundUp:
+		return amount.RoundUp(c.precision)
+	case RoundDown:
+		return amount.RoundDown(c.precision)
+	case RoundCeiling:
+		return amount.RoundCeil(c.precision)
+	case RoundFloor:
+		return amount.RoundFloor(c.precision)
+	default:
+		return amount.RoundBank(c.precision)
+	}
+}
+
+// Convert turns a USD amount into a currency worth rateUSD dollars a unit,
+// rounded for that currency. It returns ErrInvalidRate when rateUSD isn't
+// positive, as the amount can't be priced in that currency.
+func Convert(currencyName string, amountUSD, rateUSD decimal.Decimal) (decimal.Decimal, error) {
+	if !rateUSD.IsPositive() {
+		return decimal.Zero, ErrInvalidRate
+	}
+
+	precision := getCurrency(currencyName).precision + divisionGuardDigits
+	return Round(currencyName, amountUSD.DivRound(rateUSD, precision)), nil
+}
+
+// Average returns the mean of amounts rounded for their currency, or zero
+// when there are none.
+func Average(currencyName string, amounts []decimal.Decimal) decimal.Decimal {
+	if len(amounts) == 0 {
+		return decimal.Zero
+	}
+
+	sum := decimal.Sum(decimal.Zero, amounts...)
+	precision := getCurrency(currencyName).precision + divisionGuardDigits
+	return Round(currencyName, sum.DivRound(decimal.NewFromInt(int64(len(amounts))), precision))
+}
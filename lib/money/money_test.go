@@ -0,0 +1,167 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/shopspring/decimal"
+)
+
+func setTestMoneyConfig(t *testing.T, cfg config.MoneyConfig) {
+	t.Helper()
+
+	err := SetMoneyConfig(cfg)
+	if err != nil {
+		t.Fatalf("SetMoneyConfig: %v", err)
+	}
+	t.Cleanup(func() {
+		SetMoneyConfig(config.MoneyConfig{})
+	})
+}
+
+func precision(p int32) *int32 {
+	return &p
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		rounding string
+		amount   string
+		want     string
+	}{
+		{RoundHalfUp, "1.25", "1.3"},
+		{RoundHalfUp, "-1.25", "-1.3"},
+		{RoundHalfUp, "1.24", "1.2"},
+		{RoundHalfEven, "1.25", "1.2"},
+		{RoundHalfEven, "1.35", "1.4"},
+		{RoundHalfEven, "-1.25", "-1.2"},
+		{RoundUp, "1.21", "1.3"},
+		{RoundUp, "-1.21", "-1.3"},
+		{RoundDown, "1.29", "1.2"},
+		{RoundDown, "-1.29", "-1.2"},
+		{RoundCeiling, "1.21", "1.3"},
+		{RoundCeiling, "-1.29", "-1.2"},
+		{RoundFloor, "1.29", "1.2"},
+		{RoundFloor, "-1.21", "-1.3"},
+	}
+
+	for _, tt := range tests {
+		setTestMoneyConfig(t, config.MoneyConfig{Default: config.CurrencyConfig{Precision: precision(1), Rounding: tt.rounding}})
+
+		got := Round("euro", decimal.RequireFromString(tt.amount))
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("Round(%s) with %s = %s, want %s", tt.amount, tt.rounding, got, tt.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name      string
+		precision int32
+		rounding  string
+		amountUSD string
+		rateUSD   string
+		want      string
+	}{
+		{"default precision", defaultPrecision, RoundHalfEven, "10", "3", "3.33333333"},
+		{"rounded after dividing", 0, RoundHalfEven, "5", "2", "2"},
+		{"guard digits kept for rounding up", 2, RoundUp, "10.000000001", "10", "1.01"},
+		{"guard digits kept for rounding down", 2, RoundDown, "1.9999999999", "1", "1.99"},
+		{"rate above a dollar", 2, RoundHalfUp, "100", "1.25", "80"},
+	}
+
+	for _, tt := range tests {
+		setTestMoneyConfig(t, config.MoneyConfig{Default: config.CurrencyConfig{Precision: precision(tt.precision), Rounding: tt.rounding}})
+
+		got, err := Convert("euro", decimal.RequireFromString(tt.amountUSD), decimal.RequireFromString(tt.rateUSD))
+		if err != nil {
+			t.Fatalf("%s: Convert: %v", tt.name, err)
+		}
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("%s: Convert(%s, %s) = %s, want %s", tt.name, tt.amountUSD, tt.rateUSD, got, tt.want)
+		}
+	}
+}
+
+func TestConvertRejectsInvalidRate(t *testing.T) {
+	for _, rate := range []decimal.Decimal{decimal.Zero, decimal.NewFromInt(-1)} {
+		_, err := Convert("euro", decimal.NewFromInt(10), rate)
+		if !errors.Is(err, ErrInvalidRate) {
+			t.Errorf("Convert with rate %s error = %v, want %v", rate, err, ErrInvalidRate)
+		}
+	}
+}
+
+func TestCurrencyOverrides(t *testing.T) {
+	setTestMoneyConfig(t, config.MoneyConfig{
+		Default: config.CurrencyConfig{Precision: precision(2), Rounding: RoundFloor},
+		Currencies: map[string]config.CurrencyConfig{
+			"indonesian-rupiah": {Precision: precision(0), Rounding: RoundHalfUp},
+			"japanese-yen":      {Precision: precision(0)},
+			"bitcoin":           {Rounding: RoundCeiling},
+		},
+	})
+
+	tests := []struct {
+		currency      string
+		amount        string
+		wantPrecision int32
+		want          string
+	}{
+		{"euro", "1.999", 2, "1.99"},
+		{"indonesian-rupiah", "1.5", 0, "2"},
+		// Settings that aren't overridden come from the default.
+		{"japanese-yen", "1.9", 0, "1"},
+		{"bitcoin", "1.001", 2, "1.01"},
+	}
+
+	for _, tt := range tests {
+		if got := Precision(tt.currency); got != tt.wantPrecision {
+			t.Errorf("Precision(%s) = %d, want %d", tt.currency, got, tt.wantPrecision)
+		}
+		got := Round(tt.currency, decimal.RequireFromString(tt.amount))
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("Round(%s, %s) = %s, want %s", tt.currency, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestSetMoneyConfigRejectsInvalidConfig(t *testing.T) {
+	setTestMoneyConfig(t, config.MoneyConfig{Default: config.CurrencyConfig{Precision: precision(2)}})
+
+	tests := []struct {
+		name string
+		cfg  config.MoneyConfig
+	}{
+		{"negative default precision", config.MoneyConfig{Default: config.CurrencyConfig{Precision: precision(-1)}}},
+		{"unknown default rounding", config.MoneyConfig{Default: config.CurrencyConfig{Rounding: "nearest"}}},
+		{"negative currency precision", config.MoneyConfig{Currencies: map[string]config.CurrencyConfig{"euro": {Precision: precision(-2)}}}},
+		{"unknown currency rounding", config.MoneyConfig{Currencies: map[string]config.CurrencyConfig{"euro": {Rounding: "nearest"}}}},
+	}
+
+	for _, tt := range tests {
+		err := SetMoneyConfig(tt.cfg)
+		if err == nil {
+			t.Errorf("%s: SetMoneyConfig succeeded, want an error", tt.name)
+		}
+	}
+
+	// A rejected config leaves the current one in place.
+	if got := Precision("euro"); got != 2 {
+		t.Fatalf("Precision after rejected configs = %d, want 2", got)
+	}
+}
+
+func TestAverage(t *testing.T) {
+	setTestMoneyConfig(t, config.MoneyConfig{Default: config.CurrencyConfig{Precision: precision(2), Rounding: RoundHalfUp}})
+
+	got := Average("euro", []decimal.Decimal{decimal.NewFromInt(1), decimal.NewFromInt(1), decimal.NewFromInt(2)})
+	if !got.Equal(decimal.RequireFromString("1.33")) {
+		t.Fatalf("Average = %s, want 1.33", got)
+	}
+	if got := Average("euro", nil); !got.IsZero() {
+		t.Fatalf("Average of none = %s, want 0", got)
+	}
+}
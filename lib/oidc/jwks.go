@@ -0,0 +1,91 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// publicKeys decodes the signing keys of the set. Encryption keys and keys of
+// unsupported types are skipped.
+func (s jsonWebKeySet) publicKeys() map[string]interface{} {
+	keys := map[string]interface{}{}
+	for _, jwk := range s.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key := jwk.publicKey()
+		if key != nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys
+}
+
+func (k jsonWebKey) publicKey() interface{} {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) > 4 {
+			return nil
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil
+		}
+		return key
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil
+		}
+		return ed25519.PublicKey(x)
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+
+	// Allowed difference between our clock and the provider's when checking
+	// exp and iat.
+	clockSkew = time.Minute
+
+	// An unknown kid makes us refetch the provider's keys, but no more often
+	// than this so forged tokens can't be used to hammer the provider.
+	minKeysRefreshInterval = time.Minute
+
+	maxResponseSize = 1 << 20
+)
+
+var defaultScopes = []string{"openid", "email"}
+
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDToken holds the verified claims of an ID token that we care about.
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// Provider talks to a single OpenID Connect provider using the authorization
+// code flow with PKCE. The discovery document and signing keys are fetched on
+// first use, so the provider doesn't need to be reachable at startup.
+type Provider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *Discovery
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func NewProvider(cfg config.OIDCConfig) (*Provider, error) {
+	if cfg.Issuer == "" {
+		return nil, errors.New("oidc issuer is required")
+	}
+	if cfg.ClientID == "" {
+		return nil, errors.New("oidc client_id is required")
+	}
+	if cfg.RedirectURL == "" {
+		return nil, errors.New("oidc redirect_url is required")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = defaultScopes
+	}
+
+	return &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *Provider) Issuer() string {
+	return p.cfg.Issuer
+}
+
+// CodeChallenge derives the S256 PKCE challenge sent with the authorization
+// request from the verifier kept until the code is exchanged.
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns where to send the user to sign in with the provider.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the raw ID token.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var tokenResponse struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&tokenResponse)
+	if err != nil {
+		return "", fmt.Errorf("token endpoint returned status code %d: %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status code %d: %s %s", resp.StatusCode, tokenResponse.Error, tokenResponse.ErrorDescription)
+	}
+
+	if tokenResponse.IDToken == "" {
+		return "", errors.New("token endpoint returned no id_token")
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, lifetime and nonce of
+// an ID token.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	parser := jwt.Parser{
+		ValidMethods:         []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg(), jwt.SigningMethodEdDSA.Alg()},
+		SkipClaimsValidation: true,
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	currTime := time.Now()
+
+	if iss, _ := claims["iss"].(string); iss != p.cfg.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, iss)
+	}
+
+	if !hasAudience(claims, p.cfg.ClientID) {
+		return nil, fmt.Errorf("%w: client is not in audience", ErrInvalidIDToken)
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: unexpected authorized party %q", ErrInvalidIDToken, azp)
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || currTime.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: token is expired", ErrInvalidIDToken)
+	}
+	if iat, ok := claims["iat"].(float64); ok && time.Unix(int64(iat), 0).After(currTime.Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: token used before issued", ErrInvalidIDToken)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	idToken := &IDToken{
+		Issuer:  p.cfg.Issuer,
+		Subject: subject,
+	}
+	idToken.Email, _ = claims["email"].(string)
+
+	// Some providers send email_verified as a string.
+	switch emailVerified := claims["email_verified"].(type) {
+	case bool:
+		idToken.EmailVerified = emailVerified
+	case string:
+		idToken.EmailVerified = emailVerified == "true"
+	}
+
+	return idToken, nil
+}
+
+func hasAudience(claims jwt.MapClaims, clientId string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == clientId
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Provider) discover(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery Discovery
+	err := p.getJSON(ctx, strings.TrimSuffix(p.cfg.Issuer, "/")+discoveryPath, &discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	if discovery.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery document issuer %q does not match %q", discovery.Issuer, p.cfg.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// publicKey looks up a provider signing key by kid. Tokens without a kid are
+// accepted only while the provider publishes a single key.
+func (p *Provider) publicKey(ctx context.Context, kid string) (interface{}, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	if p.keys != nil && time.Since(p.keysFetchedAt) < minKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	var set jsonWebKeySet
+	err = p.getJSON(ctx, discovery.JWKSURI, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	p.keys = set.publicKeys()
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (p *Provider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" {
+		if len(p.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status code %d", endpoint, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
@@ -0,0 +1,215 @@
+// Package oidctest is a minimal OpenID Connect provider for trying out and
+// testing OIDC login. It signs every user in without asking, as the email
+// passed in login_hint or as Email.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const keyId = "mock-oidc"
+
+type authorization struct {
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	email         string
+}
+
+// Provider serves the discovery document, keys, authorization and token
+// endpoints of an issuer. Its fields may be changed between requests, such as
+// to set Issuer once an httptest.Server has picked its URL.
+type Provider struct {
+	Issuer        string
+	ClientID      string
+	Email         string
+	EmailVerified bool
+
+	key *rsa.PrivateKey
+	mux *http.ServeMux
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+// NewProvider generates the provider's signing key. Users are signed in as
+// Email with a verified address unless told otherwise.
+func NewProvider(issuer, clientId string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		Issuer:        issuer,
+		ClientID:      clientId,
+		Email:         "oidc-user@example.com",
+		EmailVerified: true,
+		key:           key,
+		mux:           http.NewServeMux(),
+		codes:         map[string]authorization{},
+	}
+
+	p.mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	p.mux.HandleFunc("/jwks", p.jwks)
+	p.mux.HandleFunc("/authorize", p.authorize)
+	p.mux.HandleFunc("/token", p.token)
+	return p, nil
+}
+
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyId,
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("response_type") != "code" || query.Get("client_id") != p.ClientID {
+		http.Error(w, "unsupported response_type or unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomHex(16)
+	userEmail := query.Get("login_hint")
+	if userEmail == "" {
+		userEmail = p.Email
+	}
+
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		redirectURI:   redirectURI.String(),
+		codeChallenge: query.Get("code_challenge"),
+		nonce:         query.Get("nonce"),
+		email:         userEmail,
+	}
+	p.mu.Unlock()
+
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.FormValue("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "")
+		return
+	}
+
+	p.mu.Lock()
+	auth, ok := p.codes[r.FormValue("code")]
+	delete(p.codes, r.FormValue("code"))
+	p.mu.Unlock()
+
+	if !ok || auth.redirectURI != r.FormValue("redirect_uri") {
+		tokenError(w, "invalid_grant", "unknown code or redirect_uri mismatch")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge {
+		tokenError(w, "invalid_grant", "code_verifier does not match code_challenge")
+		return
+	}
+
+	currTime := time.Now()
+	subject := sha256.Sum256([]byte(auth.email))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            p.Issuer,
+		"sub":            hex.EncodeToString(subject[:8]),
+		"aud":            p.ClientID,
+		"iat":            currTime.Unix(),
+		"exp":            currTime.Add(5 * time.Minute).Unix(),
+		"nonce":          auth.nonce,
+		"email":          auth.email,
+		"email_verified": p.EmailVerified,
+	})
+	token.Header["kid"] = keyId
+
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		tokenError(w, "server_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomHex(16),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
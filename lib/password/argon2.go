@@ -0,0 +1,137 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	defaultArgon2Memory      = 64 * 1024
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+	defaultArgon2SaltLength  = 16
+	defaultArgon2KeyLength   = 32
+)
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+type argon2idAlgorithm struct {
+	params argon2Params
+}
+
+func newArgon2idAlgorithm(cfg config.Argon2Config) *argon2idAlgorithm {
+	params := argon2Params{
+		memory:      cfg.Memory,
+		iterations:  cfg.Iterations,
+		parallelism: cfg.Parallelism,
+		saltLength:  cfg.SaltLength,
+		keyLength:   cfg.KeyLength,
+	}
+	if params.memory == 0 {
+		params.memory = defaultArgon2Memory
+	}
+	if params.iterations == 0 {
+		params.iterations = defaultArgon2Iterations
+	}
+	if params.parallelism == 0 {
+		params.parallelism = defaultArgon2Parallelism
+	}
+	if params.saltLength == 0 {
+		params.saltLength = defaultArgon2SaltLength
+	}
+	if params.keyLength == 0 {
+		params.keyLength = defaultArgon2KeyLength
+	}
+
+	return &argon2idAlgorithm{params: params}
+}
+
+func (a *argon2idAlgorithm) hash(password string) (string, error) {
+	salt := make([]byte, a.params.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.params.iterations, a.params.memory, a.params.parallelism, a.params.keyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		Argon2id,
+		argon2.Version,
+		a.params.memory,
+		a.params.iterations,
+		a.params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *argon2idAlgorithm) verify(password, encodedHash string) (bool, error) {
+	params, salt, key, err := decodeArgon2Hash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, params.keyLength)
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+func (a *argon2idAlgorithm) needsRehash(encodedHash string) bool {
+	params, salt, _, err := decodeArgon2Hash(encodedHash)
+	if err != nil {
+		return true
+	}
+
+	params.saltLength = uint32(len(salt))
+	return params != a.params
+}
+
+func (a *argon2idAlgorithm) canVerify(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$"+Argon2id+"$")
+}
+
+func decodeArgon2Hash(encodedHash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: unsupported argon2 version %d", ErrInvalidHash, version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	params.keyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,56 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptAlgorithm struct {
+	cost int
+}
+
+func newBcryptAlgorithm(cfg config.BcryptConfig) *bcryptAlgorithm {
+	cost := cfg.Cost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+
+	return &bcryptAlgorithm{cost: cost}
+}
+
+func (b *bcryptAlgorithm) hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (b *bcryptAlgorithm) verify(password, encodedHash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (b *bcryptAlgorithm) needsRehash(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	if err != nil {
+		return true
+	}
+
+	return cost != b.cost
+}
+
+func (b *bcryptAlgorithm) canVerify(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") || strings.HasPrefix(encodedHash, "$2b$") || strings.HasPrefix(encodedHash, "$2y$")
+}
@@ -0,0 +1,81 @@
+package password
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Hasher hashes passwords with the configured algorithm and verifies them
+// against any supported encoding. Verify also reports whether the stored hash
+// should be replaced, either because its parameters are outdated or because it
+// is a legacy plaintext value.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encodedHash string) (match bool, needsRehash bool, err error)
+}
+
+type algorithm interface {
+	hash(password string) (string, error)
+	verify(password, encodedHash string) (bool, error)
+	needsRehash(encodedHash string) bool
+	canVerify(encodedHash string) bool
+}
+
+type hasher struct {
+	current    algorithm
+	algorithms []algorithm
+}
+
+func NewHasher(cfg config.PasswordConfig) (Hasher, error) {
+	argon := newArgon2idAlgorithm(cfg.Argon2)
+	bcrypt := newBcryptAlgorithm(cfg.Bcrypt)
+
+	h := &hasher{
+		algorithms: []algorithm{argon, bcrypt},
+	}
+
+	switch cfg.Algorithm {
+	case "", Argon2id:
+		h.current = argon
+	case Bcrypt:
+		h.current = bcrypt
+	default:
+		return nil, fmt.Errorf("unsupported password hashing algorithm: %s", cfg.Algorithm)
+	}
+
+	return h, nil
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	return h.current.hash(password)
+}
+
+func (h *hasher) Verify(password, encodedHash string) (bool, bool, error) {
+	for _, alg := range h.algorithms {
+		if !alg.canVerify(encodedHash) {
+			continue
+		}
+
+		match, err := alg.verify(password, encodedHash)
+		if err != nil || !match {
+			return false, false, err
+		}
+
+		return true, alg != h.current || alg.needsRehash(encodedHash), nil
+	}
+
+	// Rows written before passwords were hashed hold the plaintext value,
+	// which is anything no algorithm recognizes as its encoding.
+	match := subtle.ConstantTimeCompare([]byte(password), []byte(encodedHash)) == 1
+	return match, match, nil
+}
@@ -0,0 +1,73 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+)
+
+func newTestHasher(t *testing.T, algorithm string) Hasher {
+	t.Helper()
+
+	h, err := NewHasher(config.PasswordConfig{
+		Algorithm: algorithm,
+		Argon2:    config.Argon2Config{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		Bcrypt:    config.BcryptConfig{Cost: 4},
+	})
+	if err != nil {
+		t.Fatalf("NewHasher: %v", err)
+	}
+	return h
+}
+
+func TestVerifyHashed(t *testing.T) {
+	for _, algorithm := range []string{Argon2id, Bcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			h := newTestHasher(t, algorithm)
+
+			encodedHash, err := h.Hash("s3cret")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+
+			match, needsRehash, err := h.Verify("s3cret", encodedHash)
+			if err != nil || !match || needsRehash {
+				t.Fatalf("Verify(right password) = %v, %v, %v", match, needsRehash, err)
+			}
+
+			match, _, err = h.Verify("wrong", encodedHash)
+			if err != nil || match {
+				t.Fatalf("Verify(wrong password) = %v, %v", match, err)
+			}
+		})
+	}
+}
+
+func TestVerifyOtherAlgorithmNeedsRehash(t *testing.T) {
+	encodedHash, err := newTestHasher(t, Bcrypt).Hash("s3cret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	match, needsRehash, err := newTestHasher(t, Argon2id).Verify("s3cret", encodedHash)
+	if err != nil || !match || !needsRehash {
+		t.Fatalf("Verify = %v, %v, %v, want a match that needs a rehash", match, needsRehash, err)
+	}
+}
+
+func TestVerifyPlaintext(t *testing.T) {
+	h := newTestHasher(t, Argon2id)
+
+	// Legacy plaintext passwords may start with $ without being a hash.
+	for _, plaintext := range []string{"hunter2", "$ecret", "$2x$not-bcrypt"} {
+		match, needsRehash, err := h.Verify(plaintext, plaintext)
+		if err != nil || !match || !needsRehash {
+			t.Errorf("Verify(%q) = %v, %v, %v, want a match that needs a rehash", plaintext, match, needsRehash, err)
+		}
+
+		match, _, err = h.Verify("other", plaintext)
+		if err != nil || match {
+			t.Errorf("Verify(other, %q) = %v, %v, want no match", plaintext, match, err)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package random
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Bytes returns n cryptographically random bytes.
+func Bytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Hex returns n cryptographically random bytes encoded as a hex string.
+func Hex(n int) (string, error) {
+	b, err := Bytes(n)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,75 @@
+package tokenstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+const (
+	defaultCacheSize     = 10000
+	defaultCacheDuration = 10 * time.Second
+)
+
+type lruStore struct {
+	next  Store
+	cache *expirable.LRU[string, struct{}]
+}
+
+// NewLRUStore puts an in-memory LRU of recently rejected tokens in front of
+// next, so replayed revoked or expired tokens don't touch the database. Only
+// misses are cached: a token never becomes valid again once it is gone, while
+// caching hits would keep accepting a token revoked by another instance.
+func NewLRUStore(next Store, size int, ttl time.Duration) Store {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	if ttl <= 0 {
+		ttl = defaultCacheDuration
+	}
+
+	return &lruStore{
+		next:  next,
+		cache: expirable.NewLRU[string, struct{}](size, nil, ttl),
+	}
+}
+
+func (s *lruStore) Add(ctx context.Context, token string, userId int, expiresAt time.Time) error {
+	err := s.next.Add(ctx, token, userId, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	s.cache.Remove(Hash(token))
+	return nil
+}
+
+func (s *lruStore) Contains(ctx context.Context, token string) (bool, error) {
+	tokenHash := Hash(token)
+	if _, ok := s.cache.Get(tokenHash); ok {
+		return false, nil
+	}
+
+	ok, err := s.next.Contains(ctx, token)
+	if err != nil {
+		return false, err
+	}
+
+	if !ok {
+		s.cache.Add(tokenHash, struct{}{})
+	}
+	return ok, nil
+}
+
+func (s *lruStore) Remove(ctx context.Context, tokenHashes ...string) error {
+	err := s.next.Remove(ctx, tokenHashes...)
+	if err != nil {
+		return err
+	}
+
+	for _, tokenHash := range tokenHashes {
+		s.cache.Add(tokenHash, struct{}{})
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package tokenstore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// mapStore stands in for the shared database and counts lookups.
+type mapStore struct {
+	tokens  map[string]bool
+	lookups int
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{tokens: map[string]bool{}}
+}
+
+func (s *mapStore) Add(ctx context.Context, token string, userId int, expiresAt time.Time) error {
+	s.tokens[Hash(token)] = true
+	return nil
+}
+
+func (s *mapStore) Contains(ctx context.Context, token string) (bool, error) {
+	s.lookups++
+	return s.tokens[Hash(token)], nil
+}
+
+func (s *mapStore) Remove(ctx context.Context, tokenHashes ...string) error {
+	for _, tokenHash := range tokenHashes {
+		delete(s.tokens, tokenHash)
+	}
+	return nil
+}
+
+func TestLRUStoreSeesRemovalByAnotherInstance(t *testing.T) {
+	ctx := context.Background()
+	shared := newMapStore()
+	here := NewLRUStore(shared, 10, time.Minute)
+	there := NewLRUStore(shared, 10, time.Minute)
+
+	err := here.Add(ctx, "token", 1, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	ok, err := here.Contains(ctx, "token")
+	if err != nil || !ok {
+		t.Fatalf("Contains after Add = %v, %v", ok, err)
+	}
+
+	err = there.Remove(ctx, Hash("token"))
+	if err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	ok, err = here.Contains(ctx, "token")
+	if err != nil || ok {
+		t.Fatalf("Contains after another instance removed the token = %v, %v", ok, err)
+	}
+}
+
+func TestLRUStoreCachesMisses(t *testing.T) {
+	ctx := context.Background()
+	shared := newMapStore()
+	store := NewLRUStore(shared, 10, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		ok, err := store.Contains(ctx, "unknown")
+		if err != nil || ok {
+			t.Fatalf("Contains(unknown) = %v, %v", ok, err)
+		}
+	}
+	if shared.lookups != 1 {
+		t.Fatalf("%d lookups for a rejected token, want 1", shared.lookups)
+	}
+
+	err := store.Add(ctx, "token", 1, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	err = store.Remove(ctx, Hash("token"))
+	if err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	lookups := shared.lookups
+	ok, err := store.Contains(ctx, "token")
+	if err != nil || ok {
+		t.Fatalf("Contains after Remove = %v, %v", ok, err)
+	}
+	if shared.lookups != lookups {
+		t.Fatal("a token removed through the store was looked up again")
+	}
+}
@@ -0,0 +1,25 @@
+package tokenstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Store keeps track of the access tokens that are currently valid. A token is
+// valid from Add until it expires or is removed. Implementations backed by a
+// shared database let every instance of the app see the same tokens, so a
+// restart doesn't sign anyone out. Tokens are removed by their Hash, which is
+// all that sessions keep of them.
+type Store interface {
+	Add(ctx context.Context, token string, userId int, expiresAt time.Time) error
+	Contains(ctx context.Context, token string) (bool, error)
+	Remove(ctx context.Context, tokenHashes ...string) error
+}
+
+// Hash returns the hex encoded SHA-256 of token.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
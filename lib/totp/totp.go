@@ -0,0 +1,90 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Parameters follow the RFC 6238 defaults, which is what authenticator apps
+// assume when the provisioning URI doesn't say otherwise.
+const (
+	secretLength = 20
+	digits       = 6
+	period       = 30
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+func ProvisioningURI(issuer, accountName, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(digits))
+	query.Set("period", fmt.Sprint(period))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Validate checks code against the time steps around currTime, allowing skew
+// steps of clock drift in either direction. It returns the matching step so
+// callers can refuse to accept the same code twice.
+func Validate(code, secret string, currTime time.Time, skew int) (int64, bool) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != digits {
+		return 0, false
+	}
+
+	currStep := currTime.Unix() / period
+	for i := -skew; i <= skew; i++ {
+		step := currStep + int64(i)
+		if subtle.ConstantTimeCompare([]byte(generateCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// GenerateCode returns the code an authenticator app shows for secret at
+// currTime.
+func GenerateCode(secret string, currTime time.Time) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	return generateCode(key, currTime.Unix()/period), nil
+}
+
+func generateCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, value%1000000)
+}
@@ -0,0 +1,70 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890", in base32.
+var rfc6238Secret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes, the last 6 digits are the 6 digit code.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := GenerateCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateCode: %v", err)
+		}
+		if want := tt.want[len(tt.want)-digits:]; got != want {
+			t.Errorf("GenerateCode at %d = %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	currTime := time.Unix(1111111111, 0)
+	code, err := GenerateCode(rfc6238Secret, currTime)
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		secret   string
+		currTime time.Time
+		wantOk   bool
+	}{
+		{"current step", code, rfc6238Secret, currTime, true},
+		{"lowercase secret", code, strings.ToLower(rfc6238Secret), currTime, true},
+		{"step within skew", code, rfc6238Secret, currTime.Add(period * time.Second), true},
+		{"step beyond skew", code, rfc6238Secret, currTime.Add(2 * period * time.Second), false},
+		{"wrong code", "000000", rfc6238Secret, currTime, false},
+		{"wrong length", code[1:], rfc6238Secret, currTime, false},
+		{"invalid secret", code, "not base32!", currTime, false},
+	}
+
+	for _, tt := range tests {
+		step, ok := Validate(tt.code, tt.secret, tt.currTime, 1)
+		if ok != tt.wantOk {
+			t.Errorf("%s: Validate = %v, want %v", tt.name, ok, tt.wantOk)
+		}
+		if ok && step != currTime.Unix()/period {
+			t.Errorf("%s: Validate step = %d, want %d", tt.name, step, currTime.Unix()/period)
+		}
+	}
+}
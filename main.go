@@ -2,47 +2,143 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/michaelwongycn/crypto-tracker/controller"
 	"github.com/michaelwongycn/crypto-tracker/handler"
+	"github.com/michaelwongycn/crypto-tracker/handler/middleware"
 	"github.com/michaelwongycn/crypto-tracker/lib/auth"
 	"github.com/michaelwongycn/crypto-tracker/lib/cache"
 	"github.com/michaelwongycn/crypto-tracker/lib/cfg"
 	"github.com/michaelwongycn/crypto-tracker/lib/db"
+	"github.com/michaelwongycn/crypto-tracker/lib/mailer"
+	"github.com/michaelwongycn/crypto-tracker/lib/money"
+	"github.com/michaelwongycn/crypto-tracker/lib/oidc"
+	"github.com/michaelwongycn/crypto-tracker/lib/password"
+	"github.com/michaelwongycn/crypto-tracker/lib/tokenstore"
 	"github.com/michaelwongycn/crypto-tracker/repository/cryptoDB"
 	"github.com/michaelwongycn/crypto-tracker/repository/cryptoREST"
+	"github.com/michaelwongycn/crypto-tracker/usecase/asset"
+	"github.com/michaelwongycn/crypto-tracker/usecase/price"
 	"github.com/michaelwongycn/crypto-tracker/usecase/user"
 )
 
+var unlockLogin = flag.String("unlock-login", "", "clear the login lockout of an email or IP address and exit")
+var setRole = flag.String("set-role", "", "set the role of a user, given as <email>=<user|support|admin>, and exit")
+var backfillHistory = flag.String("backfill-history", "", "store the price history of comma separated asset ids from the price API and exit")
+var backfillDays = flag.Int("backfill-days", 30, "number of days of price history -backfill-history stores")
+
 func main() {
 	cfg, err := cfg.ReadConfig()
 	if err != nil {
 		log.Printf("Error reading config: %v\n", err)
 	}
 
-	auth.SetAuthConfig(cfg.JWT.SecretKey, cfg.JWT.AccessTokenDuration, cfg.JWT.RefreshTokenDuration)
-	cache.InitializeNewCache(*cfg)
+	err = auth.SetAuthConfig(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Error configuring JWT keys: %v\n", err)
+	}
+
+	err = money.SetMoneyConfig(cfg.Money)
+	if err != nil {
+		log.Fatalf("Error configuring money: %v\n", err)
+	}
+
+	cache, err := cache.NewCache(cfg.Cache)
+	if err != nil {
+		log.Fatalf("Error configuring cache: %v\n", err)
+	}
+
 	db, err := db.Connect(cfg.Database.Timeout, cfg.Database.DBName)
 	if err != nil {
 		log.Printf("Error connecting to DB: %v\n", err)
 	}
 
+	tokenStore := tokenstore.NewLRUStore(cryptoDB.NewTokenStore(60, db), cfg.TokenStore.CacheSize, time.Second*cfg.TokenStore.CacheDuration)
 	cryptoDB := cryptoDB.NewCryptoDBImpl(60, db)
-	cryptoREST := cryptoREST.NewCryptoRESTImpl(60, cfg.Rest.Coincap.BaseURL, cfg.Rest.Coincap.AssetEndpoint, cfg.Rest.Coincap.RatesEndpoint, cfg.Rest.Coincap.TargetCurrency)
+	cryptoREST, err := cryptoREST.NewCryptoRESTImpl(cfg.Rest.Timeout, cache, cfg.Rest)
+	if err != nil {
+		log.Fatalf("Error configuring market data providers: %v\n", err)
+	}
+
+	passwordHasher, err := password.NewHasher(cfg.Password)
+	if err != nil {
+		log.Fatalf("Error configuring password hasher: %v\n", err)
+	}
+
+	mailer, err := mailer.NewMailer(cfg.Mailer)
+	if err != nil {
+		log.Fatalf("Error configuring mailer: %v\n", err)
+	}
 
-	userUsecase := user.NewUserImpl(cryptoDB, cryptoREST, cfg.JWT.RefreshTokenDuration)
+	var oidcProvider *oidc.Provider
+	if cfg.OIDC.Issuer != "" {
+		oidcProvider, err = oidc.NewProvider(cfg.OIDC)
+		if err != nil {
+			log.Fatalf("Error configuring OIDC provider: %v\n", err)
+		}
+	}
 
-	controller := controller.NewControllerImpl(userUsecase)
+	pricePoller := price.NewPoller(cryptoDB, cryptoREST, cfg.Price)
+	assetCatalog := asset.NewCatalog(cryptoDB, cryptoREST, cfg.Catalog)
+	priceUsecase := price.NewPriceImpl(cryptoDB, cryptoREST, cfg.Price.History, cfg.Rest.Coincap.TargetCurrency)
+	userUsecase := user.NewUserImpl(cryptoDB, tokenStore, cryptoREST, pricePoller, passwordHasher, mailer, cfg.Account, cfg.Login, oidcProvider, cfg.OIDC.StateDuration, cfg.JWT.RefreshTokenDuration)
 
-	handler := handler.NewHandler(60, controller)
+	if *unlockLogin != "" {
+		err = userUsecase.ClearLoginLockout(context.Background(), *unlockLogin)
+		if err != nil {
+			log.Printf("Error clearing login lockout for %s: %v\n", *unlockLogin, err)
+		} else {
+			log.Printf("Cleared login lockout for %s\n", *unlockLogin)
+		}
+		db.Close()
+		return
+	}
+
+	if *setRole != "" {
+		email, role := *setRole, ""
+		if i := strings.LastIndex(*setRole, "="); i >= 0 {
+			email, role = (*setRole)[:i], (*setRole)[i+1:]
+		}
+		err = userUsecase.SetUserRole(context.Background(), email, role)
+		if err != nil {
+			log.Printf("Error setting role of %s: %v\n", email, err)
+		} else {
+			log.Printf("Set role of %s to %s\n", email, role)
+		}
+		db.Close()
+		return
+	}
+
+	if *backfillHistory != "" {
+		for _, assetId := range strings.Split(*backfillHistory, ",") {
+			count, err := priceUsecase.BackfillPriceHistory(context.Background(), assetId, *backfillDays)
+			if err != nil {
+				log.Printf("Error backfilling price history of %s: %v\n", assetId, err)
+			} else {
+				log.Printf("Backfilled %d prices of %s\n", count, assetId)
+			}
+		}
+		db.Close()
+		return
+	}
+
+	pricePoller.Start()
+	assetCatalog.Start()
+
+	controller := controller.NewControllerImpl(userUsecase, priceUsecase, assetCatalog)
+	middleware := middleware.NewMiddleware(userUsecase, tokenStore)
+
+	handler := handler.NewHandler(60, controller, middleware)
 
 	rest := handler.StartRoute()
 
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 	log.Printf("Shutdown Application ...")
@@ -57,5 +153,13 @@ func main() {
 	if err := rest.Shutdown(ctx); err != nil {
 		log.Printf("Server Shutdown: %v", err)
 	}
+
+	if err := pricePoller.Stop(ctx); err != nil {
+		log.Printf("Price Poller Shutdown: %v", err)
+	}
+
+	if err := assetCatalog.Stop(ctx); err != nil {
+		log.Printf("Asset Catalog Shutdown: %v", err)
+	}
 	log.Printf("Application Stopped")
 }
@@ -4,7 +4,11 @@ USE cryptoTracker;
 CREATE TABLE users (
     ID INTEGER PRIMARY KEY,
     email TEXT UNIQUE,
-    password TEXT
+    password TEXT,
+    emailVerified INTEGER DEFAULT 0,
+    role TEXT DEFAULT 'user',
+    disabled INTEGER DEFAULT 0,
+    currency TEXT DEFAULT ''
 );
 
 CREATE TABLE user_assets (
@@ -15,9 +19,140 @@ CREATE TABLE user_assets (
     CONSTRAINT unique_user_crypto UNIQUE (userId, assetId)
 );
 
-CREATE TABLE user_tokens (
-	userId INTEGER PRIMARY KEY,
-    accessToken TEXT,
-	refreshToken TEXT,
-	expirationTime INTEGER
+CREATE TABLE user_sessions (
+    ID TEXT PRIMARY KEY,
+    userId INTEGER,
+    accessTokenHash TEXT,
+    refreshTokenHash TEXT,
+    userAgent TEXT,
+    ipAddress TEXT,
+    createdAt INTEGER,
+    lastUsedAt INTEGER,
+    expirationTime INTEGER,
+    FOREIGN KEY (userId) REFERENCES users(ID)
+);
+
+CREATE TABLE refresh_tokens (
+    ID TEXT PRIMARY KEY,
+    familyId TEXT,
+    userId INTEGER,
+    issuedAt INTEGER,
+    expirationTime INTEGER,
+    rotatedAt INTEGER DEFAULT 0,
+    revokedAt INTEGER DEFAULT 0,
+    FOREIGN KEY (userId) REFERENCES users(ID)
+);
+
+CREATE TABLE audit_events (
+    ID INTEGER PRIMARY KEY,
+    userId INTEGER,
+    event TEXT,
+    detail TEXT,
+    userAgent TEXT,
+    ipAddress TEXT,
+    createdAt INTEGER
+);
+
+CREATE TABLE user_mfa (
+    userId INTEGER PRIMARY KEY,
+    secret TEXT,
+    enabled INTEGER DEFAULT 0,
+    lastUsedStep INTEGER DEFAULT 0,
+    createdAt INTEGER,
+    FOREIGN KEY (userId) REFERENCES users(ID)
+);
+
+CREATE TABLE user_recovery_codes (
+    ID INTEGER PRIMARY KEY,
+    userId INTEGER,
+    codeHash TEXT,
+    usedAt INTEGER DEFAULT 0,
+    FOREIGN KEY (userId) REFERENCES users(ID)
+);
+
+CREATE TABLE user_action_tokens (
+    ID TEXT PRIMARY KEY,
+    userId INTEGER,
+    purpose TEXT,
+    expirationTime INTEGER,
+    usedAt INTEGER DEFAULT 0,
+    FOREIGN KEY (userId) REFERENCES users(ID)
+);
+
+CREATE TABLE login_attempts (
+    key TEXT PRIMARY KEY,
+    failures INTEGER,
+    lastFailureAt INTEGER,
+    lockedUntil INTEGER
+);
+
+CREATE TABLE user_api_keys (
+    ID INTEGER PRIMARY KEY,
+    userId INTEGER,
+    name TEXT,
+    prefix TEXT,
+    keyHash TEXT UNIQUE,
+    scopes TEXT,
+    createdAt INTEGER,
+    expirationTime INTEGER DEFAULT 0,
+    lastUsedAt INTEGER DEFAULT 0,
+    revokedAt INTEGER DEFAULT 0,
+    FOREIGN KEY (userId) REFERENCES users(ID)
+);
+
+CREATE TABLE user_identities (
+    ID INTEGER PRIMARY KEY,
+    userId INTEGER,
+    issuer TEXT,
+    subject TEXT,
+    email TEXT,
+    createdAt INTEGER,
+    UNIQUE (issuer, subject),
+    FOREIGN KEY (userId) REFERENCES users(ID)
+);
+
+CREATE TABLE oidc_states (
+    state TEXT PRIMARY KEY,
+    nonce TEXT,
+    codeVerifier TEXT,
+    expirationTime INTEGER
+);
+
+CREATE TABLE access_tokens (
+    tokenHash TEXT PRIMARY KEY,
+    userId INTEGER,
+    expirationTime INTEGER,
+    FOREIGN KEY (userId) REFERENCES users(ID)
+);
+
+CREATE TABLE price_history (
+    assetId TEXT,
+    timestamp INTEGER,
+    price TEXT,
+    resolution INTEGER DEFAULT 0,
+    currency TEXT DEFAULT 'united-states-dollar',
+    PRIMARY KEY (assetId, timestamp)
+);
+
+CREATE TABLE price_candles (
+    assetId TEXT,
+    currency TEXT,
+    resolution INTEGER,
+    openTime INTEGER,
+    open TEXT,
+    high TEXT,
+    low TEXT,
+    close TEXT,
+    volume TEXT,
+    ticks INTEGER,
+    PRIMARY KEY (assetId, currency, resolution, openTime)
+);
+
+CREATE TABLE asset_catalog (
+    assetId TEXT PRIMARY KEY,
+    symbol TEXT,
+    name TEXT,
+    rank INTEGER DEFAULT 0,
+    logo TEXT,
+    syncedAt INTEGER
 );
\ No newline at end of file
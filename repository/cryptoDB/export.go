@@ -0,0 +1,181 @@
+package cryptoDB
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+)
+
+// ExportFunc returns what a table holds about a user, shaped for a personal
+// data export. Secrets such as password hashes and tokens must be left out.
+type ExportFunc func(ctx context.Context, d CryptoDBInterface, userId int) (interface{}, error)
+
+type exporter struct {
+	name   string
+	export ExportFunc
+}
+
+var exporters []exporter
+
+// RegisterExporter adds a file to every personal data export. Tables that
+// store data about a user should register one from an init function, name is
+// used as the file name and must be unique.
+func RegisterExporter(name string, export ExportFunc) {
+	for _, e := range exporters {
+		if e.name == name {
+			panic(fmt.Sprintf("cryptoDB: exporter %s registered twice", name))
+		}
+	}
+	exporters = append(exporters, exporter{name: name, export: export})
+}
+
+func (d *cryptoDBImpl) ExportUserData(ctx context.Context, userId int) (*[]model.UserDataFile, error) {
+	files := make([]model.UserDataFile, 0, len(exporters))
+	for _, e := range exporters {
+		data, err := e.export(ctx, d, userId)
+		if err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", e.name, err)
+		}
+		files = append(files, model.UserDataFile{Name: e.name, Data: data})
+	}
+	return &files, nil
+}
+
+type profileExport struct {
+	ID            int    `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	Disabled      bool   `json:"disabled"`
+	Currency      string `json:"currency"`
+}
+
+type sessionExport struct {
+	ID             string `json:"id"`
+	UserAgent      string `json:"user_agent"`
+	IPAddress      string `json:"ip_address"`
+	CreatedAt      int64  `json:"created_at"`
+	LastUsedAt     int64  `json:"last_used_at"`
+	ExpirationTime int64  `json:"expiration_time"`
+}
+
+type apiKeyExport struct {
+	ID             int      `json:"id"`
+	Name           string   `json:"name"`
+	Prefix         string   `json:"prefix"`
+	Scopes         []string `json:"scopes"`
+	CreatedAt      int64    `json:"created_at"`
+	ExpirationTime int64    `json:"expiration_time"`
+	LastUsedAt     int64    `json:"last_used_at"`
+}
+
+type mfaExport struct {
+	Enabled   bool  `json:"enabled"`
+	CreatedAt int64 `json:"created_at"`
+}
+
+func init() {
+	RegisterExporter("profile", exportProfile)
+	RegisterExporter("assets", exportAssets)
+	RegisterExporter("sessions", exportSessions)
+	RegisterExporter("api_keys", exportAPIKeys)
+	RegisterExporter("identities", exportIdentities)
+	RegisterExporter("two_factor_authentication", exportMFA)
+	RegisterExporter("audit_events", exportAuditEvents)
+}
+
+func exportProfile(ctx context.Context, d CryptoDBInterface, userId int) (interface{}, error) {
+	user, err := d.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return profileExport{
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
+		Disabled:      user.Disabled,
+		Currency:      user.Currency,
+	}, nil
+}
+
+func exportAssets(ctx context.Context, d CryptoDBInterface, userId int) (interface{}, error) {
+	assets, err := d.GetUserAssetsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	data := []string{}
+	for _, asset := range *assets {
+		data = append(data, asset.AssetId)
+	}
+	return data, nil
+}
+
+func exportSessions(ctx context.Context, d CryptoDBInterface, userId int) (interface{}, error) {
+	sessions, err := d.GetUserSessionsByUserId(ctx, userId, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	data := []sessionExport{}
+	for _, session := range *sessions {
+		data = append(data, sessionExport{
+			ID:             session.ID,
+			UserAgent:      session.UserAgent,
+			IPAddress:      session.IPAddress,
+			CreatedAt:      session.CreatedAt,
+			LastUsedAt:     session.LastUsedAt,
+			ExpirationTime: session.ExpirationTime,
+		})
+	}
+	return data, nil
+}
+
+func exportAPIKeys(ctx context.Context, d CryptoDBInterface, userId int) (interface{}, error) {
+	apiKeys, err := d.GetUserAPIKeysByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	data := []apiKeyExport{}
+	for _, apiKey := range *apiKeys {
+		data = append(data, apiKeyExport{
+			ID:             apiKey.ID,
+			Name:           apiKey.Name,
+			Prefix:         apiKey.Prefix,
+			Scopes:         apiKey.Scopes,
+			CreatedAt:      apiKey.CreatedAt,
+			ExpirationTime: apiKey.ExpirationTime,
+			LastUsedAt:     apiKey.LastUsedAt,
+		})
+	}
+	return data, nil
+}
+
+func exportIdentities(ctx context.Context, d CryptoDBInterface, userId int) (interface{}, error) {
+	return d.GetUserIdentitiesByUserId(ctx, userId)
+}
+
+func exportMFA(ctx context.Context, d CryptoDBInterface, userId int) (interface{}, error) {
+	mfa, err := d.GetUserMFA(ctx, userId)
+	if err == sql.ErrNoRows {
+		return mfaExport{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return mfaExport{
+		Enabled:   mfa.Enabled,
+		CreatedAt: mfa.CreatedAt,
+	}, nil
+}
+
+func exportAuditEvents(ctx context.Context, d CryptoDBInterface, userId int) (interface{}, error) {
+	return d.GetAuditEventsByUserId(ctx, userId)
+}
@@ -3,10 +3,12 @@ package cryptoDB
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/michaelwongycn/crypto-tracker/domain/model"
 	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/shopspring/decimal"
 )
 
 const (
@@ -27,14 +29,34 @@ func NewCryptoDBImpl(timeout time.Duration, db *sql.DB) CryptoDBInterface {
 	}
 }
 
-func (d *cryptoDBImpl) GetUserByEmailAndPassword(ctx context.Context, email, password string) (*model.User, error) {
+func (d *cryptoDBImpl) GetUserById(ctx context.Context, userId int) (*model.User, error) {
 	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
 	defer cancelfunc()
 
 	var data model.User
-	row := d.db.QueryRowContext(ctx, getUserByEmailAndPasswordQuery, email, password)
+	row := d.db.QueryRowContext(ctx, getUserByIdQuery, userId)
 
-	err := row.Scan(&data.ID)
+	err := row.Scan(&data.ID, &data.Email, &data.Password, &data.EmailVerified, &data.Role, &data.Disabled, &data.Currency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
+			return nil, err
+		} else {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+	}
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	var data model.User
+	row := d.db.QueryRowContext(ctx, getUserByEmailQuery, email)
+
+	err := row.Scan(&data.ID, &data.Email, &data.Password, &data.EmailVerified, &data.Role, &data.Disabled, &data.Currency)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
@@ -60,16 +82,188 @@ func (d *cryptoDBImpl) InsertUser(ctx context.Context, email, password string) e
 	return nil
 }
 
-func (d *cryptoDBImpl) GetUserToken(ctx context.Context, userId int) (*model.UserToken, error) {
+// InsertUserWithIdentity creates an account for someone signing in through an
+// OpenID Connect provider for the first time and links the identity to it.
+func (d *cryptoDBImpl) InsertUserWithIdentity(ctx context.Context, email, password string, identity model.UserIdentity) (int, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, insertVerifiedUserQuery, email, password)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return 0, err
+	}
+
+	userId, err := result.LastInsertId()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, insertUserIdentityQuery, userId, identity.Issuer, identity.Subject, identity.Email, identity.CreatedAt)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return 0, err
+	}
+
+	return int(userId), nil
+}
+
+func (d *cryptoDBImpl) UpdateUserPassword(ctx context.Context, userId int, password string) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, updateUserPasswordQuery, password, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) UpdateUserEmailVerified(ctx context.Context, userId int, emailVerified bool) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, updateUserEmailVerifiedQuery, emailVerified, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) UpdateUserEmail(ctx context.Context, userId int, email string) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, updateUserEmailQuery, email, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUser removes a user together with their assets, sessions, tokens and
+// every other row that references them, all or nothing.
+func (d *cryptoDBImpl) DeleteUser(ctx context.Context, userId int) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range deleteUserQueries {
+		_, err = tx.ExecContext(ctx, query, userId)
+		if err != nil {
+			log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) UpdateUserRole(ctx context.Context, userId int, role string) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, updateUserRoleQuery, role, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) UpdateUserCurrency(ctx context.Context, userId int, currency string) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, updateUserCurrencyQuery, currency, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) UpdateUserDisabled(ctx context.Context, userId int, disabled bool) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, updateUserDisabledQuery, disabled, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// SearchUsers returns a page of users whose email matches emailPattern, a
+// LIKE pattern escaped with a backslash.
+func (d *cryptoDBImpl) SearchUsers(ctx context.Context, emailPattern string, limit, offset int) (*[]model.User, error) {
 	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
 	defer cancelfunc()
 
-	UserToken := model.UserToken{
-		UserId: userId,
+	rows, err := d.db.QueryContext(ctx, searchUsersQuery, emailPattern, limit, offset)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []model.User{}
+	for rows.Next() {
+		var user model.User
+		err := rows.Scan(&user.ID, &user.Email, &user.Password, &user.EmailVerified, &user.Role, &user.Disabled, &user.Currency)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		data = append(data, user)
 	}
-	row := d.db.QueryRowContext(ctx, getUserTokenQuery, userId)
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) GetUserSession(ctx context.Context, sessionId string) (*model.UserSession, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	var data model.UserSession
+	row := d.db.QueryRowContext(ctx, getUserSessionQuery, sessionId)
 
-	err := row.Scan(&UserToken.AccessToken, &UserToken.RefreshToken, &UserToken.ExpirationTime)
+	err := row.Scan(&data.ID, &data.UserId, &data.AccessTokenHash, &data.RefreshTokenHash, &data.UserAgent, &data.IPAddress, &data.CreatedAt, &data.LastUsedAt, &data.ExpirationTime)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
@@ -79,14 +273,38 @@ func (d *cryptoDBImpl) GetUserToken(ctx context.Context, userId int) (*model.Use
 			return nil, err
 		}
 	}
-	return &UserToken, nil
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) GetUserSessionsByUserId(ctx context.Context, userId int, currTime int64) (*[]model.UserSession, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, getUserSessionsByUserIdQuery, userId, currTime)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.UserSession
+	for rows.Next() {
+		var session model.UserSession
+		err := rows.Scan(&session.ID, &session.UserId, &session.AccessTokenHash, &session.RefreshTokenHash, &session.UserAgent, &session.IPAddress, &session.CreatedAt, &session.LastUsedAt, &session.ExpirationTime)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		data = append(data, session)
+	}
+	return &data, nil
 }
 
-func (d *cryptoDBImpl) InsertUserToken(ctx context.Context, userId int, accessToken, refreshToken string, expirationTime int64) error {
+func (d *cryptoDBImpl) InsertUserSession(ctx context.Context, session model.UserSession) error {
 	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
 	defer cancelfunc()
 
-	_, err := d.db.ExecContext(ctx, insertUserTokenQuery, userId, accessToken, refreshToken, expirationTime)
+	_, err := d.db.ExecContext(ctx, insertUserSessionQuery, session.ID, session.UserId, session.AccessTokenHash, session.RefreshTokenHash, session.UserAgent, session.IPAddress, session.CreatedAt, session.LastUsedAt, session.ExpirationTime)
 	if err != nil {
 		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
 		return err
@@ -95,11 +313,11 @@ func (d *cryptoDBImpl) InsertUserToken(ctx context.Context, userId int, accessTo
 	return nil
 }
 
-func (d *cryptoDBImpl) DeleteUserToken(ctx context.Context, userId int) error {
+func (d *cryptoDBImpl) UpdateUserSessionToken(ctx context.Context, sessionId, accessTokenHash, refreshTokenHash string, lastUsedAt, expirationTime int64) error {
 	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
 	defer cancelfunc()
 
-	_, err := d.db.ExecContext(ctx, deleteUserTokenQuery, userId)
+	_, err := d.db.ExecContext(ctx, updateUserSessionTokenQuery, accessTokenHash, refreshTokenHash, lastUsedAt, expirationTime, sessionId)
 	if err != nil {
 		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
 		return err
@@ -108,40 +326,70 @@ func (d *cryptoDBImpl) DeleteUserToken(ctx context.Context, userId int) error {
 	return nil
 }
 
-func (d *cryptoDBImpl) GetUserAssetsByUserId(ctx context.Context, userId int) (*[]model.UserAsset, error) {
+func (d *cryptoDBImpl) DeleteUserSession(ctx context.Context, sessionId string) error {
 	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
 	defer cancelfunc()
 
-	rows, err := d.db.QueryContext(ctx, getUserAssetsByUserIdQuery, userId)
+	_, err := d.db.ExecContext(ctx, deleteUserSessionQuery, sessionId)
 	if err != nil {
 		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
-		return nil, err
+		return err
 	}
 
-	var data []model.UserAsset
-	for rows.Next() {
-		var userAsset model.UserAsset
-		err := rows.Scan(&userAsset.ID, &userAsset.UserId, &userAsset.AssetId)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
-				return nil, err
-			} else {
-				log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+	return nil
+}
 
-				return nil, err
-			}
+func (d *cryptoDBImpl) DeleteUserSessionsByUserId(ctx context.Context, userId int) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, deleteUserSessionsByUserIdQuery, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) DeleteExpiredUserSessions(ctx context.Context, userId int, currTime int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, deleteExpiredUserSessionsQuery, userId, currTime)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) GetRefreshToken(ctx context.Context, tokenId string) (*model.RefreshToken, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	var data model.RefreshToken
+	row := d.db.QueryRowContext(ctx, getRefreshTokenQuery, tokenId)
+
+	err := row.Scan(&data.ID, &data.FamilyId, &data.UserId, &data.IssuedAt, &data.ExpirationTime, &data.RotatedAt, &data.RevokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
+			return nil, err
+		} else {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
 		}
-		data = append(data, userAsset)
 	}
 	return &data, nil
 }
 
-func (d *cryptoDBImpl) InsertUserAsset(ctx context.Context, userId int, assetId string) error {
+func (d *cryptoDBImpl) InsertRefreshToken(ctx context.Context, token model.RefreshToken) error {
 	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
 	defer cancelfunc()
 
-	_, err := d.db.ExecContext(ctx, insertUserAssetQuery, userId, assetId)
+	_, err := d.db.ExecContext(ctx, insertRefreshTokenQuery, token.ID, token.FamilyId, token.UserId, token.IssuedAt, token.ExpirationTime)
 	if err != nil {
 		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
 		return err
@@ -150,11 +398,62 @@ func (d *cryptoDBImpl) InsertUserAsset(ctx context.Context, userId int, assetId
 	return nil
 }
 
-func (d *cryptoDBImpl) DeleteUserAsset(ctx context.Context, userId int, assetId string) error {
+// RotateRefreshToken marks oldTokenId as used and stores newToken as the
+// current token of its family in a single transaction. It reports false when
+// oldTokenId had already been rotated or revoked, so concurrent refreshes with
+// the same token can't both succeed.
+func (d *cryptoDBImpl) RotateRefreshToken(ctx context.Context, oldTokenId string, newToken model.RefreshToken, accessTokenHash, refreshTokenHash string) (bool, error) {
 	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
 	defer cancelfunc()
 
-	_, err := d.db.ExecContext(ctx, deleteUserAssetQuery, userId, assetId)
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, rotateRefreshTokenQuery, newToken.IssuedAt, oldTokenId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	rotated, err := result.RowsAffected()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+	if rotated == 0 {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, insertRefreshTokenQuery, newToken.ID, newToken.FamilyId, newToken.UserId, newToken.IssuedAt, newToken.ExpirationTime)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, updateUserSessionTokenQuery, accessTokenHash, refreshTokenHash, newToken.IssuedAt, newToken.ExpirationTime, newToken.FamilyId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (d *cryptoDBImpl) RevokeRefreshTokenFamily(ctx context.Context, familyId string, revokedAt int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, revokeRefreshTokenFamilyQuery, revokedAt, familyId)
 	if err != nil {
 		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
 		return err
@@ -162,3 +461,1032 @@ func (d *cryptoDBImpl) DeleteUserAsset(ctx context.Context, userId int, assetId
 
 	return nil
 }
+
+func (d *cryptoDBImpl) DeleteExpiredRefreshTokens(ctx context.Context, userId int, currTime int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, deleteExpiredRefreshTokensQuery, userId, currTime)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) GetUserMFA(ctx context.Context, userId int) (*model.UserMFA, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	var data model.UserMFA
+	row := d.db.QueryRowContext(ctx, getUserMFAQuery, userId)
+
+	err := row.Scan(&data.UserId, &data.Secret, &data.Enabled, &data.LastUsedStep, &data.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
+			return nil, err
+		} else {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+	}
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) InsertUserMFA(ctx context.Context, userId int, secret string, createdAt int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, insertUserMFAQuery, userId, secret, createdAt)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// EnableUserMFA turns on two-factor authentication and replaces the user's
+// recovery codes in a single transaction.
+func (d *cryptoDBImpl) EnableUserMFA(ctx context.Context, userId int, step int64, recoveryCodeHashes []string) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, enableUserMFAQuery, step, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, deleteRecoveryCodesQuery, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx, insertRecoveryCodeQuery, userId, codeHash)
+		if err != nil {
+			log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateUserMFALastUsedStep records the time step of an accepted code. It
+// reports false when that step, or a later one, was already used.
+func (d *cryptoDBImpl) UpdateUserMFALastUsedStep(ctx context.Context, userId int, step int64) (bool, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	result, err := d.db.ExecContext(ctx, updateUserMFALastUsedStepQuery, step, userId, step)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	return updated > 0, nil
+}
+
+func (d *cryptoDBImpl) UseRecoveryCode(ctx context.Context, userId int, codeHash string, usedAt int64) (bool, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	result, err := d.db.ExecContext(ctx, useRecoveryCodeQuery, usedAt, userId, codeHash)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	return updated > 0, nil
+}
+
+func (d *cryptoDBImpl) DeleteUserMFA(ctx context.Context, userId int) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, deleteUserMFAQuery, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, deleteRecoveryCodesQuery, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) InsertUserActionToken(ctx context.Context, token model.UserActionToken) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, insertUserActionTokenQuery, token.ID, token.UserId, token.Purpose, token.ExpirationTime)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// UseUserActionToken marks an unexpired action token as used. It reports false
+// when the token is unknown, expired or was already used.
+func (d *cryptoDBImpl) UseUserActionToken(ctx context.Context, tokenId string, userId int, purpose string, usedAt int64) (bool, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	result, err := d.db.ExecContext(ctx, useUserActionTokenQuery, usedAt, tokenId, userId, purpose, usedAt)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	return updated > 0, nil
+}
+
+// UseUserActionTokens burns every unused token of the user for purpose.
+func (d *cryptoDBImpl) UseUserActionTokens(ctx context.Context, userId int, purpose string, usedAt int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, useUserActionTokensQuery, usedAt, userId, purpose)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) GetLoginAttempt(ctx context.Context, key string) (*model.LoginAttempt, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	var data model.LoginAttempt
+	row := d.db.QueryRowContext(ctx, getLoginAttemptQuery, key)
+
+	err := row.Scan(&data.Key, &data.Failures, &data.LastFailureAt, &data.LockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		} else {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+	}
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) UpsertLoginAttempt(ctx context.Context, attempt model.LoginAttempt) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, upsertLoginAttemptQuery, attempt.Key, attempt.Failures, attempt.LastFailureAt, attempt.LockedUntil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) DeleteLoginAttempt(ctx context.Context, key string) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, deleteLoginAttemptQuery, key)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) GetUserAPIKeyByHash(ctx context.Context, keyHash string) (*model.UserAPIKey, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	var data model.UserAPIKey
+	var scopes string
+	row := d.db.QueryRowContext(ctx, getUserAPIKeyByHashQuery, keyHash)
+
+	err := row.Scan(&data.ID, &data.UserId, &data.Name, &data.Prefix, &data.KeyHash, &scopes, &data.CreatedAt, &data.ExpirationTime, &data.LastUsedAt, &data.RevokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
+			return nil, err
+		} else {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+	}
+	data.Scopes = strings.Fields(scopes)
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) GetUserAPIKeysByUserId(ctx context.Context, userId int) (*[]model.UserAPIKey, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, getUserAPIKeysByUserIdQuery, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.UserAPIKey
+	for rows.Next() {
+		var apiKey model.UserAPIKey
+		var scopes string
+		err := rows.Scan(&apiKey.ID, &apiKey.UserId, &apiKey.Name, &apiKey.Prefix, &apiKey.KeyHash, &scopes, &apiKey.CreatedAt, &apiKey.ExpirationTime, &apiKey.LastUsedAt, &apiKey.RevokedAt)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		apiKey.Scopes = strings.Fields(scopes)
+		data = append(data, apiKey)
+	}
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) InsertUserAPIKey(ctx context.Context, apiKey model.UserAPIKey) (int, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	result, err := d.db.ExecContext(ctx, insertUserAPIKeyQuery, apiKey.UserId, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, strings.Join(apiKey.Scopes, " "), apiKey.CreatedAt, apiKey.ExpirationTime)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (d *cryptoDBImpl) UpdateUserAPIKeyLastUsed(ctx context.Context, apiKeyId int, lastUsedAt int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, updateUserAPIKeyLastUsedQuery, lastUsedAt, apiKeyId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) RevokeUserAPIKey(ctx context.Context, userId, apiKeyId int, revokedAt int64) (bool, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	result, err := d.db.ExecContext(ctx, revokeUserAPIKeyQuery, revokedAt, apiKeyId, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return false, err
+	}
+
+	return revoked > 0, nil
+}
+
+func (d *cryptoDBImpl) GetUserIdentity(ctx context.Context, issuer, subject string) (*model.UserIdentity, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	var data model.UserIdentity
+	row := d.db.QueryRowContext(ctx, getUserIdentityQuery, issuer, subject)
+
+	err := row.Scan(&data.ID, &data.UserId, &data.Issuer, &data.Subject, &data.Email, &data.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
+			return nil, err
+		} else {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+	}
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) GetUserIdentitiesByUserId(ctx context.Context, userId int) (*[]model.UserIdentity, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, getUserIdentitiesByUserIdQuery, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []model.UserIdentity{}
+	for rows.Next() {
+		var identity model.UserIdentity
+		err := rows.Scan(&identity.ID, &identity.UserId, &identity.Issuer, &identity.Subject, &identity.Email, &identity.CreatedAt)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		data = append(data, identity)
+	}
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) InsertUserIdentity(ctx context.Context, identity model.UserIdentity) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, insertUserIdentityQuery, identity.UserId, identity.Issuer, identity.Subject, identity.Email, identity.CreatedAt)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) InsertOIDCState(ctx context.Context, state model.OIDCState) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, insertOIDCStateQuery, state.State, state.Nonce, state.CodeVerifier, state.ExpirationTime)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// UseOIDCState deletes and returns a pending authorization request so its
+// state can only be redeemed once. Expired states are reported as
+// sql.ErrNoRows.
+func (d *cryptoDBImpl) UseOIDCState(ctx context.Context, state string, currTime int64) (*model.OIDCState, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var data model.OIDCState
+	row := tx.QueryRowContext(ctx, getOIDCStateQuery, state)
+
+	err = row.Scan(&data.State, &data.Nonce, &data.CodeVerifier, &data.ExpirationTime)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
+			return nil, err
+		} else {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, deleteOIDCStateQuery, state)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+
+	if data.ExpirationTime <= currTime {
+		return nil, sql.ErrNoRows
+	}
+
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) DeleteExpiredOIDCStates(ctx context.Context, currTime int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, deleteExpiredOIDCStatesQuery, currTime)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) GetAuditEventsByUserId(ctx context.Context, userId int) (*[]model.AuditEvent, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, getAuditEventsByUserIdQuery, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []model.AuditEvent{}
+	for rows.Next() {
+		var event model.AuditEvent
+		err := rows.Scan(&event.ID, &event.UserId, &event.Event, &event.Detail, &event.UserAgent, &event.IPAddress, &event.CreatedAt)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		data = append(data, event)
+	}
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) InsertAuditEvent(ctx context.Context, event model.AuditEvent) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, insertAuditEventQuery, event.UserId, event.Event, event.Detail, event.UserAgent, event.IPAddress, event.CreatedAt)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) GetUserAssetsByUserId(ctx context.Context, userId int) (*[]model.UserAsset, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, getUserAssetsByUserIdQuery, userId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+
+	var data []model.UserAsset
+	for rows.Next() {
+		var userAsset model.UserAsset
+		err := rows.Scan(&userAsset.ID, &userAsset.UserId, &userAsset.AssetId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
+				return nil, err
+			} else {
+				log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+
+				return nil, err
+			}
+		}
+		data = append(data, userAsset)
+	}
+	return &data, nil
+}
+
+// GetTrackedAssetIds returns every asset tracked by at least one user.
+func (d *cryptoDBImpl) GetTrackedAssetIds(ctx context.Context) (*[]string, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, getTrackedAssetIdsQuery)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []string{}
+	for rows.Next() {
+		var assetId string
+		err := rows.Scan(&assetId)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		data = append(data, assetId)
+	}
+	return &data, nil
+}
+
+func (d *cryptoDBImpl) InsertUserAsset(ctx context.Context, userId int, assetId string) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, insertUserAssetQuery, userId, assetId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) DeleteUserAsset(ctx context.Context, userId int, assetId string) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, deleteUserAssetQuery, userId, assetId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// InsertPriceHistory stores prices, skipping any asset and timestamp that
+// already has one.
+func (d *cryptoDBImpl) InsertPriceHistory(ctx context.Context, prices []model.PriceHistory) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertPriceHistoryQuery)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer stmt.Close()
+
+	for _, price := range prices {
+		_, err = stmt.ExecContext(ctx, price.AssetId, price.Timestamp, price.Price, price.Resolution)
+		if err != nil {
+			log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// DownsamplePriceHistory replaces the prices older than before that have a
+// finer resolution with their average per resolution seconds. Prices are in
+// USD and kept unrounded, they are only rounded once converted to the currency
+// they are served in. before must be a multiple of resolution, so no period
+// is only partly downsampled.
+func (d *cryptoDBImpl) DownsamplePriceHistory(ctx context.Context, resolution, before int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, getDownsampledHistoryQuery, resolution, before)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	// Rows come ordered by asset and time, so each period's prices are
+	// consecutive.
+	var averages []model.PriceHistory
+	var prices []decimal.Decimal
+	for rows.Next() {
+		var price model.PriceHistory
+		err = rows.Scan(&price.AssetId, &price.Timestamp, &price.Price)
+		if err != nil {
+			rows.Close()
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return err
+		}
+
+		timestamp := price.Timestamp / resolution * resolution
+		n := len(averages)
+		if n == 0 || averages[n-1].AssetId != price.AssetId || averages[n-1].Timestamp != timestamp {
+			if n > 0 {
+				averages[n-1].Price = decimal.Avg(prices[0], prices[1:]...)
+			}
+			averages = append(averages, model.PriceHistory{AssetId: price.AssetId, Timestamp: timestamp, Resolution: resolution})
+			prices = prices[:0]
+		}
+		prices = append(prices, price.Price)
+	}
+	if n := len(averages); n > 0 {
+		averages[n-1].Price = decimal.Avg(prices[0], prices[1:]...)
+	}
+
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, deleteDownsampledHistoryQuery, resolution, before)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, insertDownsampledHistoryQuery)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer stmt.Close()
+
+	for _, average := range averages {
+		_, err = stmt.ExecContext(ctx, average.AssetId, average.Timestamp, average.Price, average.Resolution)
+		if err != nil {
+			log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) DeletePriceHistoryBefore(ctx context.Context, before int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, deletePriceHistoryBeforeQuery, before)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) GetPriceHistoryAssetIds(ctx context.Context) (*[]string, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, getPriceHistoryAssetIdsQuery)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []string{}
+	for rows.Next() {
+		var assetId string
+		err := rows.Scan(&assetId)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		data = append(data, assetId)
+	}
+	return &data, nil
+}
+
+// GetPriceTicks returns the stored prices of an asset between from
+// (inclusive) and to (exclusive) that stand for at most maxResolution
+// seconds, oldest first.
+func (d *cryptoDBImpl) GetPriceTicks(ctx context.Context, assetId string, from, to, maxResolution int64) (*[]model.PriceHistory, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, getPriceTicksQuery, assetId, from, to, maxResolution)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []model.PriceHistory{}
+	for rows.Next() {
+		var price model.PriceHistory
+		err := rows.Scan(&price.AssetId, &price.Timestamp, &price.Price, &price.Resolution)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		data = append(data, price)
+	}
+	return &data, nil
+}
+
+// GetFirstPriceTime returns the time of the oldest stored price of an asset
+// that stands for at most maxResolution seconds, or 0 when there is none.
+func (d *cryptoDBImpl) GetFirstPriceTime(ctx context.Context, assetId string, maxResolution int64) (int64, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	var timestamp int64
+	row := d.db.QueryRowContext(ctx, getFirstPriceTimeQuery, assetId, maxResolution)
+
+	err := row.Scan(&timestamp)
+	if err != nil {
+		log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+		return 0, err
+	}
+
+	return timestamp, nil
+}
+
+func (d *cryptoDBImpl) GetPriceCandles(ctx context.Context, assetId, currency string, resolution, from, to int64) (*[]model.PriceCandle, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, getPriceCandlesQuery, assetId, currency, resolution, from, to)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []model.PriceCandle{}
+	for rows.Next() {
+		var candle model.PriceCandle
+		err := rows.Scan(&candle.AssetId, &candle.Currency, &candle.Resolution, &candle.OpenTime, &candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.Volume, &candle.Ticks)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		candle.CloseTime = candle.OpenTime + candle.Resolution
+		candle.Complete = true
+		data = append(data, candle)
+	}
+	return &data, nil
+}
+
+// GetLastPriceCandleTime returns the open time of the newest stored candle,
+// or 0 when there is none.
+func (d *cryptoDBImpl) GetLastPriceCandleTime(ctx context.Context, assetId, currency string, resolution int64) (int64, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	var openTime int64
+	row := d.db.QueryRowContext(ctx, getLastPriceCandleTimeQuery, assetId, currency, resolution)
+
+	err := row.Scan(&openTime)
+	if err != nil {
+		log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+		return 0, err
+	}
+
+	return openTime, nil
+}
+
+func (d *cryptoDBImpl) InsertPriceCandles(ctx context.Context, candles []model.PriceCandle) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertPriceCandleQuery)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer stmt.Close()
+
+	for _, candle := range candles {
+		_, err = stmt.ExecContext(ctx, candle.AssetId, candle.Currency, candle.Resolution, candle.OpenTime, candle.Open, candle.High, candle.Low, candle.Close, candle.Volume, candle.Ticks)
+		if err != nil {
+			log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// DeletePriceCandlesBefore drops the candles shorter than maxResolution
+// seconds that open before before.
+func (d *cryptoDBImpl) DeletePriceCandlesBefore(ctx context.Context, maxResolution, before int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, deletePriceCandlesBeforeQuery, maxResolution, before)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// DeletePriceCandlesByAssetId drops every candle of an asset, so they are
+// materialized again from its price history.
+func (d *cryptoDBImpl) DeletePriceCandlesByAssetId(ctx context.Context, assetId string) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	_, err := d.db.ExecContext(ctx, deletePriceCandlesByAssetIdQuery, assetId)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+// SyncAssetCatalog stores the assets listed by the price API and drops the
+// ones it no longer lists, which were synced before syncedAt.
+func (d *cryptoDBImpl) SyncAssetCatalog(ctx context.Context, assets []model.AssetInfo, syncedAt int64) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, upsertCatalogAssetQuery)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+	defer stmt.Close()
+
+	for _, asset := range assets {
+		_, err = stmt.ExecContext(ctx, asset.AssetId, asset.Symbol, asset.Name, asset.Rank, asset.Logo, syncedAt)
+		if err != nil {
+			log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, deleteStaleCatalogAssetsQuery, syncedAt)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *cryptoDBImpl) GetCatalogAsset(ctx context.Context, assetId string) (*model.AssetInfo, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	var data model.AssetInfo
+	row := d.db.QueryRowContext(ctx, getCatalogAssetQuery, assetId)
+
+	err := row.Scan(&data.AssetId, &data.Symbol, &data.Name, &data.Rank, &data.Logo)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.PrintLogErr(ctx, noRowsFoundErrorMsg, err)
+			return nil, err
+		} else {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+	}
+	return &data, nil
+}
+
+// GetAssetCatalog returns every asset in the catalog by rank, unranked ones
+// last.
+func (d *cryptoDBImpl) GetAssetCatalog(ctx context.Context) (*[]model.AssetInfo, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, getAssetCatalogQuery)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []model.AssetInfo{}
+	for rows.Next() {
+		var asset model.AssetInfo
+		err := rows.Scan(&asset.AssetId, &asset.Symbol, &asset.Name, &asset.Rank, &asset.Logo)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		data = append(data, asset)
+	}
+	return &data, nil
+}
+
+// FindCatalogAssets returns the catalog assets whose symbol or name is
+// symbolOrName, ignoring case, by rank.
+func (d *cryptoDBImpl) FindCatalogAssets(ctx context.Context, symbolOrName string) (*[]model.AssetInfo, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, d.timeout)
+	defer cancelfunc()
+
+	rows, err := d.db.QueryContext(ctx, findCatalogAssetsQuery, symbolOrName, symbolOrName)
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []model.AssetInfo{}
+	for rows.Next() {
+		var asset model.AssetInfo
+		err := rows.Scan(&asset.AssetId, &asset.Symbol, &asset.Name, &asset.Rank, &asset.Logo)
+		if err != nil {
+			log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+			return nil, err
+		}
+		data = append(data, asset)
+	}
+	return &data, nil
+}
@@ -7,13 +7,88 @@ import (
 )
 
 type CryptoDBInterface interface {
-	GetUserByEmailAndPassword(ctx context.Context, email, password string) (*model.User, error)
+	GetUserById(ctx context.Context, userId int) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
 	InsertUser(ctx context.Context, email, password string) error
-	GetUserToken(ctx context.Context, userId int) (*model.UserToken, error)
-	InsertUserToken(ctx context.Context, userId int, accessToken, refreshToken string, expirationTime int64) error
-	DeleteUserToken(ctx context.Context, userId int) error
+	InsertUserWithIdentity(ctx context.Context, email, password string, identity model.UserIdentity) (int, error)
+	UpdateUserPassword(ctx context.Context, userId int, password string) error
+	UpdateUserEmailVerified(ctx context.Context, userId int, emailVerified bool) error
+	UpdateUserEmail(ctx context.Context, userId int, email string) error
+	DeleteUser(ctx context.Context, userId int) error
+	UpdateUserRole(ctx context.Context, userId int, role string) error
+	UpdateUserDisabled(ctx context.Context, userId int, disabled bool) error
+	UpdateUserCurrency(ctx context.Context, userId int, currency string) error
+	SearchUsers(ctx context.Context, emailPattern string, limit, offset int) (*[]model.User, error)
+
+	GetUserSession(ctx context.Context, sessionId string) (*model.UserSession, error)
+	GetUserSessionsByUserId(ctx context.Context, userId int, currTime int64) (*[]model.UserSession, error)
+	InsertUserSession(ctx context.Context, session model.UserSession) error
+	UpdateUserSessionToken(ctx context.Context, sessionId, accessTokenHash, refreshTokenHash string, lastUsedAt, expirationTime int64) error
+	DeleteUserSession(ctx context.Context, sessionId string) error
+	DeleteUserSessionsByUserId(ctx context.Context, userId int) error
+	DeleteExpiredUserSessions(ctx context.Context, userId int, currTime int64) error
+
+	GetRefreshToken(ctx context.Context, tokenId string) (*model.RefreshToken, error)
+	InsertRefreshToken(ctx context.Context, token model.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, oldTokenId string, newToken model.RefreshToken, accessTokenHash, refreshTokenHash string) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string, revokedAt int64) error
+	DeleteExpiredRefreshTokens(ctx context.Context, userId int, currTime int64) error
+
+	GetUserMFA(ctx context.Context, userId int) (*model.UserMFA, error)
+	InsertUserMFA(ctx context.Context, userId int, secret string, createdAt int64) error
+	EnableUserMFA(ctx context.Context, userId int, step int64, recoveryCodeHashes []string) error
+	UpdateUserMFALastUsedStep(ctx context.Context, userId int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string, usedAt int64) (bool, error)
+	DeleteUserMFA(ctx context.Context, userId int) error
+
+	InsertUserActionToken(ctx context.Context, token model.UserActionToken) error
+	UseUserActionToken(ctx context.Context, tokenId string, userId int, purpose string, usedAt int64) (bool, error)
+	UseUserActionTokens(ctx context.Context, userId int, purpose string, usedAt int64) error
+
+	GetLoginAttempt(ctx context.Context, key string) (*model.LoginAttempt, error)
+	UpsertLoginAttempt(ctx context.Context, attempt model.LoginAttempt) error
+	DeleteLoginAttempt(ctx context.Context, key string) error
+
+	GetUserAPIKeyByHash(ctx context.Context, keyHash string) (*model.UserAPIKey, error)
+	GetUserAPIKeysByUserId(ctx context.Context, userId int) (*[]model.UserAPIKey, error)
+	InsertUserAPIKey(ctx context.Context, apiKey model.UserAPIKey) (int, error)
+	UpdateUserAPIKeyLastUsed(ctx context.Context, apiKeyId int, lastUsedAt int64) error
+	RevokeUserAPIKey(ctx context.Context, userId, apiKeyId int, revokedAt int64) (bool, error)
+
+	GetUserIdentity(ctx context.Context, issuer, subject string) (*model.UserIdentity, error)
+	GetUserIdentitiesByUserId(ctx context.Context, userId int) (*[]model.UserIdentity, error)
+	InsertUserIdentity(ctx context.Context, identity model.UserIdentity) error
+
+	InsertOIDCState(ctx context.Context, state model.OIDCState) error
+	UseOIDCState(ctx context.Context, state string, currTime int64) (*model.OIDCState, error)
+	DeleteExpiredOIDCStates(ctx context.Context, currTime int64) error
+
+	GetAuditEventsByUserId(ctx context.Context, userId int) (*[]model.AuditEvent, error)
+	InsertAuditEvent(ctx context.Context, event model.AuditEvent) error
+
+	ExportUserData(ctx context.Context, userId int) (*[]model.UserDataFile, error)
 
 	GetUserAssetsByUserId(ctx context.Context, userId int) (*[]model.UserAsset, error)
+	GetTrackedAssetIds(ctx context.Context) (*[]string, error)
+
+	InsertPriceHistory(ctx context.Context, prices []model.PriceHistory) error
+	DownsamplePriceHistory(ctx context.Context, resolution, before int64) error
+	DeletePriceHistoryBefore(ctx context.Context, before int64) error
+	GetPriceHistoryAssetIds(ctx context.Context) (*[]string, error)
+	GetPriceTicks(ctx context.Context, assetId string, from, to, maxResolution int64) (*[]model.PriceHistory, error)
+	GetFirstPriceTime(ctx context.Context, assetId string, maxResolution int64) (int64, error)
+
+	GetPriceCandles(ctx context.Context, assetId, currency string, resolution, from, to int64) (*[]model.PriceCandle, error)
+	GetLastPriceCandleTime(ctx context.Context, assetId, currency string, resolution int64) (int64, error)
+	InsertPriceCandles(ctx context.Context, candles []model.PriceCandle) error
+	DeletePriceCandlesBefore(ctx context.Context, maxResolution, before int64) error
+	DeletePriceCandlesByAssetId(ctx context.Context, assetId string) error
+
+	SyncAssetCatalog(ctx context.Context, assets []model.AssetInfo, syncedAt int64) error
+	GetCatalogAsset(ctx context.Context, assetId string) (*model.AssetInfo, error)
+	GetAssetCatalog(ctx context.Context) (*[]model.AssetInfo, error)
+	FindCatalogAssets(ctx context.Context, symbolOrName string) (*[]model.AssetInfo, error)
+
 	InsertUserAsset(ctx context.Context, userId int, assetId string) error
 	DeleteUserAsset(ctx context.Context, userId int, assetId string) error
 }
@@ -1,13 +1,119 @@
 package cryptoDB
 
 const (
-	getUserByEmailAndPasswordQuery = "SELECT id FROM users WHERE email = ? AND password = ?"
-	insertUserQuery                = "INSERT INTO users (email, password) VALUES (?, ?)"
-	getUserTokenQuery              = "SELECT accessToken,refreshToken, expirationTime FROM user_tokens WHERE userId = ?"
-	insertUserTokenQuery           = "INSERT OR REPLACE INTO user_tokens (userId,accessToken, refreshToken, expirationTime) VALUES (?, ?, ?, ?)"
-	deleteUserTokenQuery           = "DELETE FROM user_tokens WHERE userId = ?"
+	getUserByIdQuery             = "SELECT id, email, password, emailVerified, role, disabled, currency FROM users WHERE id = ?"
+	getUserByEmailQuery          = "SELECT id, email, password, emailVerified, role, disabled, currency FROM users WHERE email = ?"
+	searchUsersQuery             = "SELECT id, email, password, emailVerified, role, disabled, currency FROM users WHERE email LIKE ? ESCAPE '\\' ORDER BY id LIMIT ? OFFSET ?"
+	insertUserQuery              = "INSERT INTO users (email, password) VALUES (?, ?)"
+	insertVerifiedUserQuery      = "INSERT INTO users (email, password, emailVerified) VALUES (?, ?, 1)"
+	updateUserPasswordQuery      = "UPDATE users SET password = ? WHERE id = ?"
+	updateUserEmailVerifiedQuery = "UPDATE users SET emailVerified = ? WHERE id = ?"
+	updateUserEmailQuery         = "UPDATE users SET email = ?, emailVerified = 1 WHERE id = ?"
+	updateUserRoleQuery          = "UPDATE users SET role = ? WHERE id = ?"
+	updateUserDisabledQuery      = "UPDATE users SET disabled = ? WHERE id = ?"
+	updateUserCurrencyQuery      = "UPDATE users SET currency = ? WHERE id = ?"
+
+	getUserSessionQuery             = "SELECT ID, userId, accessTokenHash, refreshTokenHash, userAgent, ipAddress, createdAt, lastUsedAt, expirationTime FROM user_sessions WHERE ID = ?"
+	getUserSessionsByUserIdQuery    = "SELECT ID, userId, accessTokenHash, refreshTokenHash, userAgent, ipAddress, createdAt, lastUsedAt, expirationTime FROM user_sessions WHERE userId = ? AND expirationTime > ? ORDER BY lastUsedAt DESC"
+	insertUserSessionQuery          = "INSERT INTO user_sessions (ID, userId, accessTokenHash, refreshTokenHash, userAgent, ipAddress, createdAt, lastUsedAt, expirationTime) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	updateUserSessionTokenQuery     = "UPDATE user_sessions SET accessTokenHash = ?, refreshTokenHash = ?, lastUsedAt = ?, expirationTime = ? WHERE ID = ?"
+	deleteUserSessionQuery          = "DELETE FROM user_sessions WHERE ID = ?"
+	deleteUserSessionsByUserIdQuery = "DELETE FROM user_sessions WHERE userId = ?"
+	deleteExpiredUserSessionsQuery  = "DELETE FROM user_sessions WHERE userId = ? AND expirationTime <= ?"
+
+	getRefreshTokenQuery            = "SELECT ID, familyId, userId, issuedAt, expirationTime, rotatedAt, revokedAt FROM refresh_tokens WHERE ID = ?"
+	insertRefreshTokenQuery         = "INSERT INTO refresh_tokens (ID, familyId, userId, issuedAt, expirationTime) VALUES (?, ?, ?, ?, ?)"
+	rotateRefreshTokenQuery         = "UPDATE refresh_tokens SET rotatedAt = ? WHERE ID = ? AND rotatedAt = 0 AND revokedAt = 0"
+	revokeRefreshTokenFamilyQuery   = "UPDATE refresh_tokens SET revokedAt = ? WHERE familyId = ? AND revokedAt = 0"
+	deleteExpiredRefreshTokensQuery = "DELETE FROM refresh_tokens WHERE userId = ? AND expirationTime <= ?"
+
+	getUserMFAQuery                = "SELECT userId, secret, enabled, lastUsedStep, createdAt FROM user_mfa WHERE userId = ?"
+	insertUserMFAQuery             = "INSERT OR REPLACE INTO user_mfa (userId, secret, enabled, lastUsedStep, createdAt) VALUES (?, ?, 0, 0, ?)"
+	enableUserMFAQuery             = "UPDATE user_mfa SET enabled = 1, lastUsedStep = ? WHERE userId = ?"
+	updateUserMFALastUsedStepQuery = "UPDATE user_mfa SET lastUsedStep = ? WHERE userId = ? AND lastUsedStep < ?"
+	deleteUserMFAQuery             = "DELETE FROM user_mfa WHERE userId = ?"
+	insertRecoveryCodeQuery        = "INSERT INTO user_recovery_codes (userId, codeHash) VALUES (?, ?)"
+	useRecoveryCodeQuery           = "UPDATE user_recovery_codes SET usedAt = ? WHERE userId = ? AND codeHash = ? AND usedAt = 0"
+	deleteRecoveryCodesQuery       = "DELETE FROM user_recovery_codes WHERE userId = ?"
+
+	insertUserActionTokenQuery = "INSERT INTO user_action_tokens (ID, userId, purpose, expirationTime) VALUES (?, ?, ?, ?)"
+	useUserActionTokenQuery    = "UPDATE user_action_tokens SET usedAt = ? WHERE ID = ? AND userId = ? AND purpose = ? AND usedAt = 0 AND expirationTime > ?"
+	useUserActionTokensQuery   = "UPDATE user_action_tokens SET usedAt = ? WHERE userId = ? AND purpose = ? AND usedAt = 0"
+
+	getLoginAttemptQuery    = "SELECT key, failures, lastFailureAt, lockedUntil FROM login_attempts WHERE key = ?"
+	upsertLoginAttemptQuery = "INSERT OR REPLACE INTO login_attempts (key, failures, lastFailureAt, lockedUntil) VALUES (?, ?, ?, ?)"
+	deleteLoginAttemptQuery = "DELETE FROM login_attempts WHERE key = ?"
+
+	getUserAPIKeyByHashQuery      = "SELECT ID, userId, name, prefix, keyHash, scopes, createdAt, expirationTime, lastUsedAt, revokedAt FROM user_api_keys WHERE keyHash = ?"
+	getUserAPIKeysByUserIdQuery   = "SELECT ID, userId, name, prefix, keyHash, scopes, createdAt, expirationTime, lastUsedAt, revokedAt FROM user_api_keys WHERE userId = ? AND revokedAt = 0 ORDER BY createdAt DESC"
+	insertUserAPIKeyQuery         = "INSERT INTO user_api_keys (userId, name, prefix, keyHash, scopes, createdAt, expirationTime) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	updateUserAPIKeyLastUsedQuery = "UPDATE user_api_keys SET lastUsedAt = ? WHERE ID = ?"
+	revokeUserAPIKeyQuery         = "UPDATE user_api_keys SET revokedAt = ? WHERE ID = ? AND userId = ? AND revokedAt = 0"
+
+	getUserIdentityQuery           = "SELECT ID, userId, issuer, subject, email, createdAt FROM user_identities WHERE issuer = ? AND subject = ?"
+	getUserIdentitiesByUserIdQuery = "SELECT ID, userId, issuer, subject, email, createdAt FROM user_identities WHERE userId = ? ORDER BY createdAt"
+	insertUserIdentityQuery        = "INSERT INTO user_identities (userId, issuer, subject, email, createdAt) VALUES (?, ?, ?, ?, ?)"
+
+	getOIDCStateQuery            = "SELECT state, nonce, codeVerifier, expirationTime FROM oidc_states WHERE state = ?"
+	insertOIDCStateQuery         = "INSERT INTO oidc_states (state, nonce, codeVerifier, expirationTime) VALUES (?, ?, ?, ?)"
+	deleteOIDCStateQuery         = "DELETE FROM oidc_states WHERE state = ?"
+	deleteExpiredOIDCStatesQuery = "DELETE FROM oidc_states WHERE expirationTime <= ?"
+
+	countAccessTokenQuery          = "SELECT COUNT(*) FROM access_tokens WHERE tokenHash = ? AND expirationTime > ?"
+	insertAccessTokenQuery         = "INSERT INTO access_tokens (tokenHash, userId, expirationTime) VALUES (?, ?, ?)"
+	deleteAccessTokenQuery         = "DELETE FROM access_tokens WHERE tokenHash = ?"
+	deleteExpiredAccessTokensQuery = "DELETE FROM access_tokens WHERE userId = ? AND expirationTime <= ?"
+
+	insertPriceHistoryQuery       = "INSERT OR IGNORE INTO price_history (assetId, timestamp, price, resolution) VALUES (?, ?, ?, ?)"
+	getDownsampledHistoryQuery    = "SELECT assetId, timestamp, price FROM price_history WHERE resolution < ? AND timestamp < ? ORDER BY assetId, timestamp"
+	insertDownsampledHistoryQuery = "INSERT OR REPLACE INTO price_history (assetId, timestamp, price, resolution) VALUES (?, ?, ?, ?)"
+	deleteDownsampledHistoryQuery = "DELETE FROM price_history WHERE resolution < ? AND timestamp < ?"
+	deletePriceHistoryBeforeQuery = "DELETE FROM price_history WHERE timestamp < ?"
+	getPriceHistoryAssetIdsQuery  = "SELECT DISTINCT assetId FROM price_history"
+	getPriceTicksQuery            = "SELECT assetId, timestamp, price, resolution FROM price_history WHERE assetId = ? AND timestamp >= ? AND timestamp < ? AND resolution <= ? ORDER BY timestamp"
+	getFirstPriceTimeQuery        = "SELECT COALESCE(MIN(timestamp), 0) FROM price_history WHERE assetId = ? AND resolution <= ?"
+
+	getPriceCandlesQuery             = "SELECT assetId, currency, resolution, openTime, open, high, low, close, volume, ticks FROM price_candles WHERE assetId = ? AND currency = ? AND resolution = ? AND openTime >= ? AND openTime < ? ORDER BY openTime"
+	getLastPriceCandleTimeQuery      = "SELECT COALESCE(MAX(openTime), 0) FROM price_candles WHERE assetId = ? AND currency = ? AND resolution = ?"
+	insertPriceCandleQuery           = "INSERT OR REPLACE INTO price_candles (assetId, currency, resolution, openTime, open, high, low, close, volume, ticks) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	deletePriceCandlesBeforeQuery    = "DELETE FROM price_candles WHERE resolution < ? AND openTime < ?"
+	deletePriceCandlesByAssetIdQuery = "DELETE FROM price_candles WHERE assetId = ?"
+	upsertCatalogAssetQuery          = "INSERT OR REPLACE INTO asset_catalog (assetId, symbol, name, rank, logo, syncedAt) VALUES (?, ?, ?, ?, ?, ?)"
+	deleteStaleCatalogAssetsQuery    = "DELETE FROM asset_catalog WHERE syncedAt < ?"
+	getCatalogAssetQuery             = "SELECT assetId, symbol, name, rank, logo FROM asset_catalog WHERE assetId = ?"
+	getAssetCatalogQuery             = "SELECT assetId, symbol, name, rank, logo FROM asset_catalog ORDER BY rank = 0, rank, assetId"
+	findCatalogAssetsQuery           = "SELECT assetId, symbol, name, rank, logo FROM asset_catalog WHERE symbol = ? COLLATE NOCASE OR name = ? COLLATE NOCASE ORDER BY rank = 0, rank, assetId"
+
+	getAuditEventsByUserIdQuery = "SELECT ID, userId, event, detail, userAgent, ipAddress, createdAt FROM audit_events WHERE userId = ? ORDER BY createdAt"
+	insertAuditEventQuery       = "INSERT INTO audit_events (userId, event, detail, userAgent, ipAddress, createdAt) VALUES (?, ?, ?, ?, ?, ?)"
 
 	getUserAssetsByUserIdQuery = "SELECT * FROM user_assets WHERE userId = ?"
+	getTrackedAssetIdsQuery    = "SELECT DISTINCT assetId FROM user_assets"
 	insertUserAssetQuery       = "INSERT INTO user_assets (userId, assetId) VALUES (?, ?)"
 	deleteUserAssetQuery       = "DELETE FROM user_assets WHERE userId = ? AND assetId = ?"
+
+	deleteUserAssetsByUserIdQuery       = "DELETE FROM user_assets WHERE userId = ?"
+	deleteRefreshTokensByUserIdQuery    = "DELETE FROM refresh_tokens WHERE userId = ?"
+	deleteAuditEventsByUserIdQuery      = "DELETE FROM audit_events WHERE userId = ?"
+	deleteUserActionTokensByUserIdQuery = "DELETE FROM user_action_tokens WHERE userId = ?"
+	deleteUserAPIKeysByUserIdQuery      = "DELETE FROM user_api_keys WHERE userId = ?"
+	deleteUserIdentitiesByUserIdQuery   = "DELETE FROM user_identities WHERE userId = ?"
+	deleteAccessTokensByUserIdQuery     = "DELETE FROM access_tokens WHERE userId = ?"
+	deleteUserQuery                     = "DELETE FROM users WHERE id = ?"
 )
+
+// deleteUserQueries remove everything that belongs to a user, the user row
+// itself last. Each takes the user id as its only argument.
+var deleteUserQueries = []string{
+	deleteUserAssetsByUserIdQuery,
+	deleteUserSessionsByUserIdQuery,
+	deleteRefreshTokensByUserIdQuery,
+	deleteAuditEventsByUserIdQuery,
+	deleteRecoveryCodesQuery,
+	deleteUserMFAQuery,
+	deleteUserActionTokensByUserIdQuery,
+	deleteUserAPIKeysByUserIdQuery,
+	deleteUserIdentitiesByUserIdQuery,
+	deleteAccessTokensByUserIdQuery,
+	deleteUserQuery,
+}
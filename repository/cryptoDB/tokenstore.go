@@ -0,0 +1,74 @@
+package cryptoDB
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/michaelwongycn/crypto-tracker/lib/tokenstore"
+)
+
+type tokenStoreImpl struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// NewTokenStore returns a token store kept in the access_tokens table. Only a
+// hash of each token is stored.
+func NewTokenStore(timeout time.Duration, db *sql.DB) tokenstore.Store {
+	return &tokenStoreImpl{
+		db:      db,
+		timeout: timeout * time.Second,
+	}
+}
+
+func (s *tokenStoreImpl) Add(ctx context.Context, token string, userId int, expiresAt time.Time) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, s.timeout)
+	defer cancelfunc()
+
+	_, err := s.db.ExecContext(ctx, deleteExpiredAccessTokensQuery, userId, time.Now().Unix())
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, insertAccessTokenQuery, tokenstore.Hash(token), userId, expiresAt.Unix())
+	if err != nil {
+		log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *tokenStoreImpl) Contains(ctx context.Context, token string) (bool, error) {
+	ctx, cancelfunc := context.WithTimeout(ctx, s.timeout)
+	defer cancelfunc()
+
+	var count int
+	row := s.db.QueryRowContext(ctx, countAccessTokenQuery, tokenstore.Hash(token), time.Now().Unix())
+
+	err := row.Scan(&count)
+	if err != nil {
+		log.PrintLogErr(ctx, errorScanningRowErrorMsg, err)
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *tokenStoreImpl) Remove(ctx context.Context, tokenHashes ...string) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, s.timeout)
+	defer cancelfunc()
+
+	for _, tokenHash := range tokenHashes {
+		_, err := s.db.ExecContext(ctx, deleteAccessTokenQuery, tokenHash)
+		if err != nil {
+			log.PrintLogErr(ctx, errorQueryingSQLErrorMsg, err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,274 @@
+package cryptoREST
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultBinanceBaseURL    = "https://api.binance.com/api/v3/"
+	defaultBinanceQuoteAsset = "USDT"
+
+	// Most candles Binance returns for one klines request.
+	maxBinanceKlines = 1000
+)
+
+// binanceKlineIntervals maps the resolutions we keep history at to Binance's
+// interval names.
+var binanceKlineIntervals = map[time.Duration]string{
+	time.Minute:      "1m",
+	5 * time.Minute:  "5m",
+	15 * time.Minute: "15m",
+	time.Hour:        "1h",
+	24 * time.Hour:   "1d",
+}
+
+type binanceTickerPrice struct {
+	Symbol string `json:"symbol"`
+	Price  string `json:"price"`
+}
+
+type binanceTicker24Hr struct {
+	Symbol             string `json:"symbol"`
+	LastPrice          string `json:"lastPrice"`
+	PriceChangePercent string `json:"priceChangePercent"`
+	QuoteVolume        string `json:"quoteVolume"`
+}
+
+// binanceProvider uses the public Binance spot API. Binance trades pairs
+// rather than pricing assets, so each asset needs its base symbol configured
+// in Symbols, and a price is that of the pair with QuoteAsset, a USD
+// stablecoin. It has no fiat rates.
+type binanceProvider struct {
+	client     *restClient
+	baseURL    string
+	quoteAsset string
+	symbols    map[string]string
+}
+
+func newBinanceProvider(client *restClient, cfg config.BinanceConfig) *binanceProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBinanceBaseURL
+	}
+
+	quoteAsset := strings.ToUpper(cfg.QuoteAsset)
+	if quoteAsset == "" {
+		quoteAsset = defaultBinanceQuoteAsset
+	}
+
+	return &binanceProvider{
+		client:     client,
+		baseURL:    baseURL,
+		quoteAsset: quoteAsset,
+		symbols:    cfg.Symbols,
+	}
+}
+
+func (p *binanceProvider) Name() string {
+	return binanceProviderName
+}
+
+// pair returns the trading pair an asset is priced with.
+func (p *binanceProvider) pair(assetId string) (string, bool) {
+	symbol, ok := p.symbols[assetId]
+	if !ok {
+		return "", false
+	}
+	return strings.ToUpper(symbol) + p.quoteAsset, true
+}
+
+func (p *binanceProvider) LookupAsset(ctx context.Context, assetId string) (*model.AssetInfo, error) {
+	pair, ok := p.pair(assetId)
+	if !ok {
+		return nil, errNotSupported
+	}
+
+	var APIResponse binanceTickerPrice
+	err := p.client.getJSON(ctx, p.baseURL+"ticker/price?symbol="+url.QueryEscape(pair), nil, &APIResponse)
+	if err != nil {
+		return nil, invalidSymbolAsAnswer(err)
+	}
+
+	return &model.AssetInfo{
+		AssetId: assetId,
+		Symbol:  strings.ToUpper(p.symbols[assetId]),
+	}, nil
+}
+
+// GetQuotesUSD reads the pairs' 24h tickers, their volume is traded in the
+// quote asset and so in USD. Binance has no names, ranks or market caps.
+func (p *binanceProvider) GetQuotesUSD(ctx context.Context, assetIds []string) (map[string]model.Asset, error) {
+	assetIdByPair := map[string]string{}
+	pairs := []string{}
+	for _, assetId := range assetIds {
+		if pair, ok := p.pair(assetId); ok {
+			assetIdByPair[pair] = assetId
+			pairs = append(pairs, pair)
+		}
+	}
+
+	if len(pairs) == 0 {
+		return nil, errNotSupported
+	}
+
+	symbols, err := json.Marshal(pairs)
+	if err != nil {
+		return nil, err
+	}
+
+	var APIResponse []binanceTicker24Hr
+	err = p.client.getJSON(ctx, p.baseURL+"ticker/24hr?symbols="+url.QueryEscape(string(symbols)), nil, &APIResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := map[string]model.Asset{}
+	for _, ticker := range APIResponse {
+		price, err := decimal.NewFromString(ticker.LastPrice)
+		if err != nil {
+			log.PrintLogErr(ctx, errorParsingPriceErrorMsg, err)
+			continue
+		}
+		if assetId, ok := assetIdByPair[ticker.Symbol]; ok {
+			quotes[assetId] = model.Asset{
+				AssetId:           assetId,
+				Symbol:            strings.ToUpper(p.symbols[assetId]),
+				Price:             decimal.NewNullDecimal(price),
+				ChangePercent24Hr: parseOptionalDecimal(ticker.PriceChangePercent),
+				Volume24Hr:        parseOptionalDecimal(ticker.QuoteVolume),
+			}
+		}
+	}
+	return quotes, nil
+}
+
+func (p *binanceProvider) GetRatesUSD(ctx context.Context) ([]model.CurrencyRate, error) {
+	return nil, errNotSupported
+}
+
+// GetHistoryUSD returns the closing price of an asset every resolution
+// between start and end.
+func (p *binanceProvider) GetHistoryUSD(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) ([]model.PricePoint, error) {
+	klines, err := p.getKlines(ctx, assetId, resolution, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]model.PricePoint, 0, len(klines))
+	for _, kline := range klines {
+		price, err := decimal.NewFromString(kline.closePrice)
+		if err != nil {
+			log.PrintLogErr(ctx, errorParsingPriceErrorMsg, err)
+			return nil, err
+		}
+
+		data = append(data, model.PricePoint{
+			Timestamp: kline.openTime / 1000,
+			Price:     price,
+		})
+	}
+
+	return data, nil
+}
+
+// GetVolumeUSD returns the volume traded in the quote asset every resolution
+// between start and end.
+func (p *binanceProvider) GetVolumeUSD(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) ([]model.VolumePoint, error) {
+	klines, err := p.getKlines(ctx, assetId, resolution, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]model.VolumePoint, 0, len(klines))
+	for _, kline := range klines {
+		volume, err := decimal.NewFromString(kline.quoteVolume)
+		if err != nil {
+			log.PrintLogErr(ctx, errorParsingPriceErrorMsg, err)
+			return nil, err
+		}
+
+		data = append(data, model.VolumePoint{
+			Timestamp: kline.openTime / 1000,
+			Volume:    volume,
+		})
+	}
+
+	return data, nil
+}
+
+// binanceKline is the part of a kline we use. openTime is in milliseconds.
+type binanceKline struct {
+	openTime    int64
+	closePrice  string
+	quoteVolume string
+}
+
+// getKlines pages through the klines of an asset between start and end.
+func (p *binanceProvider) getKlines(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) ([]binanceKline, error) {
+	pair, ok := p.pair(assetId)
+	interval, intervalOk := binanceKlineIntervals[resolution]
+	if !ok || !intervalOk {
+		return nil, errNotSupported
+	}
+
+	data := []binanceKline{}
+	for from := start; from.Before(end); {
+		query := url.Values{}
+		query.Set("symbol", pair)
+		query.Set("interval", interval)
+		query.Set("startTime", strconv.FormatInt(from.UnixMilli(), 10))
+		query.Set("endTime", strconv.FormatInt(end.UnixMilli(), 10))
+		query.Set("limit", strconv.Itoa(maxBinanceKlines))
+
+		// A kline is an array that starts with its open time, has the close
+		// price fifth and the quote asset volume eighth.
+		var APIResponse [][]json.RawMessage
+		err := p.client.getJSON(ctx, p.baseURL+"klines?"+query.Encode(), nil, &APIResponse)
+		if err != nil {
+			return nil, invalidSymbolAsAnswer(err)
+		}
+
+		for _, raw := range APIResponse {
+			if len(raw) < 8 {
+				continue
+			}
+
+			var kline binanceKline
+			if json.Unmarshal(raw[0], &kline.openTime) != nil || json.Unmarshal(raw[4], &kline.closePrice) != nil || json.Unmarshal(raw[7], &kline.quoteVolume) != nil {
+				continue
+			}
+
+			data = append(data, kline)
+			from = time.UnixMilli(kline.openTime).Add(resolution)
+		}
+
+		if len(APIResponse) < maxBinanceKlines {
+			break
+		}
+	}
+
+	return data, nil
+}
+
+// invalidSymbolAsAnswer turns Binance's 400 for an unknown pair into
+// errAssetNotFound.
+func invalidSymbolAsAnswer(err error) error {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadRequest {
+		return fmt.Errorf("%w: %w", errAssetNotFound, err)
+	}
+	return err
+}
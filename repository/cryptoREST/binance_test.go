@@ -0,0 +1,84 @@
+package cryptoREST
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/shopspring/decimal"
+)
+
+func newTestBinanceProvider(baseURL string) *binanceProvider {
+	client := newRESTClient(newHTTPClient(), 0, config.RetryConfig{MaxAttempts: 1}, newProviderHealth(0, 0))
+	return newBinanceProvider(client, config.BinanceConfig{
+		BaseURL: baseURL + "/",
+		Symbols: map[string]string{"bitcoin": "btc"},
+	})
+}
+
+func TestBinanceKlines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/klines" || query.Get("symbol") != "BTCUSDT" || query.Get("interval") != "1h" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		serveFixture(t, w, "binance_klines.json")
+	}))
+	defer server.Close()
+
+	p := newTestBinanceProvider(server.URL)
+	ctx := context.Background()
+	start := time.UnixMilli(1717200000000)
+	end := start.Add(2 * time.Hour)
+
+	history, err := p.GetHistoryUSD(ctx, "bitcoin", time.Hour, start, end)
+	if err != nil {
+		t.Fatalf("GetHistoryUSD: %v", err)
+	}
+	if len(history) != 2 || history[1].Timestamp != 1717203600 || !history[1].Price.Equal(decimal.RequireFromString("67577.99")) {
+		t.Fatalf("history = %+v", history)
+	}
+
+	volumes, err := p.GetVolumeUSD(ctx, "bitcoin", time.Hour, start, end)
+	if err != nil {
+		t.Fatalf("GetVolumeUSD: %v", err)
+	}
+	if len(volumes) != 2 || volumes[0].Timestamp != 1717200000 || !volumes[0].Volume.Equal(decimal.RequireFromString("34655301.23456789")) {
+		t.Fatalf("volumes = %+v", volumes)
+	}
+
+	_, err = p.GetVolumeUSD(ctx, "ethereum", time.Hour, start, end)
+	if err != errNotSupported {
+		t.Fatalf("GetVolumeUSD of an asset without a symbol error = %v, want %v", err, errNotSupported)
+	}
+}
+
+func TestBinanceGetQuotesUSD(t *testing.T) {
+	server := newFixtureServer(t, map[string]string{"/ticker/24hr": "binance_ticker_24hr.json"})
+	p := newTestBinanceProvider(server.URL)
+	ctx := context.Background()
+
+	quotes, err := p.GetQuotesUSD(ctx, []string{"bitcoin", "ethereum"})
+	if err != nil {
+		t.Fatalf("GetQuotesUSD: %v", err)
+	}
+
+	// Ethereum has no symbol configured, so it isn't asked for.
+	bitcoin, ok := quotes["bitcoin"]
+	if len(quotes) != 1 || !ok || bitcoin.Symbol != "BTC" || !bitcoin.Price.Decimal.Equal(decimal.RequireFromString("67512.01")) || !bitcoin.Volume24Hr.Decimal.Equal(decimal.RequireFromString("1382511320.12345678")) {
+		t.Fatalf("quotes = %+v, want bitcoin from BTCUSDT", quotes)
+	}
+
+	requests := server.received()
+	if len(requests) != 1 || requests[0].Query().Get("symbols") != `["BTCUSDT"]` {
+		t.Fatalf("requests = %v, want one for BTCUSDT", requests)
+	}
+
+	_, err = p.GetQuotesUSD(ctx, []string{"ethereum"})
+	if err != errNotSupported {
+		t.Fatalf("GetQuotesUSD of assets without symbols error = %v, want %v", err, errNotSupported)
+	}
+}
@@ -0,0 +1,205 @@
+package cryptoREST
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+)
+
+// statusError is returned for a response other than 200 OK. RetryAfter is
+// taken from the Retry-After header, if the provider sent one.
+type statusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: %d", apiRequestFailedErrorMsg, e.StatusCode)
+}
+
+// isRateLimited reports whether the provider asked us to slow down. Binance
+// answers 418 once an IP keeps going after a 429.
+func (e *statusError) isRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusTeapot
+}
+
+// isRetryable reports whether the request may succeed if sent again.
+func (e *statusError) isRetryable() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+const (
+	defaultRequestTimeout = 10 * time.Second
+	defaultRetryAttempts  = 3
+	defaultRetryBaseDelay = 200 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+)
+
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: maxConcurrentAssetRequests,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// restClient is a provider's HTTP client. Each attempt has its own deadline,
+// failed attempts are retried with jittered exponential backoff and retries
+// are counted in the provider's health.
+type restClient struct {
+	client      *http.Client
+	timeout     time.Duration
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	health      *providerHealth
+}
+
+func newRESTClient(client *http.Client, timeout time.Duration, cfg config.RetryConfig, health *providerHealth) *restClient {
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryAttempts
+	}
+
+	baseDelay := time.Millisecond * cfg.BaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+
+	maxDelay := time.Millisecond * cfg.MaxDelay
+	if maxDelay < baseDelay {
+		maxDelay = max(defaultRetryMaxDelay, baseDelay)
+	}
+
+	return &restClient{
+		client:      client,
+		timeout:     timeout,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		maxDelay:    maxDelay,
+		health:      health,
+	}
+}
+
+// getJSON sends a GET request and decodes the JSON response into v. Network
+// errors, 5xx and 429 responses are retried up to maxAttempts in all, after
+// the delay asked for by Retry-After or else a random delay up to baseDelay
+// doubled for every attempt. A Retry-After longer than maxDelay isn't waited
+// for, the error is returned so the provider's circuit breaker opens.
+func (c *restClient) getJSON(ctx context.Context, endpoint string, header http.Header, v interface{}) error {
+	for attempt := 1; ; attempt++ {
+		err := c.getJSONOnce(ctx, endpoint, header, v)
+		if err == nil || attempt >= c.maxAttempts || ctx.Err() != nil || !isRetryable(err) {
+			return err
+		}
+
+		delay := c.backoff(attempt)
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+			if statusErr.RetryAfter > c.maxDelay {
+				return err
+			}
+			delay = statusErr.RetryAfter
+		}
+
+		c.health.retried()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns a random delay up to baseDelay doubled for each attempt
+// made, capped at maxDelay.
+func (c *restClient) backoff(attempt int) time.Duration {
+	delay := c.maxDelay
+	if attempt < 32 {
+		delay = min(c.baseDelay<<(attempt-1), c.maxDelay)
+	}
+	return rand.N(delay) + 1
+}
+
+func isRetryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.isRetryable()
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func (c *restClient) getJSONOnce(ctx context.Context, endpoint string, header http.Header, v interface{}) error {
+	ctx, cancelfunc := context.WithTimeout(ctx, c.timeout)
+	defer cancelfunc()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		log.PrintLogErr(ctx, errorAccessingAPIErrorMsg, err)
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.PrintLogErr(ctx, errorAccessingAPIErrorMsg, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.PrintLogAPIErr(ctx, apiRequestFailedErrorMsg, resp.StatusCode)
+		return &statusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		log.PrintLogErr(ctx, invalidAPIResponseErrorMsg, err)
+		return err
+	}
+	return nil
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date. It returns 0 when there is none.
+func parseRetryAfter(value string, currTime time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(currTime), 0)
+	}
+	return 0
+}
@@ -0,0 +1,210 @@
+package cryptoREST
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+)
+
+// scriptedResponse is a failure a scriptedServer answers with.
+type scriptedResponse struct {
+	status     int
+	retryAfter func() string
+}
+
+// scriptedServer answers with its failures in order, then with 200 OK.
+type scriptedServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []scriptedResponse
+	requests  []time.Time
+}
+
+func newScriptedServer(t *testing.T, responses ...scriptedResponse) *scriptedServer {
+	t.Helper()
+
+	s := &scriptedServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, time.Now())
+		var response *scriptedResponse
+		if len(s.responses) > 0 {
+			response = &s.responses[0]
+			s.responses = s.responses[1:]
+		}
+		s.mu.Unlock()
+
+		if response == nil {
+			w.Write([]byte(`{"ok":true}`))
+			return
+		}
+		if response.retryAfter != nil {
+			w.Header().Set("Retry-After", response.retryAfter())
+		}
+		w.WriteHeader(response.status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *scriptedServer) received() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]time.Time(nil), s.requests...)
+}
+
+// newTestRESTClient retries up to 3 attempts in all, with delays of a few
+// milliseconds unless Retry-After asks for more.
+func newTestRESTClient(maxDelay time.Duration) (*restClient, *providerHealth) {
+	health := newProviderHealth(0, 0)
+	cfg := config.RetryConfig{MaxAttempts: 3, BaseDelay: 5, MaxDelay: maxDelay / time.Millisecond}
+	return newRESTClient(newHTTPClient(), 0, cfg, health), health
+}
+
+func retryAfter(value string) func() string {
+	return func() string { return value }
+}
+
+func TestGetJSONRetriesServerErrors(t *testing.T) {
+	server := newScriptedServer(t, scriptedResponse{status: http.StatusServiceUnavailable})
+	c, health := newTestRESTClient(time.Second)
+
+	var v struct{ OK bool }
+	err := c.getJSON(context.Background(), server.URL, nil, &v)
+	if err != nil || !v.OK {
+		t.Fatalf("getJSON = %+v, %v, want the second attempt's answer", v, err)
+	}
+	if len(server.received()) != 2 {
+		t.Fatalf("%d requests, want 2", len(server.received()))
+	}
+	if retries := health.snapshot(time.Now()).Retries; retries != 1 {
+		t.Fatalf("%d retries counted, want 1", retries)
+	}
+}
+
+func TestGetJSONHonoursRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter func() string
+		// least wait expected, an HTTP date is only precise to the second
+		wait time.Duration
+	}{
+		{name: "seconds", retryAfter: retryAfter("1"), wait: time.Second},
+		{name: "HTTP date", retryAfter: func() string {
+			return time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat)
+		}, wait: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newScriptedServer(t, scriptedResponse{status: http.StatusTooManyRequests, retryAfter: tt.retryAfter})
+			c, _ := newTestRESTClient(5 * time.Second)
+
+			var v struct{ OK bool }
+			err := c.getJSON(context.Background(), server.URL, nil, &v)
+			if err != nil || !v.OK {
+				t.Fatalf("getJSON = %+v, %v", v, err)
+			}
+
+			requests := server.received()
+			if len(requests) != 2 {
+				t.Fatalf("%d requests, want 2", len(requests))
+			}
+			if wait := requests[1].Sub(requests[0]); wait < tt.wait {
+				t.Fatalf("retried after %s, want at least %s", wait, tt.wait)
+			}
+		})
+	}
+}
+
+func TestGetJSONReturnsLongRetryAfter(t *testing.T) {
+	server := newScriptedServer(t, scriptedResponse{status: http.StatusTooManyRequests, retryAfter: retryAfter("60")})
+	c, health := newTestRESTClient(time.Second)
+
+	err := c.getJSON(context.Background(), server.URL, nil, &struct{}{})
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) || statusErr.RetryAfter != time.Minute {
+		t.Fatalf("getJSON error = %v, want the 429 with its Retry-After", err)
+	}
+	if len(server.received()) != 1 {
+		t.Fatalf("%d requests, want 1", len(server.received()))
+	}
+
+	// Recording it opens the breaker until Retry-After passes.
+	currTime := time.Now()
+	health.failure(currTime, err)
+	if state := health.snapshot(currTime).State; state != breakerOpen {
+		t.Fatalf("breaker is %s, want %s", state, breakerOpen)
+	}
+}
+
+func TestGetJSONDoesNotRetryClientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusNotFound} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			server := newScriptedServer(t, scriptedResponse{status: status})
+			c, health := newTestRESTClient(time.Second)
+
+			err := c.getJSON(context.Background(), server.URL, nil, &struct{}{})
+			var statusErr *statusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+				t.Fatalf("getJSON error = %v, want %d", err, status)
+			}
+			if len(server.received()) != 1 || health.snapshot(time.Now()).Retries != 0 {
+				t.Fatalf("%d requests, want 1 without retries", len(server.received()))
+			}
+		})
+	}
+}
+
+func TestGetJSONStopsWaitingWhenCanceled(t *testing.T) {
+	server := newScriptedServer(t, scriptedResponse{status: http.StatusServiceUnavailable, retryAfter: retryAfter("3")})
+	c, _ := newTestRESTClient(5 * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := c.getJSON(ctx, server.URL, nil, &struct{}{})
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("getJSON error = %v, want the last attempt's 503", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("getJSON returned after %s, want it to stop waiting when canceled", elapsed)
+	}
+	if len(server.received()) != 1 {
+		t.Fatalf("%d requests, want 1", len(server.received()))
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	currTime := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-5", 0},
+		{currTime.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{currTime.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, currTime); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package cryptoREST
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/domain/response"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	coincapHistoryPath = "/history"
+
+	// Coincap lists at most this many assets a page.
+	maxCoincapAssetsPerPage = 2000
+
+	// Coincap doesn't return logos, but serves one for each symbol here.
+	coincapIconURL = "https://assets.coincap.io/assets/icons/%s@2x.png"
+)
+
+// coincapHistoryIntervals maps the resolutions we keep history at to
+// Coincap's interval names.
+var coincapHistoryIntervals = map[time.Duration]string{
+	time.Minute:      "m1",
+	5 * time.Minute:  "m5",
+	15 * time.Minute: "m15",
+	time.Hour:        "h1",
+	24 * time.Hour:   "d1",
+}
+
+type coincapProvider struct {
+	client        *restClient
+	baseURL       string
+	assetEndpoint string
+	ratesEndpoint string
+}
+
+func newCoincapProvider(client *restClient, cfg config.CoincapConfig) *coincapProvider {
+	return &coincapProvider{
+		client:        client,
+		baseURL:       cfg.BaseURL,
+		assetEndpoint: cfg.AssetEndpoint,
+		ratesEndpoint: cfg.RatesEndpoint,
+	}
+}
+
+func (p *coincapProvider) Name() string {
+	return coincapProviderName
+}
+
+func (p *coincapProvider) LookupAsset(ctx context.Context, assetId string) (*model.AssetInfo, error) {
+	asset, err := p.getAsset(ctx, assetId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toCoincapAssetInfo(*asset), nil
+}
+
+// ListAssets pages through Coincap's assets, which come ordered by rank.
+func (p *coincapProvider) ListAssets(ctx context.Context, limit int) ([]model.AssetInfo, error) {
+	var assets []model.AssetInfo
+	for len(assets) < limit {
+		pageSize := min(limit-len(assets), maxCoincapAssetsPerPage)
+
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(pageSize))
+		query.Set("offset", strconv.Itoa(len(assets)))
+
+		var APIResponse struct {
+			Data []response.AssetValidationDataResponse `json:"data"`
+		}
+
+		err := p.client.getJSON(ctx, p.baseURL+strings.TrimSuffix(p.assetEndpoint, "/")+"?"+query.Encode(), nil, &APIResponse)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, asset := range APIResponse.Data {
+			assets = append(assets, *toCoincapAssetInfo(asset))
+		}
+		if len(APIResponse.Data) < pageSize {
+			break
+		}
+	}
+	return assets, nil
+}
+
+func toCoincapAssetInfo(asset response.AssetValidationDataResponse) *model.AssetInfo {
+	rank, _ := strconv.Atoi(asset.Rank)
+	return &model.AssetInfo{
+		AssetId: asset.ID,
+		Symbol:  asset.Symbol,
+		Name:    asset.Name,
+		Rank:    rank,
+		Logo:    fmt.Sprintf(coincapIconURL, strings.ToLower(asset.Symbol)),
+	}
+}
+
+// GetRatesUSD lists every fiat and crypto currency Coincap has a rate for.
+func (p *coincapProvider) GetRatesUSD(ctx context.Context) ([]model.CurrencyRate, error) {
+	var APIResponse struct {
+		Data []response.CurrencyRateDataResponse `json:"data"`
+	}
+
+	err := p.client.getJSON(ctx, p.baseURL+strings.TrimSuffix(p.ratesEndpoint, "/"), nil, &APIResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]model.CurrencyRate, 0, len(APIResponse.Data))
+	for _, currency := range APIResponse.Data {
+		rate, err := decimal.NewFromString(currency.RateUSD)
+		if err != nil {
+			log.PrintLogErr(ctx, errorParsingPriceErrorMsg, err)
+			return nil, err
+		}
+
+		rates = append(rates, model.CurrencyRate{
+			Id:             currency.ID,
+			Symbol:         currency.Symbol,
+			CurrencySymbol: currency.CurrencySymbol,
+			Type:           currency.Type,
+			RateUSD:        rate,
+		})
+	}
+	return rates, nil
+}
+
+// GetQuotesUSD asks for the assets in batches of maxAssetsPerBatch, any the
+// batch requests didn't return are then fetched one by one. Only a batch the
+// API rejects as unsupported is fetched one by one too, any other failure is
+// returned so an outage or rate limit costs one request, not one per asset.
+func (p *coincapProvider) GetQuotesUSD(ctx context.Context, assetIds []string) (map[string]model.Asset, error) {
+	quotes := map[string]model.Asset{}
+
+	for start := 0; start < len(assetIds); start += maxAssetsPerBatch {
+		end := min(start+maxAssetsPerBatch, len(assetIds))
+
+		batch, err := p.getBatchQuotesUSD(ctx, assetIds[start:end])
+		if err != nil {
+			if !isBatchUnsupported(err) {
+				return nil, err
+			}
+			continue
+		}
+
+		for assetId, quote := range batch {
+			quotes[assetId] = quote
+		}
+	}
+
+	var missing []string
+	for _, assetId := range assetIds {
+		if _, ok := quotes[assetId]; !ok {
+			missing = append(missing, assetId)
+		}
+	}
+
+	if len(missing) == 0 {
+		return quotes, nil
+	}
+
+	missingQuotes := make([]model.Asset, len(missing))
+	found := make([]bool, len(missing))
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxConcurrentAssetRequests)
+	for i, assetId := range missing {
+		g.Go(func() error {
+			quote, err := p.getQuoteUSD(gctx, assetId)
+			if errors.Is(err, errAssetNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			missingQuotes[i], found[i] = *quote, true
+			return nil
+		})
+	}
+
+	err := g.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, assetId := range missing {
+		if found[i] {
+			quotes[assetId] = missingQuotes[i]
+		}
+	}
+	return quotes, nil
+}
+
+// GetHistoryUSD returns the average USD price of an asset every resolution
+// between start and end.
+func (p *coincapProvider) GetHistoryUSD(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) ([]model.PricePoint, error) {
+	interval, ok := coincapHistoryIntervals[resolution]
+	if !ok {
+		return nil, errNotSupported
+	}
+
+	query := url.Values{}
+	query.Set("interval", interval)
+	query.Set("start", strconv.FormatInt(start.UnixMilli(), 10))
+	query.Set("end", strconv.FormatInt(end.UnixMilli(), 10))
+
+	var APIResponse struct {
+		Data []response.AssetHistoryDataResponse `json:"data"`
+	}
+
+	err := p.client.getJSON(ctx, p.baseURL+p.assetEndpoint+url.PathEscape(assetId)+coincapHistoryPath+"?"+query.Encode(), nil, &APIResponse)
+	if err != nil {
+		return nil, notFoundAsAnswer(err)
+	}
+
+	data := make([]model.PricePoint, 0, len(APIResponse.Data))
+	for _, point := range APIResponse.Data {
+		price, err := decimal.NewFromString(point.PriceUSD)
+		if err != nil {
+			log.PrintLogErr(ctx, errorParsingPriceErrorMsg, err)
+			return nil, err
+		}
+
+		data = append(data, model.PricePoint{
+			Timestamp: point.Time / 1000,
+			Price:     price,
+		})
+	}
+
+	return data, nil
+}
+
+func (p *coincapProvider) getBatchQuotesUSD(ctx context.Context, assetIds []string) (map[string]model.Asset, error) {
+	query := url.Values{}
+	query.Set("ids", strings.Join(assetIds, ","))
+	query.Set("limit", strconv.Itoa(len(assetIds)))
+
+	var APIResponse struct {
+		Data []response.AssetValidationDataResponse `json:"data"`
+	}
+
+	err := p.client.getJSON(ctx, p.baseURL+strings.TrimSuffix(p.assetEndpoint, "/")+"?"+query.Encode(), nil, &APIResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := map[string]model.Asset{}
+	for _, asset := range APIResponse.Data {
+		quote, err := toCoincapQuote(asset)
+		if err != nil {
+			log.PrintLogErr(ctx, errorParsingPriceErrorMsg, err)
+			continue
+		}
+		quotes[asset.ID] = *quote
+	}
+	return quotes, nil
+}
+
+func (p *coincapProvider) getQuoteUSD(ctx context.Context, assetId string) (*model.Asset, error) {
+	asset, err := p.getAsset(ctx, assetId)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := toCoincapQuote(*asset)
+	if err != nil {
+		log.PrintLogErr(ctx, errorParsingPriceErrorMsg, err)
+		return nil, err
+	}
+	return quote, nil
+}
+
+// toCoincapQuote reads an asset's market data. Only the price is required,
+// Coincap has no 24h change, market cap or volume for some assets.
+func toCoincapQuote(asset response.AssetValidationDataResponse) (*model.Asset, error) {
+	price, err := decimal.NewFromString(asset.PriceUSD)
+	if err != nil {
+		return nil, err
+	}
+
+	rank, _ := strconv.Atoi(asset.Rank)
+	return &model.Asset{
+		AssetId:           asset.ID,
+		Symbol:            asset.Symbol,
+		Name:              asset.Name,
+		Rank:              rank,
+		Price:             decimal.NewNullDecimal(price),
+		ChangePercent24Hr: parseOptionalDecimal(asset.ChangePercent24Hr),
+		MarketCap:         parseOptionalDecimal(asset.MarketCapUSD),
+		Volume24Hr:        parseOptionalDecimal(asset.VolumeUSD24Hr),
+	}, nil
+}
+
+func (p *coincapProvider) getAsset(ctx context.Context, assetId string) (*response.AssetValidationDataResponse, error) {
+	var APIResponse struct {
+		Data response.AssetValidationDataResponse `json:"data"`
+	}
+
+	err := p.client.getJSON(ctx, p.baseURL+p.assetEndpoint+url.PathEscape(assetId), nil, &APIResponse)
+	if err != nil {
+		return nil, notFoundAsAnswer(err)
+	}
+
+	if APIResponse.Data.ID != assetId {
+		return nil, errAssetNotFound
+	}
+	return &APIResponse.Data, nil
+}
+
+// isBatchUnsupported reports whether the API doesn't take the ids of a batch
+// request, like a Coincap mirror that only serves assets one by one.
+func isBatchUnsupported(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusBadRequest)
+}
+
+// notFoundAsAnswer turns a 404 into errAssetNotFound.
+func notFoundAsAnswer(err error) error {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %w", errAssetNotFound, err)
+	}
+	return err
+}
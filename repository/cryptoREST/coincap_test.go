@@ -0,0 +1,207 @@
+package cryptoREST
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/response"
+	"github.com/shopspring/decimal"
+)
+
+// newTestCoincapProvider points a Coincap provider at baseURL, with the
+// production HTTP client and no retries.
+func newTestCoincapProvider(baseURL string) *coincapProvider {
+	client := newRESTClient(newHTTPClient(), 0, config.RetryConfig{MaxAttempts: 1}, newProviderHealth(0, 0))
+	return newCoincapProvider(client, config.CoincapConfig{
+		BaseURL:       baseURL + "/",
+		AssetEndpoint: "assets/",
+		RatesEndpoint: "rates/",
+	})
+}
+
+// latencyCoincapServer answers every asset id it is asked for after latency,
+// like Coincap does from far away. With batch false batch requests come back
+// empty, so quotes fall back to one request per asset.
+func latencyCoincapServer(b *testing.B, latency time.Duration, batch bool) *httptest.Server {
+	b.Helper()
+
+	asset := func(id string) response.AssetValidationDataResponse {
+		return response.AssetValidationDataResponse{ID: id, Symbol: strings.ToUpper(id), Name: id, Rank: "1", PriceUSD: "1.5"}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(latency)
+
+		if id, ok := strings.CutPrefix(r.URL.Path, "/assets/"); ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": asset(id)})
+			return
+		}
+
+		data := []response.AssetValidationDataResponse{}
+		if batch {
+			for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+				data = append(data, asset(id))
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	b.Cleanup(server.Close)
+	return server
+}
+
+func benchmarkAssetIds(n int) []string {
+	assetIds := make([]string, n)
+	for i := range assetIds {
+		assetIds[i] = fmt.Sprintf("asset-%d", i)
+	}
+	return assetIds
+}
+
+// BenchmarkCoincapGetQuotesUSD compares quoting 50 assets with 5ms of
+// latency a request through the batch request, through the worker pool it
+// falls back to, and one request after another.
+func BenchmarkCoincapGetQuotesUSD(b *testing.B) {
+	const latency = 5 * time.Millisecond
+	assetIds := benchmarkAssetIds(50)
+	ctx := context.Background()
+
+	check := func(b *testing.B, n int) {
+		if n != len(assetIds) {
+			b.Fatalf("got %d quotes, want %d", n, len(assetIds))
+		}
+	}
+
+	b.Run("batch", func(b *testing.B) {
+		p := newTestCoincapProvider(latencyCoincapServer(b, latency, true).URL)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			quotes, err := p.GetQuotesUSD(ctx, assetIds)
+			if err != nil {
+				b.Fatalf("GetQuotesUSD: %v", err)
+			}
+			check(b, len(quotes))
+		}
+	})
+
+	b.Run("worker pool", func(b *testing.B) {
+		p := newTestCoincapProvider(latencyCoincapServer(b, latency, false).URL)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			quotes, err := p.GetQuotesUSD(ctx, assetIds)
+			if err != nil {
+				b.Fatalf("GetQuotesUSD: %v", err)
+			}
+			check(b, len(quotes))
+		}
+	})
+
+	b.Run("sequential", func(b *testing.B) {
+		p := newTestCoincapProvider(latencyCoincapServer(b, latency, false).URL)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			n := 0
+			for _, assetId := range assetIds {
+				_, err := p.getQuoteUSD(ctx, assetId)
+				if err != nil {
+					b.Fatalf("getQuoteUSD: %v", err)
+				}
+				n++
+			}
+			check(b, n)
+		}
+	})
+}
+
+func TestCoincapGetQuotesUSD(t *testing.T) {
+	server := newFixtureServer(t, coincapRoutes)
+	p := newTestCoincapProvider(server.URL)
+
+	quotes, err := p.GetQuotesUSD(context.Background(), []string{"bitcoin", "ethereum", "nosuchcoin"})
+	if err != nil {
+		t.Fatalf("GetQuotesUSD: %v", err)
+	}
+	if len(quotes) != 2 {
+		t.Fatalf("quotes = %+v, want bitcoin and ethereum", quotes)
+	}
+
+	bitcoin := quotes["bitcoin"]
+	if bitcoin.Symbol != "BTC" || bitcoin.Rank != 1 || !bitcoin.Price.Decimal.Equal(decimal.RequireFromString("67512.3456271340581523")) || !bitcoin.MarketCap.Valid {
+		t.Fatalf("bitcoin = %+v", bitcoin)
+	}
+	if ethereum := quotes["ethereum"]; ethereum.Rank != 2 || ethereum.Volume24Hr.Valid {
+		t.Fatalf("ethereum = %+v, want it without a 24h volume", ethereum)
+	}
+
+	requests := server.received()
+	if len(requests) != 2 || requests[0].Query().Get("ids") != "bitcoin,ethereum,nosuchcoin" || requests[1].Path != "/assets/nosuchcoin" {
+		t.Fatalf("requests = %v, want the batch then nosuchcoin alone", requests)
+	}
+}
+
+func TestCoincapGetQuotesUSDFallsBackToSingleAssets(t *testing.T) {
+	server := newFixtureServer(t, map[string]string{"/assets/bitcoin": "coincap_asset_bitcoin.json"})
+	p := newTestCoincapProvider(server.URL)
+
+	quotes, err := p.GetQuotesUSD(context.Background(), []string{"bitcoin"})
+	if err != nil {
+		t.Fatalf("GetQuotesUSD: %v", err)
+	}
+	if bitcoin, ok := quotes["bitcoin"]; !ok || bitcoin.Name != "Bitcoin" || !bitcoin.Price.Valid {
+		t.Fatalf("quotes = %+v, want bitcoin from its own request", quotes)
+	}
+}
+
+func TestCoincapGetQuotesUSDReturnsBatchFailures(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			server := newFixtureServer(t, coincapRoutes)
+			server.setStatus(status, "")
+			p := newTestCoincapProvider(server.URL)
+
+			_, err := p.GetQuotesUSD(context.Background(), []string{"bitcoin", "ethereum", "dogecoin"})
+			var statusErr *statusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+				t.Fatalf("GetQuotesUSD error = %v, want the batch's %d", err, status)
+			}
+
+			if requests := server.received(); len(requests) != 1 || requests[0].Path != "/assets" {
+				t.Fatalf("requests = %v, want the batch alone", requests)
+			}
+		})
+	}
+}
+
+func TestCoincapGetRatesUSD(t *testing.T) {
+	p := newTestCoincapProvider(newFixtureServer(t, coincapRoutes).URL)
+
+	rates, err := p.GetRatesUSD(context.Background())
+	if err != nil {
+		t.Fatalf("GetRatesUSD: %v", err)
+	}
+	if len(rates) != 3 || rates[0].Id != "indonesian-rupiah" || rates[0].Symbol != "IDR" || !rates[0].RateUSD.Equal(decimal.RequireFromString("0.0000614129923145")) {
+		t.Fatalf("rates = %+v", rates)
+	}
+}
+
+func TestCoincapLookupAssetNotFound(t *testing.T) {
+	p := newTestCoincapProvider(newFixtureServer(t, coincapRoutes).URL)
+	ctx := context.Background()
+
+	_, err := p.LookupAsset(ctx, "nosuchcoin")
+	if !errors.Is(err, errAssetNotFound) {
+		t.Fatalf("LookupAsset(nosuchcoin) error = %v, want %v", err, errAssetNotFound)
+	}
+
+	asset, err := p.LookupAsset(ctx, "bitcoin")
+	if err != nil || asset.Symbol != "BTC" || asset.Name != "Bitcoin" {
+		t.Fatalf("LookupAsset(bitcoin) = %+v, %v", asset, err)
+	}
+}
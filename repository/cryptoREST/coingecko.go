@@ -0,0 +1,250 @@
+package cryptoREST
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultCoinGeckoBaseURL = "https://api.coingecko.com/api/v3/"
+	coinGeckoAPIKeyHeader   = "x-cg-demo-api-key"
+
+	// Decimal places kept of the USD rate derived from two exchange rates.
+	coinGeckoRatePrecision = 24
+
+	// CoinGecko lists at most this many coins a page.
+	maxCoinGeckoMarketsPerPage = 250
+)
+
+type coinGeckoAsset struct {
+	ID     string `json:"id"`
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+}
+
+type coinGeckoMarket struct {
+	ID            string `json:"id"`
+	Symbol        string `json:"symbol"`
+	Name          string `json:"name"`
+	Image         string `json:"image"`
+	MarketCapRank int    `json:"market_cap_rank"`
+}
+
+type coinGeckoRate struct {
+	Name  string          `json:"name"`
+	Unit  string          `json:"unit"`
+	Value decimal.Decimal `json:"value"`
+	Type  string          `json:"type"`
+}
+
+// coinGeckoProvider uses CoinGecko's public API. CoinGecko names most assets
+// like Coincap does, the ones it doesn't are mapped with AssetIds. Currencies
+// are named by codes such as idr, so it only has rates for the currencies
+// mapped from Coincap's ids with CurrencyIds, and for the target currency.
+type coinGeckoProvider struct {
+	client       *restClient
+	baseURL      string
+	header       http.Header
+	currencyIds  map[string]string
+	coinGeckoIds map[string]string
+	assetIds     map[string]string
+}
+
+func newCoinGeckoProvider(client *restClient, cfg config.CoinGeckoConfig, targetCurrency string) *coinGeckoProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultCoinGeckoBaseURL
+	}
+
+	header := http.Header{}
+	if cfg.APIKey != "" {
+		header.Set(coinGeckoAPIKeyHeader, cfg.APIKey)
+	}
+
+	assetIds := make(map[string]string, len(cfg.AssetIds))
+	for assetId, coinGeckoId := range cfg.AssetIds {
+		assetIds[coinGeckoId] = assetId
+	}
+
+	currencyIds := make(map[string]string, len(cfg.CurrencyIds)+1)
+	for currencyId, code := range cfg.CurrencyIds {
+		currencyIds[currencyId] = strings.ToLower(code)
+	}
+	if _, ok := currencyIds[targetCurrency]; !ok && targetCurrency != "" && cfg.TargetCurrency != "" {
+		currencyIds[targetCurrency] = strings.ToLower(cfg.TargetCurrency)
+	}
+
+	return &coinGeckoProvider{
+		client:       client,
+		baseURL:      baseURL,
+		header:       header,
+		currencyIds:  currencyIds,
+		coinGeckoIds: cfg.AssetIds,
+		assetIds:     assetIds,
+	}
+}
+
+func (p *coinGeckoProvider) Name() string {
+	return coinGeckoProviderName
+}
+
+func (p *coinGeckoProvider) coinGeckoId(assetId string) string {
+	if id, ok := p.coinGeckoIds[assetId]; ok {
+		return id
+	}
+	return assetId
+}
+
+func (p *coinGeckoProvider) assetId(coinGeckoId string) string {
+	if id, ok := p.assetIds[coinGeckoId]; ok {
+		return id
+	}
+	return coinGeckoId
+}
+
+func (p *coinGeckoProvider) LookupAsset(ctx context.Context, assetId string) (*model.AssetInfo, error) {
+	query := url.Values{}
+	for _, field := range []string{"localization", "tickers", "market_data", "community_data", "developer_data"} {
+		query.Set(field, "false")
+	}
+
+	var APIResponse coinGeckoAsset
+	err := p.client.getJSON(ctx, p.baseURL+"coins/"+url.PathEscape(p.coinGeckoId(assetId))+"?"+query.Encode(), p.header, &APIResponse)
+	if err != nil {
+		return nil, notFoundAsAnswer(err)
+	}
+
+	return &model.AssetInfo{
+		AssetId: assetId,
+		Symbol:  strings.ToUpper(APIResponse.Symbol),
+		Name:    APIResponse.Name,
+	}, nil
+}
+
+// ListAssets pages through CoinGecko's coins by market cap. A coin without a
+// rank has a null one, which decodes as 0.
+func (p *coinGeckoProvider) ListAssets(ctx context.Context, limit int) ([]model.AssetInfo, error) {
+	var assets []model.AssetInfo
+	for page := 1; len(assets) < limit; page++ {
+		query := url.Values{}
+		query.Set("vs_currency", "usd")
+		query.Set("order", "market_cap_desc")
+		query.Set("per_page", strconv.Itoa(maxCoinGeckoMarketsPerPage))
+		query.Set("page", strconv.Itoa(page))
+
+		var APIResponse []coinGeckoMarket
+		err := p.client.getJSON(ctx, p.baseURL+"coins/markets?"+query.Encode(), p.header, &APIResponse)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, market := range APIResponse {
+			if len(assets) == limit {
+				break
+			}
+			assets = append(assets, model.AssetInfo{
+				AssetId: p.assetId(market.ID),
+				Symbol:  strings.ToUpper(market.Symbol),
+				Name:    market.Name,
+				Rank:    market.MarketCapRank,
+				Logo:    market.Image,
+			})
+		}
+		if len(APIResponse) < maxCoinGeckoMarketsPerPage {
+			break
+		}
+	}
+	return assets, nil
+}
+
+// GetQuotesUSD asks for the price, 24h change, market cap and volume. CoinGecko
+// doesn't return symbols, names or ranks with them.
+func (p *coinGeckoProvider) GetQuotesUSD(ctx context.Context, assetIds []string) (map[string]model.Asset, error) {
+	quotes := map[string]model.Asset{}
+
+	for start := 0; start < len(assetIds); start += maxAssetsPerBatch {
+		end := min(start+maxAssetsPerBatch, len(assetIds))
+
+		ids := make([]string, 0, end-start)
+		for _, assetId := range assetIds[start:end] {
+			ids = append(ids, p.coinGeckoId(assetId))
+		}
+
+		query := url.Values{}
+		query.Set("ids", strings.Join(ids, ","))
+		query.Set("vs_currencies", "usd")
+		query.Set("include_market_cap", "true")
+		query.Set("include_24hr_vol", "true")
+		query.Set("include_24hr_change", "true")
+
+		var APIResponse map[string]map[string]decimal.NullDecimal
+		err := p.client.getJSON(ctx, p.baseURL+"simple/price?"+query.Encode(), p.header, &APIResponse)
+		if err != nil {
+			return nil, err
+		}
+
+		for coinGeckoId, price := range APIResponse {
+			usd, ok := price["usd"]
+			if !ok || !usd.Valid {
+				continue
+			}
+
+			assetId := p.assetId(coinGeckoId)
+			quotes[assetId] = model.Asset{
+				AssetId:           assetId,
+				Price:             usd,
+				ChangePercent24Hr: price["usd_24h_change"],
+				MarketCap:         price["usd_market_cap"],
+				Volume24Hr:        price["usd_24h_vol"],
+			}
+		}
+	}
+
+	return quotes, nil
+}
+
+// GetRatesUSD derives the rates from CoinGecko's exchange rates, which are
+// the value of one bitcoin in each currency.
+func (p *coinGeckoProvider) GetRatesUSD(ctx context.Context) ([]model.CurrencyRate, error) {
+	if len(p.currencyIds) == 0 {
+		return nil, errNotSupported
+	}
+
+	var APIResponse struct {
+		Rates map[string]coinGeckoRate `json:"rates"`
+	}
+
+	err := p.client.getJSON(ctx, p.baseURL+"exchange_rates", p.header, &APIResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	usd, ok := APIResponse.Rates["usd"]
+	if !ok {
+		return nil, errNotSupported
+	}
+
+	rates := make([]model.CurrencyRate, 0, len(p.currencyIds))
+	for currencyId, code := range p.currencyIds {
+		currency, ok := APIResponse.Rates[code]
+		if !ok || currency.Value.IsZero() {
+			continue
+		}
+
+		rates = append(rates, model.CurrencyRate{
+			Id:             currencyId,
+			Symbol:         strings.ToUpper(code),
+			CurrencySymbol: currency.Unit,
+			Type:           currency.Type,
+			RateUSD:        usd.Value.DivRound(currency.Value, coinGeckoRatePrecision),
+		})
+	}
+	return rates, nil
+}
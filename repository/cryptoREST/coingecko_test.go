@@ -0,0 +1,75 @@
+package cryptoREST
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/shopspring/decimal"
+)
+
+func newTestCoinGeckoProvider(baseURL string) *coinGeckoProvider {
+	client := newRESTClient(newHTTPClient(), 0, config.RetryConfig{MaxAttempts: 1}, newProviderHealth(0, 0))
+	return newCoinGeckoProvider(client, config.CoinGeckoConfig{
+		BaseURL:        baseURL + "/",
+		TargetCurrency: "idr",
+		AssetIds:       map[string]string{"binance-coin": "binancecoin"},
+		CurrencyIds:    map[string]string{"singapore-dollar": "SGD", "euro": "EUR"},
+	}, "indonesian-rupiah")
+}
+
+func TestCoinGeckoGetQuotesUSD(t *testing.T) {
+	server := newFixtureServer(t, coinGeckoRoutes)
+	p := newTestCoinGeckoProvider(server.URL)
+
+	quotes, err := p.GetQuotesUSD(context.Background(), []string{"binance-coin", "dogecoin", "delisted-coin"})
+	if err != nil {
+		t.Fatalf("GetQuotesUSD: %v", err)
+	}
+	if len(quotes) != 2 {
+		t.Fatalf("quotes = %+v, want binance-coin and dogecoin", quotes)
+	}
+
+	binanceCoin, ok := quotes["binance-coin"]
+	if !ok || binanceCoin.AssetId != "binance-coin" || !binanceCoin.Price.Decimal.Equal(decimal.RequireFromString("601.23")) || !binanceCoin.MarketCap.Valid {
+		t.Fatalf("binance-coin = %+v, want CoinGecko's binancecoin", binanceCoin)
+	}
+
+	requests := server.received()
+	if len(requests) != 1 || requests[0].Query().Get("ids") != "binancecoin,dogecoin,delisted-coin" {
+		t.Fatalf("requests = %v, want one for CoinGecko's ids", requests)
+	}
+}
+
+func TestCoinGeckoGetRatesUSD(t *testing.T) {
+	p := newTestCoinGeckoProvider(newFixtureServer(t, coinGeckoRoutes).URL)
+
+	rates, err := p.GetRatesUSD(context.Background())
+	if err != nil {
+		t.Fatalf("GetRatesUSD: %v", err)
+	}
+
+	// EUR isn't in the recorded rates.
+	byId := map[string]decimal.Decimal{}
+	for _, rate := range rates {
+		byId[rate.Id] = rate.RateUSD
+	}
+	if len(byId) != 2 {
+		t.Fatalf("rates = %+v, want indonesian-rupiah and singapore-dollar", rates)
+	}
+
+	want := decimal.RequireFromString("67512.345").DivRound(decimal.RequireFromString("1099312345.5"), coinGeckoRatePrecision)
+	if !byId["indonesian-rupiah"].Equal(want) {
+		t.Fatalf("indonesian-rupiah = %s, want %s", byId["indonesian-rupiah"], want)
+	}
+}
+
+func TestCoinGeckoLookupAssetNotFound(t *testing.T) {
+	p := newTestCoinGeckoProvider(newFixtureServer(t, coinGeckoRoutes).URL)
+
+	_, err := p.LookupAsset(context.Background(), "nosuchcoin")
+	if !errors.Is(err, errAssetNotFound) {
+		t.Fatalf("LookupAsset(nosuchcoin) error = %v, want %v", err, errAssetNotFound)
+	}
+}
@@ -0,0 +1,162 @@
+package cryptoREST
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+)
+
+const (
+	defaultFailureThreshold = 3
+	defaultProviderCooldown = 30 * time.Second
+
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+// providerHealth is a circuit breaker around one provider. It opens after
+// threshold failures in a row, or as soon as the provider rate limits us, and
+// rejects requests for cooldown, or for as long as the provider's Retry-After
+// asks. Then it lets a single request through: the breaker closes if it
+// succeeds and opens again if it fails. It also counts requests for metrics.
+type providerHealth struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu          sync.Mutex
+	state       string
+	probing     bool
+	failures    int
+	lastError   string
+	lastSuccess time.Time
+	lastFailure time.Time
+	openUntil   time.Time
+
+	requests      int64
+	totalFailures int64
+	retries       int64
+	rejected      int64
+}
+
+func newProviderHealth(threshold int, cooldown time.Duration) *providerHealth {
+	if threshold <= 0 {
+		threshold = defaultFailureThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultProviderCooldown
+	}
+
+	return &providerHealth{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     breakerClosed,
+	}
+}
+
+// allow reports whether a request may be sent to the provider. Every request
+// allowed must be followed by success, failure or release.
+func (h *providerHealth) allow(currTime time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.state == breakerOpen && !currTime.Before(h.openUntil) {
+		h.state = breakerHalfOpen
+	}
+
+	switch {
+	case h.state == breakerClosed:
+	case h.state == breakerHalfOpen && !h.probing:
+		h.probing = true
+	default:
+		h.rejected++
+		return false
+	}
+
+	h.requests++
+	return true
+}
+
+// release ends a request that said nothing about the provider's health, such
+// as one it doesn't support or one that was canceled.
+func (h *providerHealth) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.probing = false
+}
+
+func (h *providerHealth) success(currTime time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.state = breakerClosed
+	h.probing = false
+	h.failures = 0
+	h.lastSuccess = currTime
+	h.openUntil = time.Time{}
+}
+
+func (h *providerHealth) failure(currTime time.Time, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.failures++
+	h.totalFailures++
+	h.lastError = err.Error()
+	h.lastFailure = currTime
+
+	wait := h.cooldown
+	var statusErr *statusError
+	rateLimited := errors.As(err, &statusErr) && statusErr.isRateLimited()
+	if rateLimited && statusErr.RetryAfter > 0 {
+		wait = statusErr.RetryAfter
+	}
+
+	if h.state == breakerHalfOpen || rateLimited || h.failures >= h.threshold {
+		h.state = breakerOpen
+		h.openUntil = currTime.Add(wait)
+	}
+	h.probing = false
+}
+
+// retried counts a request the HTTP client sent again.
+func (h *providerHealth) retried() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.retries++
+}
+
+func (h *providerHealth) snapshot(currTime time.Time) model.ProviderHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state := h.state
+	if state == breakerOpen && !currTime.Before(h.openUntil) {
+		state = breakerHalfOpen
+	}
+
+	return model.ProviderHealth{
+		State:               state,
+		Available:           state == breakerClosed || (state == breakerHalfOpen && !h.probing),
+		ConsecutiveFailures: h.failures,
+		LastError:           h.lastError,
+		LastSuccessAt:       unixOrZero(h.lastSuccess),
+		LastFailureAt:       unixOrZero(h.lastFailure),
+		UnavailableUntil:    unixOrZero(h.openUntil),
+		Requests:            h.requests,
+		Failures:            h.totalFailures,
+		Retries:             h.retries,
+		Rejected:            h.rejected,
+	}
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
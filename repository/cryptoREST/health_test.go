@@ -0,0 +1,55 @@
+package cryptoREST
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProviderHealthHalfOpenAllowsOneProbe(t *testing.T) {
+	h := newProviderHealth(1, time.Minute)
+	currTime := time.Now()
+
+	if !h.allow(currTime) {
+		t.Fatal("a closed breaker rejected a request")
+	}
+	h.failure(currTime, errors.New("boom"))
+
+	if h.allow(currTime.Add(time.Second)) {
+		t.Fatal("an open breaker let a request through")
+	}
+
+	probeTime := currTime.Add(time.Minute)
+	if !h.allow(probeTime) {
+		t.Fatal("the breaker let no probe through after the cooldown")
+	}
+	if h.allow(probeTime) {
+		t.Fatal("the breaker let a second request through while probing")
+	}
+
+	// A probe that says nothing about the provider lets another one through.
+	h.release()
+	if !h.allow(probeTime) {
+		t.Fatal("the breaker let no probe through after a released one")
+	}
+	h.success(probeTime)
+
+	if snapshot := h.snapshot(probeTime); snapshot.State != breakerClosed || snapshot.Rejected != 2 {
+		t.Fatalf("health = %+v, want closed with 2 rejected requests", snapshot)
+	}
+}
+
+func TestProviderHealthRetryAfter(t *testing.T) {
+	h := newProviderHealth(3, time.Minute)
+	currTime := time.Now()
+
+	h.allow(currTime)
+	h.failure(currTime, &statusError{StatusCode: 429, RetryAfter: 5 * time.Second})
+
+	if h.allow(currTime.Add(4 * time.Second)) {
+		t.Fatal("a rate limited provider was asked again before Retry-After")
+	}
+	if !h.allow(currTime.Add(5 * time.Second)) {
+		t.Fatal("a rate limited provider wasn't probed once Retry-After passed")
+	}
+}
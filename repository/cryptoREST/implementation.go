@@ -3,140 +3,392 @@ package cryptoREST
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
-	"strconv"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
 	"github.com/michaelwongycn/crypto-tracker/domain/model"
-	"github.com/michaelwongycn/crypto-tracker/domain/response"
 	"github.com/michaelwongycn/crypto-tracker/lib/cache"
 	"github.com/michaelwongycn/crypto-tracker/lib/log"
 )
 
 const (
-	apiRequestFailedErrorMsg   = "API request failed with status code"
-	invalidAPIResponseErrorMsg = "error when decoding response json"
-	errorAccessingAPIErrorMsg  = "error when accessing external API"
-	errorParsingPriceErrorMsg  = "error when parsing price string"
+	apiRequestFailedErrorMsg    = "API request failed with status code"
+	invalidAPIResponseErrorMsg  = "error when decoding response json"
+	errorAccessingAPIErrorMsg   = "error when accessing external API"
+	errorParsingPriceErrorMsg   = "error when parsing price string"
+	errorAccessingCacheErrorMsg = "error when accessing cache"
+	providerFailedErrorMsg      = "market data provider failed"
+	noPriceErrorMsg             = "no market data provider has a price for"
+
+	assetCacheNamespace = "asset"
+	rateCacheNamespace  = "rate"
+	ratesCacheKey       = "rates"
+
+	usdCurrency      = "united-states-dollar"
+	fiatCurrencyType = "fiat"
+
+	// Currency rates move slower than asset prices and are cached apart from
+	// them, this long unless configured.
+	defaultRateCacheDuration = 5 * time.Minute
+
+	// Providers don't delist assets often, a known asset is only checked again
+	// after this long.
+	validAssetCacheDuration = 24 * time.Hour
+
+	// Number of assets asked for in one batch request, which keeps the query
+	// string well under common URL length limits.
+	maxAssetsPerBatch = 100
+
+	// Number of single asset requests in flight at once when the batch request
+	// can't be used.
+	maxConcurrentAssetRequests = 8
 )
 
+var (
+	errNoProvider  = errors.New("no market data provider could serve the request")
+	errCircuitOpen = errors.New("market data provider circuit breaker is open")
+
+	// errIncomplete is returned to failover for a request a provider served in
+	// part, so the rest is asked of the next provider.
+	errIncomplete = errors.New("incomplete response")
+)
+
+type providerEntry struct {
+	provider Provider
+	health   *providerHealth
+}
+
+// cryptoRESTImpl serves market data from the configured providers in
+// priority order, failing over to the next one when a provider errors or
+// rate limits us, and skipping providers whose circuit breaker is open.
+// Prices are converted to currency, Coincap's id of the target currency.
 type cryptoRESTImpl struct {
-	timeout        time.Duration
-	baseURL        string
-	assetEndpoint  string
-	ratesEndpoint  string
-	targetCurrency string
+	providers         []providerEntry
+	assetCache        cache.Cache
+	rateCache         cache.Cache
+	rateCacheDuration time.Duration
+	currency          string
 }
 
-func NewCryptoRESTImpl(timeout time.Duration, baseURL, assetEndpoint, ratesEndpoint, targetCurrency string) CryptoRESTInterface {
-	return &cryptoRESTImpl{
-		timeout:        timeout * time.Second,
-		baseURL:        baseURL,
-		assetEndpoint:  assetEndpoint,
-		ratesEndpoint:  ratesEndpoint,
-		targetCurrency: targetCurrency,
+func NewCryptoRESTImpl(timeout time.Duration, appCache cache.Cache, cfg config.RestConfig) (CryptoRESTInterface, error) {
+	names := cfg.Providers
+	if len(names) == 0 {
+		names = []string{coincapProviderName}
 	}
+
+	httpClient := newHTTPClient()
+
+	providers := make([]providerEntry, 0, len(names))
+	for _, name := range names {
+		health := newProviderHealth(cfg.FailureThreshold, time.Second*cfg.Cooldown)
+		client := newRESTClient(httpClient, timeout*time.Second, cfg.Retry, health)
+
+		provider, err := newProvider(name, client, cfg)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, providerEntry{
+			provider: provider,
+			health:   health,
+		})
+	}
+
+	rateCacheDuration := time.Second * cfg.RateCacheDuration
+	if rateCacheDuration <= 0 {
+		rateCacheDuration = defaultRateCacheDuration
+	}
+
+	return &cryptoRESTImpl{
+		providers:         providers,
+		assetCache:        cache.Namespace(appCache, assetCacheNamespace),
+		rateCache:         cache.Namespace(appCache, rateCacheNamespace),
+		rateCacheDuration: rateCacheDuration,
+		currency:          cfg.Coincap.TargetCurrency,
+	}, nil
 }
 
 func (r *cryptoRESTImpl) IsValidAsset(ctx context.Context, asset string) (bool, error) {
-	if cache.GetCache(asset) != nil {
+	_, ok, err := r.assetCache.Get(ctx, asset)
+	if err != nil {
+		log.PrintLogErr(ctx, errorAccessingCacheErrorMsg, err)
+	}
+	if ok {
 		return true, nil
 	}
 
-	resp, err := http.Get(r.baseURL + r.assetEndpoint + asset)
+	err = r.failover(ctx, func(p Provider) error {
+		_, err := p.LookupAsset(ctx, asset)
+		return err
+	})
+	if errors.Is(err, errAssetNotFound) {
+		return false, nil
+	}
 	if err != nil {
-		log.PrintLogErr(ctx, errorAccessingAPIErrorMsg, err)
 		return false, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.PrintLogAPIErr(ctx, apiRequestFailedErrorMsg, resp.StatusCode)
-		return false, fmt.Errorf("%s: %d", apiRequestFailedErrorMsg, resp.StatusCode)
+	err = r.assetCache.Set(ctx, asset, "ok", validAssetCacheDuration)
+	if err != nil {
+		log.PrintLogErr(ctx, errorAccessingCacheErrorMsg, err)
+	}
+	return true, nil
+}
+
+// GetAssetCatalog lists up to limit assets by market cap rank, from the first
+// provider that has an asset list.
+func (r *cryptoRESTImpl) GetAssetCatalog(ctx context.Context, limit int) (*[]model.AssetInfo, error) {
+	var assets []model.AssetInfo
+	err := r.failover(ctx, func(p Provider) error {
+		catalogProvider, ok := p.(CatalogProvider)
+		if !ok {
+			return errNotSupported
+		}
+
+		var err error
+		assets, err = catalogProvider.ListAssets(ctx, limit)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var APIResponse struct {
-		Data response.AssetValidationDataResponse `json:"data"`
+	return &assets, nil
+}
+
+// GetAssetsPriceUSD quotes the user's assets in USD, each with the provider it
+// came from. Assets no provider has a price for come back without one, an
+// error means no provider could be asked.
+func (r *cryptoRESTImpl) GetAssetsPriceUSD(ctx context.Context, userAssets *[]model.UserAsset) (*[]model.Asset, error) {
+	assetIds := make([]string, 0, len(*userAssets))
+	for _, userAsset := range *userAssets {
+		assetIds = append(assetIds, userAsset.AssetId)
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&APIResponse)
+	quotes, err := r.getQuotesUSD(ctx, assetIds)
 	if err != nil {
-		log.PrintLogErr(ctx, invalidAPIResponseErrorMsg, err)
-		return false, err
+		return nil, err
 	}
 
-	if APIResponse.Data.ID == asset {
-		cache.SetCache(asset, "ok")
+	currTime := time.Now()
+
+	var data []model.Asset
+	for _, assetId := range assetIds {
+		asset, ok := quotes[assetId]
+		asset.AssetId = assetId
+		asset.Currency = usdCurrency
+		if ok {
+			asset.PriceUpdatedAt = currTime.Unix()
+		}
+		data = append(data, asset)
 	}
-	return APIResponse.Data.ID == asset, nil
+
+	return &data, nil
 }
 
-func (r *cryptoRESTImpl) GetAssetsPrice(ctx context.Context, userAssets *[]model.UserAsset) (*[]model.Asset, error) {
-	resp, err := http.Get(r.baseURL + r.ratesEndpoint + r.targetCurrency)
+// GetCurrencyRates lists the currencies prices can be quoted in, ordered by
+// id. The list is cached for rateCacheDuration.
+func (r *cryptoRESTImpl) GetCurrencyRates(ctx context.Context) (*[]model.CurrencyRate, error) {
+	cached, ok, err := r.rateCache.Get(ctx, ratesCacheKey)
+	if err != nil {
+		log.PrintLogErr(ctx, errorAccessingCacheErrorMsg, err)
+	}
+	if ok {
+		var rates []model.CurrencyRate
+		err = json.Unmarshal([]byte(cached), &rates)
+		if err == nil {
+			return &rates, nil
+		}
+		log.PrintLogErr(ctx, errorAccessingCacheErrorMsg, err)
+	}
+
+	var rates []model.CurrencyRate
+	err = r.failover(ctx, func(p Provider) error {
+		var err error
+		rates, err = p.GetRatesUSD(ctx)
+		return err
+	})
 	if err != nil {
-		log.PrintLogErr(ctx, errorAccessingAPIErrorMsg, err)
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.PrintLogAPIErr(ctx, apiRequestFailedErrorMsg, resp.StatusCode)
-		return nil, fmt.Errorf("%s: %d", apiRequestFailedErrorMsg, resp.StatusCode)
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Id < rates[j].Id
+	})
+
+	encoded, err := json.Marshal(rates)
+	if err == nil {
+		err = r.rateCache.Set(ctx, ratesCacheKey, string(encoded), r.rateCacheDuration)
 	}
+	if err != nil {
+		log.PrintLogErr(ctx, errorAccessingCacheErrorMsg, err)
+	}
+
+	return &rates, nil
+}
 
-	var APIResponse struct {
-		Data response.CurrencyRateDataResponse `json:"data"`
+// LookupCurrency finds a currency by its id, or else by its symbol in any
+// case, preferring a fiat currency when a fiat and a crypto one share it. An
+// empty currency is the target currency.
+func (r *cryptoRESTImpl) LookupCurrency(ctx context.Context, currency string) (*model.CurrencyRate, bool, error) {
+	if currency == "" {
+		currency = r.currency
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&APIResponse)
+	rates, err := r.GetCurrencyRates(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var found *model.CurrencyRate
+	for i, rate := range *rates {
+		if rate.Id == currency {
+			return &(*rates)[i], true, nil
+		}
+		if strings.EqualFold(rate.Symbol, currency) && (found == nil || found.Type != fiatCurrencyType) {
+			found = &(*rates)[i]
+		}
+	}
+	return found, found != nil, nil
+}
+
+// GetAssetHistoryUSD returns the USD price of an asset every resolution
+// between start and end, from the first provider that has history.
+func (r *cryptoRESTImpl) GetAssetHistoryUSD(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) (*[]model.PricePoint, error) {
+	var points []model.PricePoint
+	err := r.failover(ctx, func(p Provider) error {
+		historyProvider, ok := p.(HistoryProvider)
+		if !ok {
+			return errNotSupported
+		}
+
+		var err error
+		points, err = historyProvider.GetHistoryUSD(ctx, assetId, resolution, start, end)
+		return err
+	})
 	if err != nil {
-		log.PrintLogErr(ctx, invalidAPIResponseErrorMsg, err)
 		return nil, err
 	}
 
-	rateIDR, err := strconv.ParseFloat(APIResponse.Data.RateUSD, 64)
+	return &points, nil
+}
+
+// GetAssetVolumeUSD returns the USD value of an asset traded every resolution
+// between start and end, from the first provider that has volume. There are
+// no points when no provider has volume for the asset.
+func (r *cryptoRESTImpl) GetAssetVolumeUSD(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) (*[]model.VolumePoint, error) {
+	var points []model.VolumePoint
+	err := r.failover(ctx, func(p Provider) error {
+		volumeProvider, ok := p.(VolumeProvider)
+		if !ok {
+			return errNotSupported
+		}
+
+		var err error
+		points, err = volumeProvider.GetVolumeUSD(ctx, assetId, resolution, start, end)
+		return err
+	})
+	if errors.Is(err, errNoProvider) || errors.Is(err, errAssetNotFound) {
+		return &[]model.VolumePoint{}, nil
+	}
 	if err != nil {
-		log.PrintLogErr(ctx, errorParsingPriceErrorMsg, err)
 		return nil, err
 	}
 
-	var data []model.Asset
-	for _, userAsset := range *userAssets {
-		var asset model.Asset
-		resp, err := http.Get(r.baseURL + r.assetEndpoint + userAsset.AssetId)
+	return &points, nil
+}
+
+func (r *cryptoRESTImpl) GetProviderHealth() []model.ProviderHealth {
+	currTime := time.Now()
+
+	health := make([]model.ProviderHealth, 0, len(r.providers))
+	for i, entry := range r.providers {
+		providerHealth := entry.health.snapshot(currTime)
+		providerHealth.Name = entry.provider.Name()
+		providerHealth.Priority = i + 1
+		health = append(health, providerHealth)
+	}
+	return health
+}
+
+// getQuotesUSD returns the USD quote of every asset some provider has a price
+// for. Each provider is only asked for the assets the ones before it had no
+// quote for. It fails only when no provider answered at all.
+func (r *cryptoRESTImpl) getQuotesUSD(ctx context.Context, assetIds []string) (map[string]model.Asset, error) {
+	quotes := map[string]model.Asset{}
+	missing := assetIds
+	answered := false
+
+	err := r.failover(ctx, func(p Provider) error {
+		batch, err := p.GetQuotesUSD(ctx, missing)
 		if err != nil {
-			log.PrintLogErr(ctx, errorAccessingAPIErrorMsg, err)
-			return nil, err
+			return err
 		}
-		defer resp.Body.Close()
+		answered = true
 
-		if resp.StatusCode != http.StatusOK {
-			log.PrintLogAPIErr(ctx, apiRequestFailedErrorMsg, resp.StatusCode)
-			return nil, fmt.Errorf("%s: %d", apiRequestFailedErrorMsg, resp.StatusCode)
+		for assetId, quote := range batch {
+			quote.Source = p.Name()
+			quotes[assetId] = quote
 		}
 
-		var APIResponse struct {
-			Data response.AssetValidationDataResponse `json:"data"`
+		var stillMissing []string
+		for _, assetId := range missing {
+			if _, ok := quotes[assetId]; !ok {
+				stillMissing = append(stillMissing, assetId)
+			}
 		}
+		missing = stillMissing
 
-		err = json.NewDecoder(resp.Body).Decode(&APIResponse)
-		if err != nil {
-			log.PrintLogErr(ctx, invalidAPIResponseErrorMsg, err)
-			return nil, err
+		if len(missing) > 0 {
+			return errIncomplete
 		}
+		return nil
+	})
+	if err != nil && !answered {
+		return nil, fmt.Errorf("no price for %s: %w", strings.Join(missing, ", "), err)
+	}
+	if len(missing) > 0 {
+		log.PrintLogErr(ctx, noPriceErrorMsg, errors.New(strings.Join(missing, ", ")))
+	}
+	return quotes, nil
+}
 
-		price, err := strconv.ParseFloat(APIResponse.Data.PriceUSD, 64)
-		if err != nil {
-			log.PrintLogErr(ctx, errorParsingPriceErrorMsg, err)
-			return nil, err
+// failover calls f with each provider in priority order until one serves the
+// request. Providers whose circuit breaker is open are skipped, so while every
+// provider is down requests fail fast. errAssetNotFound is an answer and is
+// returned as is.
+func (r *cryptoRESTImpl) failover(ctx context.Context, f func(p Provider) error) error {
+	lastErr := errNoProvider
+	for _, entry := range r.providers {
+		if !entry.health.allow(time.Now()) {
+			if lastErr == errNoProvider {
+				lastErr = errCircuitOpen
+			}
+			continue
 		}
 
-		asset.AssetId = userAsset.AssetId
-		asset.Price = price / rateIDR
+		err := f(entry.provider)
 
-		data = append(data, asset)
+		switch {
+		case err == nil, errors.Is(err, errAssetNotFound):
+			entry.health.success(time.Now())
+			return err
+		case errors.Is(err, errIncomplete):
+			entry.health.success(time.Now())
+		case errors.Is(err, errNotSupported):
+			entry.health.release()
+		case ctx.Err() != nil:
+			entry.health.release()
+			return ctx.Err()
+		default:
+			log.PrintLogErr(ctx, providerFailedErrorMsg+" "+entry.provider.Name(), err)
+			entry.health.failure(time.Now(), err)
+			lastErr = err
+		}
 	}
 
-	return &data, nil
+	return lastErr
 }
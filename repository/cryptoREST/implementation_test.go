@@ -0,0 +1,349 @@
+package cryptoREST
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/cache"
+	"github.com/shopspring/decimal"
+)
+
+// fixtureServer replays recorded provider responses from testdata, routed by
+// path. Other paths get a 404 like Coincap's. setStatus makes every request
+// fail with a status instead.
+type fixtureServer struct {
+	*httptest.Server
+	t      *testing.T
+	routes map[string]string
+
+	mu         sync.Mutex
+	status     int
+	retryAfter string
+	requests   []*url.URL
+}
+
+func newFixtureServer(t *testing.T, routes map[string]string) *fixtureServer {
+	t.Helper()
+
+	s := &fixtureServer{t: t, routes: routes}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fixtureServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL)
+	status, retryAfter := s.status, s.retryAfter
+	s.mu.Unlock()
+
+	if status != 0 {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	fixture, ok := s.routes[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fixture = "coincap_not_found.json"
+	}
+	serveFixture(s.t, w, fixture)
+}
+
+func (s *fixtureServer) setStatus(status int, retryAfter string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status, s.retryAfter = status, retryAfter
+}
+
+// received returns the requests made so far.
+func (s *fixtureServer) received() []*url.URL {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*url.URL(nil), s.requests...)
+}
+
+// serveFixture answers with a file from testdata.
+func serveFixture(t *testing.T, w http.ResponseWriter, name string) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Errorf("reading fixture %s: %v", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+var (
+	coincapRoutes = map[string]string{
+		"/assets":         "coincap_assets.json",
+		"/assets/bitcoin": "coincap_asset_bitcoin.json",
+		"/rates":          "coincap_rates.json",
+	}
+	coinGeckoRoutes = map[string]string{
+		"/simple/price":   "coingecko_simple_price.json",
+		"/exchange_rates": "coingecko_exchange_rates.json",
+	}
+)
+
+func testRestConfig(coincapURL, coinGeckoURL string) config.RestConfig {
+	return config.RestConfig{
+		Providers:        []string{coincapProviderName, coinGeckoProviderName},
+		Retry:            config.RetryConfig{MaxAttempts: 1},
+		FailureThreshold: 2,
+		Coincap: config.CoincapConfig{
+			BaseURL:        coincapURL + "/",
+			AssetEndpoint:  "assets/",
+			RatesEndpoint:  "rates/",
+			TargetCurrency: "indonesian-rupiah",
+		},
+		CoinGecko: config.CoinGeckoConfig{
+			BaseURL:        coinGeckoURL + "/",
+			TargetCurrency: "idr",
+			AssetIds:       map[string]string{"binance-coin": "binancecoin"},
+		},
+	}
+}
+
+// newTestCryptoREST serves market data from a Coincap and a CoinGecko
+// fixture server, in that order. Breakers open for cooldown.
+func newTestCryptoREST(t *testing.T, cooldown time.Duration) (*cryptoRESTImpl, *fixtureServer, *fixtureServer) {
+	t.Helper()
+
+	coincap := newFixtureServer(t, coincapRoutes)
+	coinGecko := newFixtureServer(t, coinGeckoRoutes)
+
+	appCache, err := cache.NewCache(config.CacheConfig{})
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+
+	r, err := NewCryptoRESTImpl(0, appCache, testRestConfig(coincap.URL, coinGecko.URL))
+	if err != nil {
+		t.Fatalf("NewCryptoRESTImpl: %v", err)
+	}
+
+	impl := r.(*cryptoRESTImpl)
+	for _, entry := range impl.providers {
+		entry.health.cooldown = cooldown
+	}
+	return impl, coincap, coinGecko
+}
+
+func userAssets(assetIds ...string) *[]model.UserAsset {
+	data := []model.UserAsset{}
+	for _, assetId := range assetIds {
+		data = append(data, model.UserAsset{AssetId: assetId})
+	}
+	return &data
+}
+
+func breakerState(r *cryptoRESTImpl, i int) string {
+	return r.GetProviderHealth()[i].State
+}
+
+func TestNewCryptoRESTImplRejectsUnknownProvider(t *testing.T) {
+	cfg := testRestConfig("http://coincap.test", "http://coingecko.test")
+	cfg.Providers = append(cfg.Providers, "nosuchprovider")
+
+	_, err := NewCryptoRESTImpl(0, nil, cfg)
+	if err == nil {
+		t.Fatal("NewCryptoRESTImpl accepted an unknown provider")
+	}
+}
+
+func TestGetAssetsPriceUSDFailsOver(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		// state of Coincap's breaker after one failed request
+		state string
+	}{
+		{name: "server error", status: http.StatusInternalServerError, state: breakerClosed},
+		{name: "rate limited", status: http.StatusTooManyRequests, retryAfter: "60", state: breakerOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, coincap, _ := newTestCryptoREST(t, time.Minute)
+			coincap.setStatus(tt.status, tt.retryAfter)
+
+			assets, err := r.GetAssetsPriceUSD(context.Background(), userAssets("binance-coin", "dogecoin"))
+			if err != nil {
+				t.Fatalf("GetAssetsPriceUSD: %v", err)
+			}
+
+			for _, asset := range *assets {
+				if !asset.Price.Valid || asset.Source != coinGeckoProviderName {
+					t.Fatalf("%s = %+v, want a price from %s", asset.AssetId, asset, coinGeckoProviderName)
+				}
+			}
+			if !(*assets)[0].Price.Decimal.Equal(decimal.RequireFromString("601.23")) {
+				t.Fatalf("binance-coin = %s, want CoinGecko's binancecoin price", (*assets)[0].Price.Decimal)
+			}
+
+			if got := breakerState(r, 0); got != tt.state {
+				t.Fatalf("Coincap breaker is %s, want %s", got, tt.state)
+			}
+		})
+	}
+}
+
+func TestGetAssetsPriceUSDAsksNextProviderForMissingAssets(t *testing.T) {
+	r, coincap, coinGecko := newTestCryptoREST(t, time.Minute)
+
+	assets, err := r.GetAssetsPriceUSD(context.Background(), userAssets("bitcoin", "dogecoin", "nosuchcoin"))
+	if err != nil {
+		t.Fatalf("GetAssetsPriceUSD: %v", err)
+	}
+
+	bitcoin, dogecoin, nosuchcoin := (*assets)[0], (*assets)[1], (*assets)[2]
+	if bitcoin.Source != coincapProviderName || bitcoin.Name != "Bitcoin" || !bitcoin.Price.Valid {
+		t.Fatalf("bitcoin = %+v, want it from %s", bitcoin, coincapProviderName)
+	}
+	if dogecoin.Source != coinGeckoProviderName || !dogecoin.Price.Decimal.Equal(decimal.RequireFromString("0.161234")) {
+		t.Fatalf("dogecoin = %+v, want it from %s", dogecoin, coinGeckoProviderName)
+	}
+	if nosuchcoin.Price.Valid || nosuchcoin.Source != "" || nosuchcoin.PriceUpdatedAt != 0 {
+		t.Fatalf("nosuchcoin = %+v, want no price", nosuchcoin)
+	}
+
+	// CoinGecko is only asked for what Coincap didn't have.
+	requests := coinGecko.received()
+	if len(requests) != 1 || requests[0].Query().Get("ids") != "dogecoin,nosuchcoin" {
+		t.Fatalf("CoinGecko got %v, want one request for dogecoin and nosuchcoin", requests)
+	}
+
+	// An incomplete answer is not a failure.
+	for i, health := range r.GetProviderHealth() {
+		if health.State != breakerClosed || health.Failures != 0 {
+			t.Fatalf("provider %d health = %+v, want closed without failures", i, health)
+		}
+	}
+	if len(coincap.received()) != 3 {
+		t.Fatalf("Coincap got %d requests, want the batch and one per missing asset", len(coincap.received()))
+	}
+}
+
+func TestGetAssetsPriceUSDFailsWhenNoProviderAnswers(t *testing.T) {
+	r, coincap, coinGecko := newTestCryptoREST(t, time.Minute)
+	coincap.setStatus(http.StatusBadGateway, "")
+	coinGecko.setStatus(http.StatusServiceUnavailable, "")
+
+	_, err := r.GetAssetsPriceUSD(context.Background(), userAssets("bitcoin"))
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("GetAssetsPriceUSD error = %v, want the last provider's %d", err, http.StatusServiceUnavailable)
+	}
+}
+
+func TestIsValidAssetNotFoundIsAnAnswer(t *testing.T) {
+	r, _, coinGecko := newTestCryptoREST(t, time.Minute)
+	ctx := context.Background()
+
+	ok, err := r.IsValidAsset(ctx, "nosuchcoin")
+	if err != nil || ok {
+		t.Fatalf("IsValidAsset(nosuchcoin) = %v, %v, want false", ok, err)
+	}
+	if len(coinGecko.received()) != 0 {
+		t.Fatal("a 404 from Coincap failed over to CoinGecko")
+	}
+	if health := r.GetProviderHealth()[0]; health.Failures != 0 {
+		t.Fatalf("a 404 counted as a failure: %+v", health)
+	}
+
+	ok, err = r.IsValidAsset(ctx, "bitcoin")
+	if err != nil || !ok {
+		t.Fatalf("IsValidAsset(bitcoin) = %v, %v, want true", ok, err)
+	}
+}
+
+func TestGetCurrencyRates(t *testing.T) {
+	r, _, _ := newTestCryptoREST(t, time.Minute)
+	ctx := context.Background()
+
+	rates, err := r.GetCurrencyRates(ctx)
+	if err != nil {
+		t.Fatalf("GetCurrencyRates: %v", err)
+	}
+	if len(*rates) != 3 || (*rates)[0].Id != "bitcoin" || (*rates)[2].Id != "united-states-dollar" {
+		t.Fatalf("rates = %+v, want Coincap's three ordered by id", *rates)
+	}
+
+	rate, ok, err := r.LookupCurrency(ctx, "idr")
+	if err != nil || !ok || rate.Id != "indonesian-rupiah" || !rate.RateUSD.Equal(decimal.RequireFromString("0.0000614129923145")) {
+		t.Fatalf("LookupCurrency(idr) = %+v, %v, %v", rate, ok, err)
+	}
+}
+
+func TestCircuitBreakerOpensAndHalfOpens(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	r, coincap, _ := newTestCryptoREST(t, cooldown)
+	ctx := context.Background()
+
+	price := func() {
+		t.Helper()
+		_, err := r.GetAssetsPriceUSD(ctx, userAssets("dogecoin"))
+		if err != nil {
+			t.Fatalf("GetAssetsPriceUSD: %v", err)
+		}
+	}
+
+	// Two failures in a row open the breaker, then Coincap is skipped.
+	coincap.setStatus(http.StatusInternalServerError, "")
+	price()
+	if got := breakerState(r, 0); got != breakerClosed {
+		t.Fatalf("breaker is %s after one failure, want %s", got, breakerClosed)
+	}
+	price()
+	if got := breakerState(r, 0); got != breakerOpen {
+		t.Fatalf("breaker is %s after two failures, want %s", got, breakerOpen)
+	}
+
+	requests := len(coincap.received())
+	price()
+	if len(coincap.received()) != requests {
+		t.Fatal("a request was sent to Coincap while its breaker was open")
+	}
+
+	// After the cooldown a single probe is let through. A failed probe opens
+	// the breaker again right away.
+	time.Sleep(cooldown)
+	if got := breakerState(r, 0); got != breakerHalfOpen {
+		t.Fatalf("breaker is %s after the cooldown, want %s", got, breakerHalfOpen)
+	}
+	price()
+	if len(coincap.received()) == requests {
+		t.Fatal("no probe was sent to Coincap after the cooldown")
+	}
+	if got := breakerState(r, 0); got != breakerOpen {
+		t.Fatalf("breaker is %s after a failed probe, want %s", got, breakerOpen)
+	}
+
+	// A successful probe closes it.
+	coincap.setStatus(0, "")
+	time.Sleep(cooldown)
+	price()
+	if got := breakerState(r, 0); got != breakerClosed {
+		t.Fatalf("breaker is %s after a successful probe, want %s", got, breakerClosed)
+	}
+}
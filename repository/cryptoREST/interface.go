@@ -2,11 +2,18 @@ package cryptoREST
 
 import (
 	"context"
+	"time"
 
 	"github.com/michaelwongycn/crypto-tracker/domain/model"
 )
 
 type CryptoRESTInterface interface {
 	IsValidAsset(ctx context.Context, asset string) (bool, error)
-	GetAssetsPrice(ctx context.Context, userAssets *[]model.UserAsset) (*[]model.Asset, error)
+	GetAssetCatalog(ctx context.Context, limit int) (*[]model.AssetInfo, error)
+	GetAssetsPriceUSD(ctx context.Context, userAssets *[]model.UserAsset) (*[]model.Asset, error)
+	GetCurrencyRates(ctx context.Context) (*[]model.CurrencyRate, error)
+	LookupCurrency(ctx context.Context, currency string) (*model.CurrencyRate, bool, error)
+	GetProviderHealth() []model.ProviderHealth
+	GetAssetHistoryUSD(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) (*[]model.PricePoint, error)
+	GetAssetVolumeUSD(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) (*[]model.VolumePoint, error)
 }
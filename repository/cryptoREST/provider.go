@@ -0,0 +1,85 @@
+package cryptoREST
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	coincapProviderName   = "coincap"
+	coinGeckoProviderName = "coingecko"
+	binanceProviderName   = "binance"
+)
+
+var (
+	// errAssetNotFound is a provider's answer that an asset doesn't exist. It
+	// is an answer rather than a failure, so it doesn't trigger failover.
+	errAssetNotFound = errors.New("asset not found")
+
+	// errNotSupported means a provider can't serve a request, such as Binance
+	// asked for an asset it has no symbol configured for. The next provider is
+	// tried without counting it as a failure.
+	errNotSupported = errors.New("not supported by provider")
+)
+
+// Provider is a source of market data. Assets are named by our asset ids,
+// which are Coincap's, a provider that names them differently maps them
+// itself.
+type Provider interface {
+	Name() string
+	LookupAsset(ctx context.Context, assetId string) (*model.AssetInfo, error)
+	// GetQuotesUSD returns the market data in USD of the assets it has a price
+	// for, with whatever else it knows about them.
+	GetQuotesUSD(ctx context.Context, assetIds []string) (map[string]model.Asset, error)
+	// GetRatesUSD returns the currencies it has a USD rate for.
+	GetRatesUSD(ctx context.Context) ([]model.CurrencyRate, error)
+}
+
+// CatalogProvider is a Provider that can list the assets it knows, by market
+// cap rank.
+type CatalogProvider interface {
+	Provider
+	ListAssets(ctx context.Context, limit int) ([]model.AssetInfo, error)
+}
+
+// HistoryProvider is a Provider that also has past prices.
+type HistoryProvider interface {
+	Provider
+	GetHistoryUSD(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) ([]model.PricePoint, error)
+}
+
+// VolumeProvider is a Provider that has the traded volume of past periods.
+type VolumeProvider interface {
+	Provider
+	GetVolumeUSD(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) ([]model.VolumePoint, error)
+}
+
+// newProvider builds the provider configured under name.
+func newProvider(name string, client *restClient, cfg config.RestConfig) (Provider, error) {
+	switch name {
+	case coincapProviderName:
+		return newCoincapProvider(client, cfg.Coincap), nil
+	case coinGeckoProviderName:
+		return newCoinGeckoProvider(client, cfg.CoinGecko, cfg.Coincap.TargetCurrency), nil
+	case binanceProviderName:
+		return newBinanceProvider(client, cfg.Binance), nil
+	default:
+		return nil, fmt.Errorf("unknown market data provider: %s", name)
+	}
+}
+
+// parseOptionalDecimal reads a number a provider may leave empty, anything
+// that isn't a number is null.
+func parseOptionalDecimal(value string) decimal.NullDecimal {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.NullDecimal{}
+	}
+	return decimal.NewNullDecimal(d)
+}
@@ -0,0 +1,166 @@
+package asset
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoDB"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoREST"
+)
+
+const (
+	defaultSyncInterval = 6 * time.Hour
+	defaultMaxAssets    = 2000
+
+	failedToSyncCatalogErrorMsg = "failed to sync asset catalog"
+	failedToLoadCatalogErrorMsg = "failed to load asset catalog"
+)
+
+var ErrEmptyQuery = errors.New("empty search query")
+
+type catalogEntry struct {
+	asset  model.AssetInfo
+	symbol string
+	name   string
+	words  []string
+}
+
+// newCatalogEntry lowercases the asset's symbol and name and splits the name
+// into words for searching.
+func newCatalogEntry(asset model.AssetInfo) catalogEntry {
+	name := strings.ToLower(asset.Name)
+	return catalogEntry{
+		asset:  asset,
+		symbol: strings.ToLower(asset.Symbol),
+		name:   name,
+		words:  strings.FieldsFunc(name, isWordSeparator),
+	}
+}
+
+// Catalog keeps a local copy of the assets the price API lists, synced in
+// the background every interval, so assets can be searched by symbol and name
+// and known ids validated without calling the price API. The catalog is
+// stored in the database and searched from memory.
+type Catalog struct {
+	dbCrypto   cryptoDB.CryptoDBInterface
+	restCrypto cryptoREST.CryptoRESTInterface
+	interval   time.Duration
+	maxAssets  int
+
+	mu      sync.RWMutex
+	entries []catalogEntry
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewCatalog(dbCrypto cryptoDB.CryptoDBInterface, restCrypto cryptoREST.CryptoRESTInterface, cfg config.CatalogConfig) *Catalog {
+	interval := time.Second * cfg.SyncInterval
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+
+	maxAssets := cfg.MaxAssets
+	if maxAssets <= 0 {
+		maxAssets = defaultMaxAssets
+	}
+
+	return &Catalog{
+		dbCrypto:   dbCrypto,
+		restCrypto: restCrypto,
+		interval:   interval,
+		maxAssets:  maxAssets,
+	}
+}
+
+// Start loads the stored catalog, then syncs it right away and every interval
+// until Stop is called.
+func (c *Catalog) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+
+	err := c.load(ctx)
+	if err != nil {
+		log.PrintLogErr(ctx, failedToLoadCatalogErrorMsg, err)
+	}
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			err := c.Sync(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.PrintLogErr(ctx, failedToSyncCatalogErrorMsg, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels the running sync and waits for the catalog to exit, or for
+// ctx to be done.
+func (c *Catalog) Stop(ctx context.Context) error {
+	if c.cancel == nil {
+		return nil
+	}
+
+	c.cancel()
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sync replaces the catalog with the first maxAssets assets the price API
+// lists. An empty list is ignored rather than wiping the catalog.
+func (c *Catalog) Sync(ctx context.Context) error {
+	assets, err := c.restCrypto.GetAssetCatalog(ctx, c.maxAssets)
+	if err != nil {
+		return err
+	}
+	if len(*assets) == 0 {
+		return nil
+	}
+
+	err = c.dbCrypto.SyncAssetCatalog(ctx, *assets, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	return c.load(ctx)
+}
+
+func (c *Catalog) load(ctx context.Context) error {
+	assets, err := c.dbCrypto.GetAssetCatalog(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]catalogEntry, 0, len(*assets))
+	for _, asset := range *assets {
+		entries = append(entries, newCatalogEntry(asset))
+	}
+
+	c.mu.Lock()
+	c.entries = entries
+	c.mu.Unlock()
+	return nil
+}
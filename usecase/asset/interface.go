@@ -0,0 +1,11 @@
+package asset
+
+import (
+	"context"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+)
+
+type AssetUsecase interface {
+	SearchAssets(ctx context.Context, query string, limit int) (*[]model.AssetInfo, error)
+}
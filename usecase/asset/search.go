@@ -0,0 +1,180 @@
+package asset
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+
+	// Shortest query matched with typos or as a subsequence, shorter ones
+	// would match nearly every asset.
+	minFuzzyQueryLength = 3
+)
+
+// How well an asset matches a query, better matches first.
+const (
+	matchExactSymbol = iota
+	matchExactName
+	matchSymbolPrefix
+	matchNamePrefix
+	matchWordPrefix
+	matchSubstring
+	matchTypo
+	matchSubsequence
+)
+
+// SearchAssets finds up to limit assets whose symbol or name matches query,
+// ignoring case. Exact matches come first, then prefixes of the symbol, the
+// name or a word of the name, then substrings, then names a typo or two away
+// and names holding the query's letters in order. Matches that are as good
+// are ordered by rank.
+func (c *Catalog) SearchAssets(ctx context.Context, query string, limit int) (*[]model.AssetInfo, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	c.mu.RLock()
+	entries := c.entries
+	c.mu.RUnlock()
+
+	type match struct {
+		score int
+		asset model.AssetInfo
+	}
+
+	var matches []match
+	for i := range entries {
+		score, ok := matchScore(&entries[i], query)
+		if ok {
+			matches = append(matches, match{score: score, asset: entries[i].asset})
+		}
+	}
+
+	// Entries are in rank order, which a stable sort keeps among ties.
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score < matches[j].score
+	})
+
+	data := make([]model.AssetInfo, 0, min(limit, len(matches)))
+	for _, m := range matches[:min(limit, len(matches))] {
+		data = append(data, m.asset)
+	}
+	return &data, nil
+}
+
+func matchScore(entry *catalogEntry, query string) (int, bool) {
+	switch {
+	case entry.symbol == query:
+		return matchExactSymbol, true
+	case entry.name == query:
+		return matchExactName, true
+	case strings.HasPrefix(entry.symbol, query):
+		return matchSymbolPrefix, true
+	case strings.HasPrefix(entry.name, query):
+		return matchNamePrefix, true
+	case hasWordPrefix(entry.words, query):
+		return matchWordPrefix, true
+	case strings.Contains(entry.name, query) || strings.Contains(entry.symbol, query):
+		return matchSubstring, true
+	case isTypo(entry, query):
+		return matchTypo, true
+	case isSubsequence(query, entry.name):
+		return matchSubsequence, true
+	}
+	return 0, false
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func hasWordPrefix(words []string, query string) bool {
+	for _, word := range words {
+		if strings.HasPrefix(word, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTypo reports whether the start of the name, or of one of its words, is
+// within one edit of the query, or two for queries of 8 letters or more.
+func isTypo(entry *catalogEntry, query string) bool {
+	q := []rune(query)
+	if len(q) < minFuzzyQueryLength {
+		return false
+	}
+
+	maxEdits := 1
+	if len(q) >= 8 {
+		maxEdits = 2
+	}
+
+	candidates := append([]string{entry.name, entry.symbol}, entry.words...)
+	for _, candidate := range candidates {
+		c := []rune(candidate)
+		for n := len(q) - maxEdits; n <= len(q)+maxEdits; n++ {
+			if n <= 0 || n > len(c) {
+				continue
+			}
+			if editDistance(q, c[:n]) <= maxEdits {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSubsequence reports whether s holds every letter of query in order.
+func isSubsequence(query, s string) bool {
+	q := []rune(query)
+	if len(q) < minFuzzyQueryLength {
+		return false
+	}
+
+	i := 0
+	for _, r := range s {
+		if r == q[i] {
+			i++
+			if i == len(q) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,213 @@
+package asset
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/db"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoDB"
+)
+
+// testCatalogAssets is out of rank order, the catalog has to sort it.
+var testCatalogAssets = []model.AssetInfo{
+	{AssetId: "wrapped-bitcoin", Symbol: "WBTC", Name: "Wrapped Bitcoin", Rank: 15},
+	{AssetId: "bitcoin-sv", Symbol: "BSV", Name: "Bitcoin SV", Rank: 30},
+	{AssetId: "ethereum", Symbol: "ETH", Name: "Ethereum", Rank: 2},
+	{AssetId: "bitcoin-cash", Symbol: "BCH", Name: "Bitcoin Cash", Rank: 20},
+	{AssetId: "bitcoin", Symbol: "BTC", Name: "Bitcoin", Rank: 1},
+	{AssetId: "polygon", Symbol: "MATIC", Name: "Polygon", Rank: 12},
+	{AssetId: "ethereum-classic", Symbol: "ETC", Name: "Ethereum Classic", Rank: 25},
+}
+
+// newTestCatalog stores assets in a fresh database and loads them into a
+// catalog. db.Connect opens a path relative to the working directory.
+func newTestCatalog(t *testing.T, assets []model.AssetInfo) *Catalog {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	name, err := filepath.Rel(wd, filepath.Join(t.TempDir(), "crypto"))
+	if err != nil {
+		t.Fatalf("Rel: %v", err)
+	}
+
+	database, err := db.Connect(5, name)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	ctx := context.Background()
+	dbCrypto := cryptoDB.NewCryptoDBImpl(5, database)
+	err = dbCrypto.SyncAssetCatalog(ctx, assets, time.Now().Unix())
+	if err != nil {
+		t.Fatalf("SyncAssetCatalog: %v", err)
+	}
+
+	c := NewCatalog(dbCrypto, nil, config.CatalogConfig{})
+	err = c.load(ctx)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return c
+}
+
+func TestMatchScore(t *testing.T) {
+	tests := []struct {
+		query     string
+		name      string
+		symbol    string
+		wantScore int
+		wantOk    bool
+	}{
+		{"btc", "Bitcoin", "BTC", matchExactSymbol, true},
+		{"bitcoin", "Bitcoin", "BTC", matchExactName, true},
+		{"bt", "Bitcoin", "BTC", matchSymbolPrefix, true},
+		{"bitc", "Bitcoin", "BTC", matchNamePrefix, true},
+		{"cash", "Bitcoin Cash", "BCH", matchWordPrefix, true},
+		{"coin", "Bitcoin", "BTC", matchSubstring, true},
+		{"bitcon", "Bitcoin", "BTC", matchTypo, true},
+		// Queries of 8 letters or more allow two edits.
+		{"etheruem", "Ethereum", "ETH", matchTypo, true},
+		{"bco", "Bitcoin", "BTC", matchSubsequence, true},
+		{"plygn", "Polygon", "MATIC", matchSubsequence, true},
+		{"xyz", "Bitcoin", "BTC", 0, false},
+		{"bitcqqn", "Bitcoin", "BTC", 0, false},
+	}
+
+	for _, tt := range tests {
+		entry := newCatalogEntry(model.AssetInfo{Name: tt.name, Symbol: tt.symbol})
+		score, ok := matchScore(&entry, tt.query)
+		if ok != tt.wantOk || score != tt.wantScore {
+			t.Errorf("matchScore(%s, %q) = %d, %v, want %d, %v", tt.name, tt.query, score, ok, tt.wantScore, tt.wantOk)
+		}
+	}
+}
+
+func TestFuzzyMatchesNeedMinimumQueryLength(t *testing.T) {
+	entry := newCatalogEntry(model.AssetInfo{Name: "Bitcoin", Symbol: "BTC"})
+
+	tests := []struct {
+		query     string
+		wantScore int
+		wantOk    bool
+	}{
+		// One edit from "bi" and a subsequence of "bitcoin", but too short.
+		{"xi", 0, false},
+		{"bc", 0, false},
+		{"bxt", matchTypo, true},
+		{"bco", matchSubsequence, true},
+	}
+
+	for _, tt := range tests {
+		score, ok := matchScore(&entry, tt.query)
+		if ok != tt.wantOk || score != tt.wantScore {
+			t.Errorf("matchScore(%q) = %d, %v, want %d, %v", tt.query, score, ok, tt.wantScore, tt.wantOk)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "abc", 0},
+		{"abc", "abd", 1},
+		{"abc", "ab", 1},
+		{"ab", "ba", 2},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSearchAssets(t *testing.T) {
+	c := newTestCatalog(t, testCatalogAssets)
+	ctx := context.Background()
+
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		// The exact name first, then name prefixes by rank, then the word
+		// prefix.
+		{"Bitcoin", 0, []string{"bitcoin", "bitcoin-cash", "bitcoin-sv", "wrapped-bitcoin"}},
+		{" bitcoin ", 2, []string{"bitcoin", "bitcoin-cash"}},
+		// The exact symbol, then the symbols a typo away by rank.
+		{"etc", 0, []string{"ethereum-classic", "bitcoin", "ethereum", "wrapped-bitcoin"}},
+		{"etherium", 0, []string{"ethereum", "ethereum-classic"}},
+		{"nosuchasset", 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		assets, err := c.SearchAssets(ctx, tt.query, tt.limit)
+		if err != nil {
+			t.Fatalf("SearchAssets(%q): %v", tt.query, err)
+		}
+
+		got := make([]string, 0, len(*assets))
+		for _, asset := range *assets {
+			got = append(got, asset.AssetId)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("SearchAssets(%q, %d) = %v, want %v", tt.query, tt.limit, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("SearchAssets(%q, %d) = %v, want %v", tt.query, tt.limit, got, tt.want)
+				break
+			}
+		}
+	}
+
+	_, err := c.SearchAssets(ctx, "  ", 0)
+	if err != ErrEmptyQuery {
+		t.Fatalf("SearchAssets with an empty query error = %v, want %v", err, ErrEmptyQuery)
+	}
+}
+
+func TestSearchAssetsCapsLimit(t *testing.T) {
+	assets := make([]model.AssetInfo, 0, maxSearchLimit+10)
+	for i := range maxSearchLimit + 10 {
+		assets = append(assets, model.AssetInfo{AssetId: fmt.Sprintf("token-%02d", i), Symbol: "TKN", Name: "Token", Rank: i + 1})
+	}
+	c := newTestCatalog(t, assets)
+	ctx := context.Background()
+
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{0, defaultSearchLimit},
+		{-1, defaultSearchLimit},
+		{5, 5},
+		{maxSearchLimit + 5, maxSearchLimit},
+	}
+
+	for _, tt := range tests {
+		data, err := c.SearchAssets(ctx, "tkn", tt.limit)
+		if err != nil {
+			t.Fatalf("SearchAssets: %v", err)
+		}
+		if len(*data) != tt.want {
+			t.Errorf("SearchAssets with limit %d returned %d assets, want %d", tt.limit, len(*data), tt.want)
+		}
+	}
+}
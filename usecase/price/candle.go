@@ -0,0 +1,264 @@
+package price
+
+import (
+	"context"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/michaelwongycn/crypto-tracker/lib/money"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoDB"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoREST"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultCandleInterval = "1h"
+	defaultCandleCount    = 100
+
+	failedToGetVolumeErrorMsg = "failed to get candle volume"
+)
+
+// candleIntervals are the intervals candles are materialized and can be asked
+// for in.
+var candleIntervals = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+var candleResolutions = []int64{60, 300, secondsPerHour, secondsPerDay}
+
+// buildCandles groups ticks, oldest first, into candles of resolution
+// seconds. A candle still open at currTime is marked incomplete.
+func buildCandles(ticks []model.PriceHistory, assetId, currency string, resolution int64, currTime time.Time) []model.PriceCandle {
+	candles := []model.PriceCandle{}
+	for _, tick := range ticks {
+		openTime := tick.Timestamp / resolution * resolution
+
+		n := len(candles)
+		if n == 0 || candles[n-1].OpenTime != openTime {
+			candles = append(candles, model.PriceCandle{
+				AssetId:    assetId,
+				Currency:   currency,
+				Resolution: resolution,
+				OpenTime:   openTime,
+				CloseTime:  openTime + resolution,
+				Open:       tick.Price,
+				High:       tick.Price,
+				Low:        tick.Price,
+				Complete:   openTime+resolution <= currTime.Unix(),
+			})
+			n++
+		}
+
+		candle := &candles[n-1]
+		if tick.Price.GreaterThan(candle.High) {
+			candle.High = tick.Price
+		}
+		if tick.Price.LessThan(candle.Low) {
+			candle.Low = tick.Price
+		}
+		candle.Close = tick.Price
+		candle.Ticks++
+	}
+	return candles
+}
+
+// candleRetentionStart returns the open time of the oldest candle of a
+// resolution that is kept, or 0 when they are kept forever.
+func candleRetentionStart(cfg config.PriceHistoryConfig, resolution int64, currTime time.Time) int64 {
+	if resolution < secondsPerHour {
+		return currTime.Add(-daysDuration(cfg.HourlyRetentionDays)).Unix()
+	}
+	if cfg.DailyRetentionDays > 0 {
+		return currTime.Add(-daysDuration(cfg.DailyRetentionDays)).Unix()
+	}
+	return 0
+}
+
+// materializeCandles stores the USD candles that closed since the last stored
+// one of every asset with price history, so queries only have to build the
+// candles that are still open. Their volume is fetched once here, candles are
+// stored without one when no provider has it.
+func materializeCandles(ctx context.Context, dbCrypto cryptoDB.CryptoDBInterface, restCrypto cryptoREST.CryptoRESTInterface, cfg config.PriceHistoryConfig, currTime time.Time) error {
+	assetIds, err := dbCrypto.GetPriceHistoryAssetIds(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, assetId := range *assetIds {
+		for _, resolution := range candleResolutions {
+			end := currTime.Unix() / resolution * resolution
+
+			start, err := dbCrypto.GetLastPriceCandleTime(ctx, assetId, usdCurrency, resolution)
+			if err != nil {
+				return err
+			}
+
+			if start > 0 {
+				start += resolution
+			} else {
+				start, err = dbCrypto.GetFirstPriceTime(ctx, assetId, resolution)
+				if err != nil {
+					return err
+				}
+				if start == 0 {
+					continue
+				}
+			}
+
+			start = max(start, candleRetentionStart(cfg, resolution, currTime))
+			start = start / resolution * resolution
+			if start >= end {
+				continue
+			}
+
+			ticks, err := dbCrypto.GetPriceTicks(ctx, assetId, start, end, resolution)
+			if err != nil {
+				return err
+			}
+
+			candles := buildCandles(*ticks, assetId, usdCurrency, resolution, currTime)
+			if len(candles) == 0 {
+				continue
+			}
+
+			duration := time.Duration(resolution) * time.Second
+			volumes, err := restCrypto.GetAssetVolumeUSD(ctx, assetId, duration, time.Unix(start, 0), time.Unix(end, 0))
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.PrintLogErr(ctx, failedToGetVolumeErrorMsg, err)
+			} else {
+				addCandleVolumes(candles, *volumes)
+			}
+
+			err = dbCrypto.InsertPriceCandles(ctx, candles)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetPriceCandles returns the candles of an asset that open between from and
+// to. Stored candles are used where they exist, the rest, including the
+// candle that is still open, are built from the stored prices. A zero to
+// means now and a zero from means 100 candles before to.
+//
+// Candles are stored in USD and converted to currency, given by id or symbol,
+// or else to the user's preferred currency, with today's rates. Only stored
+// candles have a volume, the ones built on request don't.
+func (p *priceImpl) GetPriceCandles(ctx context.Context, userId int, assetId string, from, to time.Time, interval, currency string) (*model.PriceCandleSeries, error) {
+	if interval == "" {
+		interval = defaultCandleInterval
+	}
+	duration, ok := candleIntervals[interval]
+	if !ok {
+		return nil, ErrInvalidInterval
+	}
+
+	currTime := time.Now()
+	if to.IsZero() {
+		to = currTime
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultCandleCount * duration)
+	}
+	if !from.Before(to) || to.Sub(from)/duration > maxHistoryPoints {
+		return nil, ErrInvalidTimeRange
+	}
+
+	rate, err := p.currencyRate(ctx, userId, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	resolution := int64(duration / time.Second)
+	fromUnix := from.Unix() / resolution * resolution
+	toUnix := to.Unix()
+
+	candles, err := p.dbCrypto.GetPriceCandles(ctx, assetId, usdCurrency, resolution, fromUnix, toUnix)
+	if err != nil {
+		return nil, err
+	}
+
+	lastStored, err := p.dbCrypto.GetLastPriceCandleTime(ctx, assetId, usdCurrency, resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	liveStart := fromUnix
+	if lastStored > 0 {
+		liveStart = max(liveStart, lastStored+resolution)
+	}
+
+	if liveStart < toUnix {
+		// Take the ticks up to the end of the last candle, not to, so it isn't
+		// cut short.
+		liveEnd := (toUnix + resolution - 1) / resolution * resolution
+
+		ticks, err := p.dbCrypto.GetPriceTicks(ctx, assetId, liveStart, liveEnd, resolution)
+		if err != nil {
+			return nil, err
+		}
+
+		*candles = append(*candles, buildCandles(*ticks, assetId, usdCurrency, resolution, currTime)...)
+	}
+
+	for i := range *candles {
+		err = convertCandle(&(*candles)[i], *rate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.PriceCandleSeries{
+		AssetId:  assetId,
+		Currency: rate.Id,
+		Interval: interval,
+		From:     fromUnix,
+		To:       toUnix,
+		Candles:  *candles,
+	}, nil
+}
+
+// convertCandle converts the prices of a candle stored in USD to currency.
+func convertCandle(candle *model.PriceCandle, currency model.CurrencyRate) error {
+	prices := []*decimal.Decimal{&candle.Open, &candle.High, &candle.Low, &candle.Close}
+	if candle.Volume.Valid {
+		prices = append(prices, &candle.Volume.Decimal)
+	}
+
+	for _, price := range prices {
+		converted, err := money.Convert(currency.Id, *price, currency.RateUSD)
+		if err != nil {
+			return err
+		}
+		*price = converted
+	}
+
+	candle.Currency = currency.Id
+	return nil
+}
+
+// addCandleVolumes sets the USD volume of each candle that opens when a
+// volume point does.
+func addCandleVolumes(candles []model.PriceCandle, volumes []model.VolumePoint) {
+	volumeByTime := make(map[int64]decimal.Decimal, len(volumes))
+	for _, volume := range volumes {
+		volumeByTime[volume.Timestamp] = volume.Volume
+	}
+
+	for i := range candles {
+		if volume, ok := volumeByTime[candles[i].OpenTime]; ok {
+			candles[i].Volume = decimal.NewNullDecimal(volume)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package price
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/shopspring/decimal"
+)
+
+func TestGetPriceCandlesConvertsStoredVolume(t *testing.T) {
+	ctx := context.Background()
+	dbCrypto := newTestDBCrypto(t)
+
+	hour := time.Now().Add(-3*time.Hour).Unix() / secondsPerHour * secondsPerHour
+	err := dbCrypto.InsertPriceHistory(ctx, []model.PriceHistory{
+		{AssetId: "bitcoin", Timestamp: hour + 60, Price: decimal.NewFromInt(100)},
+		{AssetId: "bitcoin", Timestamp: hour + 120, Price: decimal.NewFromInt(120)},
+		{AssetId: "bitcoin", Timestamp: hour + 180, Price: decimal.NewFromInt(90)},
+	})
+	if err != nil {
+		t.Fatalf("InsertPriceHistory: %v", err)
+	}
+
+	rest := &fakeREST{
+		rates:   []model.CurrencyRate{{Id: "euro", Symbol: "EUR", Type: "fiat", RateUSD: decimal.NewFromInt(2)}},
+		volumes: []model.VolumePoint{{Timestamp: hour, Volume: decimal.NewFromInt(5000)}},
+	}
+	p := NewPriceImpl(dbCrypto, rest, config.PriceHistoryConfig{}, testCurrency)
+
+	err = materializeCandles(ctx, dbCrypto, rest, config.PriceHistoryConfig{}, time.Now())
+	if err != nil {
+		t.Fatalf("materializeCandles: %v", err)
+	}
+	volumeCalls := rest.volumeCalls
+	if volumeCalls == 0 {
+		t.Fatalf("materializeCandles didn't request the volume")
+	}
+
+	from, to := time.Unix(hour, 0), time.Unix(hour+secondsPerHour, 0)
+
+	series, err := p.GetPriceCandles(ctx, 0, "bitcoin", from, to, "1h", "")
+	if err != nil {
+		t.Fatalf("GetPriceCandles: %v", err)
+	}
+	if series.Currency != testCurrency || len(series.Candles) != 1 {
+		t.Fatalf("got %d candles in %s, want 1 in %s", len(series.Candles), series.Currency, testCurrency)
+	}
+	candle := series.Candles[0]
+	if !candle.Open.Equal(decimal.NewFromInt(100)) || !candle.High.Equal(decimal.NewFromInt(120)) || !candle.Volume.Decimal.Equal(decimal.NewFromInt(5000)) || candle.Ticks != 3 {
+		t.Fatalf("candle = %+v", candle)
+	}
+
+	// A euro is worth 2 USD, so prices and volume halve.
+	series, err = p.GetPriceCandles(ctx, 0, "bitcoin", from, to, "1h", "EUR")
+	if err != nil {
+		t.Fatalf("GetPriceCandles in EUR: %v", err)
+	}
+	candle = series.Candles[0]
+	if series.Currency != "euro" || !candle.Open.Equal(decimal.NewFromInt(50)) || !candle.Low.Equal(decimal.NewFromInt(45)) || !candle.Close.Equal(decimal.NewFromInt(45)) || !candle.Volume.Decimal.Equal(decimal.NewFromInt(2500)) {
+		t.Fatalf("candle in %s = %+v", series.Currency, candle)
+	}
+
+	_, err = p.GetPriceCandles(ctx, 0, "bitcoin", from, to, "1h", "nosuch")
+	if err != ErrCurrencyNotFound {
+		t.Fatalf("GetPriceCandles in an unknown currency error = %v, want %v", err, ErrCurrencyNotFound)
+	}
+
+	if rest.volumeCalls != volumeCalls {
+		t.Fatalf("GetPriceCandles requested the volume %d times, want 0", rest.volumeCalls-volumeCalls)
+	}
+}
+
+func TestGetPriceCandlesLeavesOpenCandleVolumeNull(t *testing.T) {
+	ctx := context.Background()
+	dbCrypto := newTestDBCrypto(t)
+
+	currTime := time.Now()
+	err := dbCrypto.InsertPriceHistory(ctx, []model.PriceHistory{
+		{AssetId: "bitcoin", Timestamp: currTime.Unix() - 1, Price: decimal.NewFromInt(100)},
+	})
+	if err != nil {
+		t.Fatalf("InsertPriceHistory: %v", err)
+	}
+
+	rest := &fakeREST{volumes: []model.VolumePoint{{Timestamp: currTime.Unix() / secondsPerHour * secondsPerHour, Volume: decimal.NewFromInt(5000)}}}
+	p := NewPriceImpl(dbCrypto, rest, config.PriceHistoryConfig{}, testCurrency)
+
+	series, err := p.GetPriceCandles(ctx, 0, "bitcoin", currTime.Add(-time.Hour), currTime, "1h", "")
+	if err != nil {
+		t.Fatalf("GetPriceCandles: %v", err)
+	}
+	if len(series.Candles) != 1 || series.Candles[0].Volume.Valid {
+		t.Fatalf("candles = %+v, want 1 without volume", series.Candles)
+	}
+	if rest.volumeCalls != 0 {
+		t.Fatalf("GetPriceCandles requested the volume %d times, want 0", rest.volumeCalls)
+	}
+}
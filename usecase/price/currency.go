@@ -0,0 +1,82 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+)
+
+const (
+	fiatCurrencyType   = "fiat"
+	cryptoCurrencyType = "crypto"
+
+	// Price history and candles are stored in USD.
+	usdCurrency = "united-states-dollar"
+)
+
+var ErrInvalidCurrencyType = errors.New("invalid currency type")
+
+// GetCurrencies lists the currencies prices can be quoted in, only the fiat
+// or crypto ones when currencyType is set.
+func (p *priceImpl) GetCurrencies(ctx context.Context, currencyType string) (*[]model.CurrencyRate, error) {
+	if currencyType != "" && currencyType != fiatCurrencyType && currencyType != cryptoCurrencyType {
+		return nil, ErrInvalidCurrencyType
+	}
+
+	rates, err := p.restCrypto.GetCurrencyRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := []model.CurrencyRate{}
+	for _, rate := range *rates {
+		if currencyType == "" || rate.Type == currencyType {
+			data = append(data, rate)
+		}
+	}
+	return &data, nil
+}
+
+// currencyRate returns the rate of currency, given by id or symbol, or else
+// of the user's preferred currency, or else of the target currency. A
+// preferred currency without a rate falls back to the target currency.
+func (p *priceImpl) currencyRate(ctx context.Context, userId int, currency string) (*model.CurrencyRate, error) {
+	if currency != "" {
+		rate, ok, err := p.restCrypto.LookupCurrency(ctx, currency)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrCurrencyNotFound
+		}
+		return rate, nil
+	}
+
+	if userId != 0 {
+		user, err := p.dbCrypto.GetUserById(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		if user.Currency != "" {
+			rate, ok, err := p.restCrypto.LookupCurrency(ctx, user.Currency)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return rate, nil
+			}
+		}
+	}
+
+	rate, ok, err := p.restCrypto.LookupCurrency(ctx, p.currency)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no rate for %s", p.currency)
+	}
+	return rate, nil
+}
@@ -0,0 +1,288 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/money"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoDB"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoREST"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultRawRetentionDays    = 1
+	defaultHourlyRetentionDays = 30
+
+	defaultHistoryRange = 24 * time.Hour
+	maxHistoryPoints    = 2000
+
+	// Coincap limits how much history one request may cover.
+	hourlyBackfillChunk = 7 * 24 * time.Hour
+	dailyBackfillChunk  = 365 * 24 * time.Hour
+
+	secondsPerHour = int64(time.Hour / time.Second)
+	secondsPerDay  = int64(24 * time.Hour / time.Second)
+)
+
+// historyIntervals are the intervals price history can be asked for in.
+var historyIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+var (
+	ErrInvalidInterval  = errors.New("invalid interval")
+	ErrInvalidTimeRange = errors.New("invalid time range")
+	ErrInvalidDays      = errors.New("invalid number of days")
+	ErrCurrencyNotFound = errors.New("currency not found")
+)
+
+type priceImpl struct {
+	dbCrypto      cryptoDB.CryptoDBInterface
+	restCrypto    cryptoREST.CryptoRESTInterface
+	historyConfig config.PriceHistoryConfig
+	currency      string
+}
+
+func NewPriceImpl(dbCrypto cryptoDB.CryptoDBInterface, restCrypto cryptoREST.CryptoRESTInterface, historyConfig config.PriceHistoryConfig, currency string) PriceUsecase {
+	return &priceImpl{
+		dbCrypto:      dbCrypto,
+		restCrypto:    restCrypto,
+		historyConfig: withHistoryConfigDefaults(historyConfig),
+		currency:      currency,
+	}
+}
+
+func withHistoryConfigDefaults(cfg config.PriceHistoryConfig) config.PriceHistoryConfig {
+	if cfg.RawRetentionDays <= 0 {
+		cfg.RawRetentionDays = defaultRawRetentionDays
+	}
+	if cfg.HourlyRetentionDays < cfg.RawRetentionDays {
+		cfg.HourlyRetentionDays = max(defaultHourlyRetentionDays, cfg.RawRetentionDays)
+	}
+	if cfg.DailyRetentionDays < 0 {
+		cfg.DailyRetentionDays = 0
+	}
+	return cfg
+}
+
+func daysDuration(days int) time.Duration {
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// defaultInterval picks an interval that gives a readable chart for a range.
+func defaultInterval(historyRange time.Duration) string {
+	switch {
+	case historyRange <= 24*time.Hour:
+		return "5m"
+	case historyRange <= 30*24*time.Hour:
+		return "1h"
+	default:
+		return "1d"
+	}
+}
+
+// GetPriceHistory returns the average price of an asset per interval between
+// from and to. A zero to means now and a zero from means a day before to.
+//
+// History is stored in USD and converted to currency, given by id or symbol,
+// or else to the user's preferred currency, with today's rates.
+func (p *priceImpl) GetPriceHistory(ctx context.Context, userId int, assetId string, from, to time.Time, interval, currency string) (*model.PriceHistorySeries, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultHistoryRange)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	if interval == "" {
+		interval = defaultInterval(to.Sub(from))
+	}
+	resolution, ok := historyIntervals[interval]
+	if !ok {
+		return nil, ErrInvalidInterval
+	}
+
+	if to.Sub(from)/resolution > maxHistoryPoints {
+		return nil, ErrInvalidTimeRange
+	}
+
+	rate, err := p.currencyRate(ctx, userId, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	seconds := int64(resolution / time.Second)
+	fromUnix := from.Unix() / seconds * seconds
+
+	ticks, err := p.dbCrypto.GetPriceTicks(ctx, assetId, fromUnix, to.Unix(), secondsPerDay)
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := averagePrices(*ticks, seconds, *rate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PriceHistorySeries{
+		AssetId:  assetId,
+		Currency: rate.Id,
+		Interval: interval,
+		From:     fromUnix,
+		To:       to.Unix(),
+		Points:   points,
+	}, nil
+}
+
+// averagePrices returns the average of USD ticks per resolution seconds,
+// converted to currency. Ticks must be ordered by time.
+func averagePrices(ticks []model.PriceHistory, resolution int64, currency model.CurrencyRate) ([]model.PricePoint, error) {
+	points := []model.PricePoint{}
+	var prices []decimal.Decimal
+	average := func(point *model.PricePoint) error {
+		price, err := money.Convert(currency.Id, decimal.Avg(prices[0], prices[1:]...), currency.RateUSD)
+		point.Price = price
+		return err
+	}
+
+	for _, tick := range ticks {
+		timestamp := tick.Timestamp / resolution * resolution
+		n := len(points)
+		if n == 0 || points[n-1].Timestamp != timestamp {
+			if n > 0 {
+				err := average(&points[n-1])
+				if err != nil {
+					return nil, err
+				}
+			}
+			points = append(points, model.PricePoint{Timestamp: timestamp})
+			prices = prices[:0]
+		}
+		prices = append(prices, tick.Price)
+	}
+	if n := len(points); n > 0 {
+		err := average(&points[n-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+// BackfillPriceHistory stores the last days of an asset's USD price history
+// from the price API: hourly within the hourly retention and daily before
+// that.
+// It returns the number of prices fetched, ones already stored are kept. The
+// asset's candles are dropped so the next poll materializes them again with
+// the backfilled prices.
+func (p *priceImpl) BackfillPriceHistory(ctx context.Context, assetId string, days int) (int, error) {
+	if days <= 0 {
+		return 0, ErrInvalidDays
+	}
+	if p.historyConfig.DailyRetentionDays > 0 {
+		days = min(days, p.historyConfig.DailyRetentionDays)
+	}
+
+	currTime := time.Now()
+	start := currTime.Add(-daysDuration(days))
+	hourlyStart := currTime.Add(-daysDuration(p.historyConfig.HourlyRetentionDays))
+	if hourlyStart.Before(start) {
+		hourlyStart = start
+	}
+
+	count := 0
+	for _, segment := range []struct {
+		start, end time.Time
+		resolution time.Duration
+		chunk      time.Duration
+	}{
+		{start, hourlyStart, 24 * time.Hour, dailyBackfillChunk},
+		{hourlyStart, currTime, time.Hour, hourlyBackfillChunk},
+	} {
+		for chunkStart := segment.start; chunkStart.Before(segment.end); chunkStart = chunkStart.Add(segment.chunk) {
+			chunkEnd := chunkStart.Add(segment.chunk)
+			if chunkEnd.After(segment.end) {
+				chunkEnd = segment.end
+			}
+
+			points, err := p.restCrypto.GetAssetHistoryUSD(ctx, assetId, segment.resolution, chunkStart, chunkEnd)
+			if err != nil {
+				return count, err
+			}
+
+			prices := make([]model.PriceHistory, 0, len(*points))
+			for _, point := range *points {
+				prices = append(prices, model.PriceHistory{
+					AssetId:    assetId,
+					Timestamp:  point.Timestamp,
+					Price:      point.Price,
+					Resolution: int64(segment.resolution / time.Second),
+				})
+			}
+
+			err = p.dbCrypto.InsertPriceHistory(ctx, prices)
+			if err != nil {
+				return count, err
+			}
+			count += len(prices)
+		}
+	}
+
+	err := p.dbCrypto.DeletePriceCandlesByAssetId(ctx, assetId)
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// applyRetention averages prices older than the raw retention per hour and
+// prices older than the hourly retention per day, then drops prices older
+// than the daily retention when one is set. Candles shorter than an hour are
+// kept for the hourly retention and the others for the daily retention.
+func applyRetention(ctx context.Context, dbCrypto cryptoDB.CryptoDBInterface, cfg config.PriceHistoryConfig, currTime time.Time) error {
+	hourlyBefore := currTime.Add(-daysDuration(cfg.RawRetentionDays)).Unix() / secondsPerHour * secondsPerHour
+	err := dbCrypto.DownsamplePriceHistory(ctx, secondsPerHour, hourlyBefore)
+	if err != nil {
+		return err
+	}
+
+	dailyBefore := currTime.Add(-daysDuration(cfg.HourlyRetentionDays)).Unix() / secondsPerDay * secondsPerDay
+	err = dbCrypto.DownsamplePriceHistory(ctx, secondsPerDay, dailyBefore)
+	if err != nil {
+		return err
+	}
+
+	err = dbCrypto.DeletePriceCandlesBefore(ctx, secondsPerHour, candleRetentionStart(cfg, 0, currTime))
+	if err != nil {
+		return err
+	}
+
+	if cfg.DailyRetentionDays > 0 {
+		before := currTime.Add(-daysDuration(cfg.DailyRetentionDays)).Unix()
+		err = dbCrypto.DeletePriceCandlesBefore(ctx, secondsPerDay+1, before)
+		if err != nil {
+			return err
+		}
+		return dbCrypto.DeletePriceHistoryBefore(ctx, before)
+	}
+	return nil
+}
+
+// GetProviderHealth returns the state of each market data provider, in
+// priority order.
+func (p *priceImpl) GetProviderHealth(ctx context.Context) []model.ProviderHealth {
+	return p.restCrypto.GetProviderHealth()
+}
@@ -0,0 +1,74 @@
+package price
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/shopspring/decimal"
+)
+
+func TestGetPriceHistoryConvertsStoredUSD(t *testing.T) {
+	ctx := context.Background()
+	dbCrypto := newTestDBCrypto(t)
+
+	hour := time.Now().Add(-3*time.Hour).Unix() / secondsPerHour * secondsPerHour
+	err := dbCrypto.InsertPriceHistory(ctx, []model.PriceHistory{
+		{AssetId: "bitcoin", Timestamp: hour + 60, Price: decimal.NewFromInt(100)},
+		{AssetId: "bitcoin", Timestamp: hour + 120, Price: decimal.NewFromInt(120)},
+	})
+	if err != nil {
+		t.Fatalf("InsertPriceHistory: %v", err)
+	}
+
+	err = dbCrypto.InsertUser(ctx, "euro@example.com", "hash")
+	if err != nil {
+		t.Fatalf("InsertUser: %v", err)
+	}
+	user, err := dbCrypto.GetUserByEmail(ctx, "euro@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	err = dbCrypto.UpdateUserCurrency(ctx, user.ID, "euro")
+	if err != nil {
+		t.Fatalf("UpdateUserCurrency: %v", err)
+	}
+
+	rest := &fakeREST{rates: []model.CurrencyRate{
+		{Id: "euro", Symbol: "EUR", Type: "fiat", RateUSD: decimal.NewFromInt(2)},
+		{Id: "indonesian-rupiah", Symbol: "IDR", Type: "fiat", RateUSD: decimal.RequireFromString("0.0001")},
+	}}
+	p := NewPriceImpl(dbCrypto, rest, config.PriceHistoryConfig{}, "indonesian-rupiah")
+	from, to := time.Unix(hour, 0), time.Unix(hour+secondsPerHour, 0)
+
+	tests := []struct {
+		name     string
+		userId   int
+		currency string
+		want     model.CurrencyRate
+		price    string
+	}{
+		{name: "target currency", currency: "", want: rest.rates[1], price: "1100000"},
+		{name: "preferred currency", userId: user.ID, want: rest.rates[0], price: "55"},
+		{name: "requested currency", userId: user.ID, currency: "USD", want: testRate, price: "110"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series, err := p.GetPriceHistory(ctx, tt.userId, "bitcoin", from, to, "1h", tt.currency)
+			if err != nil {
+				t.Fatalf("GetPriceHistory: %v", err)
+			}
+			if series.Currency != tt.want.Id || len(series.Points) != 1 || !series.Points[0].Price.Equal(decimal.RequireFromString(tt.price)) {
+				t.Fatalf("series = %+v, want one point of %s in %s", series, tt.price, tt.want.Id)
+			}
+		})
+	}
+
+	_, err = p.GetPriceHistory(ctx, 0, "bitcoin", from, to, "1h", "nosuch")
+	if err != ErrCurrencyNotFound {
+		t.Fatalf("GetPriceHistory in an unknown currency error = %v, want %v", err, ErrCurrencyNotFound)
+	}
+}
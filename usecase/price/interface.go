@@ -0,0 +1,16 @@
+package price
+
+import (
+	"context"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+)
+
+type PriceUsecase interface {
+	GetPriceHistory(ctx context.Context, userId int, assetId string, from, to time.Time, interval, currency string) (*model.PriceHistorySeries, error)
+	GetPriceCandles(ctx context.Context, userId int, assetId string, from, to time.Time, interval, currency string) (*model.PriceCandleSeries, error)
+	BackfillPriceHistory(ctx context.Context, assetId string, days int) (int, error)
+	GetProviderHealth(ctx context.Context) []model.ProviderHealth
+	GetCurrencies(ctx context.Context, currencyType string) (*[]model.CurrencyRate, error)
+}
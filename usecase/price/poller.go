@@ -0,0 +1,315 @@
+package price
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/michaelwongycn/crypto-tracker/lib/money"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoDB"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoREST"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultPollInterval = time.Minute
+
+	// How often old price history is downsampled and dropped.
+	retentionInterval = time.Hour
+
+	failedToPollPricesErrorMsg     = "failed to poll prices"
+	failedToRecordPricesErrorMsg   = "failed to record price history"
+	failedToApplyRetentionErrorMsg = "failed to apply price history retention"
+	failedToBuildCandlesErrorMsg   = "failed to materialize price candles"
+)
+
+// Poller keeps a snapshot of the USD prices of every asset tracked by any
+// user, refreshed in the background, so that serving a user's assets doesn't
+// hit the price API. Assets the snapshot has no fresh price for are fetched on
+// demand. Prices are converted to the currency asked for when served, with
+// rates the price API caches on their own. Every polled price is also recorded
+// as USD price history, and candles that closed since the last poll are
+// materialized. An asset no provider has a price for keeps its
+// last price until that is too old, and is otherwise served without one.
+type Poller struct {
+	dbCrypto      cryptoDB.CryptoDBInterface
+	restCrypto    cryptoREST.CryptoRESTInterface
+	interval      time.Duration
+	maxAge        time.Duration
+	historyConfig config.PriceHistoryConfig
+	lastRetention time.Time
+
+	mu       sync.RWMutex
+	snapshot map[string]model.Asset
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewPoller(dbCrypto cryptoDB.CryptoDBInterface, restCrypto cryptoREST.CryptoRESTInterface, cfg config.PriceConfig) *Poller {
+	interval := time.Second * cfg.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	// A price survives a few failed polls before it is fetched on demand.
+	maxAge := time.Second * cfg.MaxAge
+	if maxAge < interval {
+		maxAge = 5 * interval
+	}
+
+	return &Poller{
+		dbCrypto:      dbCrypto,
+		restCrypto:    restCrypto,
+		interval:      interval,
+		maxAge:        maxAge,
+		historyConfig: withHistoryConfigDefaults(cfg.History),
+		snapshot:      map[string]model.Asset{},
+	}
+}
+
+// Start polls right away and then every interval until Stop is called.
+func (p *Poller) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.poll(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels the running poll and waits for the poller to exit, or for ctx
+// to be done.
+func (p *Poller) Stop(ctx context.Context) error {
+	if p.cancel == nil {
+		return nil
+	}
+
+	p.cancel()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Poller) poll(ctx context.Context) {
+	assetIds, err := p.dbCrypto.GetTrackedAssetIds(ctx)
+	if err != nil {
+		log.PrintLogErr(ctx, failedToPollPricesErrorMsg, err)
+		return
+	}
+
+	snapshot := map[string]model.Asset{}
+	if len(*assetIds) > 0 {
+		assets, err := p.restCrypto.GetAssetsPriceUSD(ctx, toUserAssets(*assetIds))
+		if err != nil {
+			if ctx.Err() == nil {
+				log.PrintLogErr(ctx, failedToPollPricesErrorMsg, err)
+			}
+			return
+		}
+
+		priced := pricedAssets(*assets)
+		p.fillFromCatalog(ctx, priced)
+		for _, asset := range priced {
+			snapshot[asset.AssetId] = asset
+		}
+
+		err = p.recordPrices(ctx, priced)
+		if err != nil && ctx.Err() == nil {
+			log.PrintLogErr(ctx, failedToRecordPricesErrorMsg, err)
+		}
+	}
+
+	// Assets nobody tracks anymore drop out of the snapshot here, the ones
+	// left unpriced keep their last price.
+	p.mu.Lock()
+	for _, assetId := range *assetIds {
+		if _, ok := snapshot[assetId]; ok {
+			continue
+		}
+		if asset, ok := p.snapshot[assetId]; ok {
+			snapshot[assetId] = asset
+		}
+	}
+	p.snapshot = snapshot
+	p.mu.Unlock()
+
+	currTime := time.Now()
+	err = materializeCandles(ctx, p.dbCrypto, p.restCrypto, p.historyConfig, currTime)
+	if err != nil {
+		log.PrintLogErr(ctx, failedToBuildCandlesErrorMsg, err)
+	}
+
+	if currTime.Sub(p.lastRetention) >= retentionInterval {
+		err = applyRetention(ctx, p.dbCrypto, p.historyConfig, currTime)
+		if err != nil {
+			log.PrintLogErr(ctx, failedToApplyRetentionErrorMsg, err)
+			return
+		}
+		p.lastRetention = currTime
+	}
+}
+
+// recordPrices stores polled USD prices as price history.
+func (p *Poller) recordPrices(ctx context.Context, assets []model.Asset) error {
+	prices := make([]model.PriceHistory, 0, len(assets))
+	for _, asset := range assets {
+		prices = append(prices, model.PriceHistory{
+			AssetId:   asset.AssetId,
+			Timestamp: asset.PriceUpdatedAt,
+			Price:     asset.Price.Decimal,
+		})
+	}
+
+	return p.dbCrypto.InsertPriceHistory(ctx, prices)
+}
+
+// GetPrices returns the price of each asset in currency, in order, taken from
+// the snapshot where it is fresh enough. The rest are fetched from the price
+// API and added to the snapshot. Where that fails, prices older than maxAge
+// are still returned rather than none, and assets with no price at all come
+// back without one. It fails only when none of the assets has a price.
+func (p *Poller) GetPrices(ctx context.Context, assetIds []string, currency model.CurrencyRate) (*[]model.Asset, error) {
+	prices := make(map[string]model.Asset, len(assetIds))
+	var missing []string
+
+	currTime := time.Now()
+
+	p.mu.RLock()
+	for _, assetId := range assetIds {
+		asset, ok := p.snapshot[assetId]
+		if ok {
+			prices[assetId] = asset
+		}
+		if !ok || currTime.Sub(time.Unix(asset.PriceUpdatedAt, 0)) > p.maxAge {
+			missing = append(missing, assetId)
+		}
+	}
+	p.mu.RUnlock()
+
+	if len(missing) > 0 {
+		assets, err := p.restCrypto.GetAssetsPriceUSD(ctx, toUserAssets(missing))
+		if err != nil {
+			if len(prices) == 0 {
+				return nil, err
+			}
+			log.PrintLogErr(ctx, failedToPollPricesErrorMsg, err)
+		} else {
+			priced := pricedAssets(*assets)
+			p.fillFromCatalog(ctx, priced)
+
+			p.mu.Lock()
+			for _, asset := range priced {
+				prices[asset.AssetId] = asset
+				p.snapshot[asset.AssetId] = asset
+			}
+			p.mu.Unlock()
+		}
+	}
+
+	data := make([]model.Asset, 0, len(assetIds))
+	for _, assetId := range assetIds {
+		asset, ok := prices[assetId]
+		if !ok {
+			data = append(data, p.unpricedAsset(ctx, assetId, currency))
+			continue
+		}
+
+		asset.Price = convertOptional(currency, asset.Price)
+		asset.MarketCap = convertOptional(currency, asset.MarketCap)
+		asset.Volume24Hr = convertOptional(currency, asset.Volume24Hr)
+		asset.Currency = currency.Id
+		asset.PriceAge = int64(currTime.Sub(time.Unix(asset.PriceUpdatedAt, 0)).Seconds())
+		if asset.PriceAge < 0 {
+			asset.PriceAge = 0
+		}
+		data = append(data, asset)
+	}
+
+	return &data, nil
+}
+
+// unpricedAsset is an asset no provider has a price for, with what the catalog
+// knows about it.
+func (p *Poller) unpricedAsset(ctx context.Context, assetId string, currency model.CurrencyRate) model.Asset {
+	assets := []model.Asset{{AssetId: assetId, Currency: currency.Id}}
+	p.fillFromCatalog(ctx, assets)
+	return assets[0]
+}
+
+// pricedAssets returns the assets that came with a price.
+func pricedAssets(assets []model.Asset) []model.Asset {
+	priced := make([]model.Asset, 0, len(assets))
+	for _, asset := range assets {
+		if asset.Price.Valid {
+			priced = append(priced, asset)
+		}
+	}
+	return priced
+}
+
+// fillFromCatalog fills in the symbol, name and rank of assets quoted by a
+// provider that doesn't return them.
+func (p *Poller) fillFromCatalog(ctx context.Context, assets []model.Asset) {
+	for i := range assets {
+		asset := &assets[i]
+		if asset.Symbol != "" && asset.Name != "" {
+			continue
+		}
+
+		info, err := p.dbCrypto.GetCatalogAsset(ctx, asset.AssetId)
+		if err != nil {
+			continue
+		}
+
+		if asset.Symbol == "" {
+			asset.Symbol = info.Symbol
+		}
+		if asset.Name == "" {
+			asset.Name = info.Name
+		}
+		if asset.Rank == 0 {
+			asset.Rank = info.Rank
+		}
+	}
+}
+
+func convertOptional(currency model.CurrencyRate, amountUSD decimal.NullDecimal) decimal.NullDecimal {
+	if !amountUSD.Valid {
+		return amountUSD
+	}
+	amount, err := money.Convert(currency.Id, amountUSD.Decimal, currency.RateUSD)
+	if err != nil {
+		return decimal.NullDecimal{}
+	}
+	return decimal.NewNullDecimal(amount)
+}
+
+func toUserAssets(assetIds []string) *[]model.UserAsset {
+	userAssets := make([]model.UserAsset, 0, len(assetIds))
+	for _, assetId := range assetIds {
+		userAssets = append(userAssets, model.UserAsset{AssetId: assetId})
+	}
+	return &userAssets
+}
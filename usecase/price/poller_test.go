@@ -0,0 +1,163 @@
+package price
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/db"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoDB"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoREST"
+	"github.com/shopspring/decimal"
+)
+
+const testCurrency = "united-states-dollar"
+
+var testRate = model.CurrencyRate{Id: testCurrency, Symbol: "USD", Type: "fiat", RateUSD: decimal.NewFromInt(1)}
+
+// fakeREST prices the assets in prices, in USD, and no others. It has the
+// rates in rates besides the target currency's, and the given volumes, and
+// counts the volume requests.
+type fakeREST struct {
+	cryptoREST.CryptoRESTInterface
+	prices      map[string]decimal.Decimal
+	rates       []model.CurrencyRate
+	volumes     []model.VolumePoint
+	volumeCalls int
+}
+
+func (r *fakeREST) GetAssetsPriceUSD(ctx context.Context, userAssets *[]model.UserAsset) (*[]model.Asset, error) {
+	data := []model.Asset{}
+	for _, userAsset := range *userAssets {
+		asset := model.Asset{AssetId: userAsset.AssetId, Currency: testCurrency}
+		if price, ok := r.prices[userAsset.AssetId]; ok {
+			asset.Price = decimal.NewNullDecimal(price)
+			asset.PriceUpdatedAt = time.Now().Unix()
+		}
+		data = append(data, asset)
+	}
+	return &data, nil
+}
+
+func (r *fakeREST) LookupCurrency(ctx context.Context, currency string) (*model.CurrencyRate, bool, error) {
+	for _, rate := range append([]model.CurrencyRate{testRate}, r.rates...) {
+		if rate.Id == currency || rate.Symbol == currency {
+			return &rate, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (r *fakeREST) GetAssetVolumeUSD(ctx context.Context, assetId string, resolution time.Duration, start, end time.Time) (*[]model.VolumePoint, error) {
+	r.volumeCalls++
+	return &r.volumes, nil
+}
+
+func newTestDBCrypto(t *testing.T) cryptoDB.CryptoDBInterface {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	name, err := filepath.Rel(wd, filepath.Join(t.TempDir(), "crypto"))
+	if err != nil {
+		t.Fatalf("Rel: %v", err)
+	}
+
+	database, err := db.Connect(5, name)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	return cryptoDB.NewCryptoDBImpl(5, database)
+}
+
+func newTestPoller(t *testing.T, rest *fakeREST, trackedAssetIds ...string) (*Poller, cryptoDB.CryptoDBInterface) {
+	t.Helper()
+
+	dbCrypto := newTestDBCrypto(t)
+	for _, assetId := range trackedAssetIds {
+		err := dbCrypto.InsertUserAsset(context.Background(), 1, assetId)
+		if err != nil {
+			t.Fatalf("InsertUserAsset: %v", err)
+		}
+	}
+
+	return NewPoller(dbCrypto, rest, config.PriceConfig{}), dbCrypto
+}
+
+func TestPollKeepsPricedAssets(t *testing.T) {
+	rest := &fakeREST{prices: map[string]decimal.Decimal{
+		"bitcoin":  decimal.NewFromInt(60000),
+		"ethereum": decimal.NewFromInt(3000),
+	}}
+	p, dbCrypto := newTestPoller(t, rest, "bitcoin", "ethereum", "delisted")
+	ctx := context.Background()
+
+	p.poll(ctx)
+
+	if len(p.snapshot) != 2 {
+		t.Fatalf("snapshot holds %d assets, want the 2 priced ones", len(p.snapshot))
+	}
+
+	historyAssetIds, err := dbCrypto.GetPriceHistoryAssetIds(ctx)
+	if err != nil {
+		t.Fatalf("GetPriceHistoryAssetIds: %v", err)
+	}
+	if len(*historyAssetIds) != 2 {
+		t.Fatalf("price history recorded for %v, want bitcoin and ethereum", *historyAssetIds)
+	}
+
+	// A price survives a poll that can't price the asset anymore.
+	delete(rest.prices, "ethereum")
+	p.poll(ctx)
+
+	if asset, ok := p.snapshot["ethereum"]; !ok || !asset.Price.Decimal.Equal(decimal.NewFromInt(3000)) {
+		t.Fatalf("ethereum's last price was dropped from the snapshot: %+v", asset)
+	}
+}
+
+func TestGetPricesReturnsUnpricedAssets(t *testing.T) {
+	rest := &fakeREST{prices: map[string]decimal.Decimal{"bitcoin": decimal.NewFromInt(60000)}}
+	p, _ := newTestPoller(t, rest)
+
+	assets, err := p.GetPrices(context.Background(), []string{"bitcoin", "delisted"}, testRate)
+	if err != nil {
+		t.Fatalf("GetPrices: %v", err)
+	}
+	if len(*assets) != 2 {
+		t.Fatalf("got %d assets, want 2", len(*assets))
+	}
+
+	bitcoin, delisted := (*assets)[0], (*assets)[1]
+	if bitcoin.AssetId != "bitcoin" || !bitcoin.Price.Valid || !bitcoin.Price.Decimal.Equal(decimal.NewFromInt(60000)) {
+		t.Fatalf("bitcoin = %+v, want it priced at 60000", bitcoin)
+	}
+	if delisted.AssetId != "delisted" || delisted.Price.Valid || delisted.Currency != testCurrency {
+		t.Fatalf("delisted = %+v, want it without a price", delisted)
+	}
+
+	if _, ok := p.snapshot["delisted"]; ok {
+		t.Fatal("an asset without a price was added to the snapshot")
+	}
+}
+
+func TestGetPricesLeavesAssetsUnpricedWithoutRate(t *testing.T) {
+	rest := &fakeREST{prices: map[string]decimal.Decimal{"bitcoin": decimal.NewFromInt(60000)}}
+	p, _ := newTestPoller(t, rest)
+
+	noRate := model.CurrencyRate{Id: "euro", Symbol: "EUR", Type: "fiat"}
+	assets, err := p.GetPrices(context.Background(), []string{"bitcoin"}, noRate)
+	if err != nil {
+		t.Fatalf("GetPrices: %v", err)
+	}
+	if len(*assets) != 1 || (*assets)[0].Price.Valid {
+		t.Fatalf("assets = %+v, want bitcoin without a price", *assets)
+	}
+}
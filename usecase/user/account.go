@@ -0,0 +1,202 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/auth"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/michaelwongycn/crypto-tracker/lib/mailer"
+	"github.com/michaelwongycn/crypto-tracker/lib/random"
+)
+
+const (
+	verifyEmailPurpose   = "verify_email"
+	resetPasswordPurpose = "reset_password"
+
+	actionTokenIdLength = 16
+
+	verifyEmailSubject = "Confirm your Crypto Tracker email address"
+	verifyEmailBody    = `Hi,
+
+Please confirm your email address by opening the link below:
+
+%s
+
+The link expires in %d minutes. If you didn't create a Crypto Tracker account you can ignore this email.
+`
+	resetPasswordSubject = "Reset your Crypto Tracker password"
+	resetPasswordBody    = `Hi,
+
+Someone asked to reset the password of your Crypto Tracker account. Open the link below to choose a new one:
+
+%s
+
+The link expires in %d minutes. If it wasn't you, you can ignore this email and your password stays the same.
+`
+)
+
+// SendVerificationEmail resends the confirmation link. It doesn't reveal
+// whether the address belongs to an account.
+func (u *userImpl) SendVerificationEmail(ctx context.Context, email string) error {
+	user, err := u.dbCrypto.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	return u.sendVerificationEmail(ctx, *user)
+}
+
+func (u *userImpl) VerifyEmail(ctx context.Context, token string) error {
+	user, err := u.useActionToken(ctx, token, verifyEmailPurpose)
+	if err != nil {
+		return err
+	}
+
+	return u.dbCrypto.UpdateUserEmailVerified(ctx, user.ID, true)
+}
+
+// RequestPasswordReset emails a reset link. Like SendVerificationEmail it
+// succeeds for unknown addresses so it can't be used to enumerate accounts.
+func (u *userImpl) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := u.dbCrypto.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	link, err := u.createActionLink(ctx, *user, resetPasswordPurpose, "", u.accountConfig.ResetPasswordTokenDuration, "/reset-password")
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: resetPasswordSubject,
+		Body:    fmt.Sprintf(resetPasswordBody, link, u.accountConfig.ResetPasswordTokenDuration),
+	})
+}
+
+// ResetPassword sets a new password and signs the user out everywhere. Every
+// other reset link sent to the user stops working too. Opening the emailed
+// link also proves the address, so the email becomes verified.
+func (u *userImpl) ResetPassword(ctx context.Context, token, password string) error {
+	user, err := u.useActionToken(ctx, token, resetPasswordPurpose)
+	if err != nil {
+		return err
+	}
+
+	err = u.dbCrypto.UseUserActionTokens(ctx, user.ID, resetPasswordPurpose, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := u.passwordHasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	err = u.dbCrypto.UpdateUserPassword(ctx, user.ID, hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	if !user.EmailVerified {
+		err = u.dbCrypto.UpdateUserEmailVerified(ctx, user.ID, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return u.RevokeAllUserSessions(ctx, user.ID)
+}
+
+func (u *userImpl) sendVerificationEmail(ctx context.Context, user model.User) error {
+	link, err := u.createActionLink(ctx, user, verifyEmailPurpose, "", u.accountConfig.VerifyEmailTokenDuration, "/verify-email")
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: verifyEmailSubject,
+		Body:    fmt.Sprintf(verifyEmailBody, link, u.accountConfig.VerifyEmailTokenDuration),
+	})
+}
+
+// createActionLink records a single-use token for purpose and returns the
+// frontend link that carries it.
+func (u *userImpl) createActionLink(ctx context.Context, user model.User, purpose, newEmail string, duration time.Duration, path string) (string, error) {
+	currTime := time.Now()
+
+	tokenId, err := random.Hex(actionTokenIdLength)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := auth.CreateActionToken(currTime, user.ID, purpose, tokenId, user.Email, newEmail, duration)
+	if err != nil {
+		return "", err
+	}
+
+	err = u.dbCrypto.InsertUserActionToken(ctx, model.UserActionToken{
+		ID:             tokenId,
+		UserId:         user.ID,
+		Purpose:        purpose,
+		ExpirationTime: currTime.Add(time.Minute * duration).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return u.accountConfig.BaseURL + path + "?token=" + url.QueryEscape(token), nil
+}
+
+// useActionToken validates and burns a token created by createActionLink. A
+// token is also rejected when the account's email changed after it was sent.
+func (u *userImpl) useActionToken(ctx context.Context, token, purpose string) (*model.User, error) {
+	claims, err := auth.ParseActionToken(token, purpose)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+
+	userId, _ := claims["sub"].(float64)
+	tokenId, _ := claims["jti"].(string)
+	email, _ := claims["email"].(string)
+
+	user, err := u.dbCrypto.GetUserById(ctx, int(userId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidActionToken
+		}
+		return nil, err
+	}
+
+	if user.Email != email {
+		return nil, ErrInvalidActionToken
+	}
+
+	used, err := u.dbCrypto.UseUserActionToken(ctx, tokenId, user.ID, purpose, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		log.PrintLogErr(ctx, invalidActionTokenErrorMsg, ErrInvalidActionToken)
+		return nil, ErrInvalidActionToken
+	}
+
+	return user, nil
+}
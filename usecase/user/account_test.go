@@ -0,0 +1,156 @@
+package user
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/lib/mailer"
+)
+
+var testLinkTokenPattern = regexp.MustCompile(`token=(\S+)`)
+
+// useTestFileMailer makes u write its emails into a temporary directory,
+// which it returns, with links that stay valid for an hour.
+func useTestFileMailer(t *testing.T, u *userImpl) string {
+	t.Helper()
+
+	directory := t.TempDir()
+	fileMailer, err := mailer.NewMailer(config.MailerConfig{Driver: mailer.File, Directory: directory})
+	if err != nil {
+		t.Fatalf("NewMailer: %v", err)
+	}
+
+	u.mailer = fileMailer
+	u.accountConfig.VerifyEmailTokenDuration = 60
+	u.accountConfig.ResetPasswordTokenDuration = 60
+	return directory
+}
+
+// readTestMailTokens returns the tokens of the links in the emails with
+// subject written into directory.
+func readTestMailTokens(t *testing.T, directory, subject string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.eml"))
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+
+	tokens := []string{}
+	for _, file := range files {
+		message, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if !strings.Contains(string(message), "Subject: "+subject+"\r\n") {
+			continue
+		}
+
+		match := testLinkTokenPattern.FindSubmatch(message)
+		if match == nil {
+			t.Fatalf("email has no link: %s", message)
+		}
+		token, err := url.QueryUnescape(string(match[1]))
+		if err != nil {
+			t.Fatalf("QueryUnescape: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func TestRegisterSendsSingleUseVerificationLink(t *testing.T) {
+	u, _ := newTestUserImpl(t, nil)
+	directory := useTestFileMailer(t, u)
+	ctx := context.Background()
+
+	userId := registerTestUser(t, u, "new@example.com", false)
+
+	tokens := readTestMailTokens(t, directory, verifyEmailSubject)
+	if len(tokens) != 1 {
+		t.Fatalf("got %d verification emails, want 1", len(tokens))
+	}
+
+	err := u.VerifyEmail(ctx, tokens[0])
+	if err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+
+	user, err := u.dbCrypto.GetUserById(ctx, userId)
+	if err != nil {
+		t.Fatalf("GetUserById: %v", err)
+	}
+	if !user.EmailVerified {
+		t.Fatalf("email isn't verified after opening the link")
+	}
+
+	err = u.VerifyEmail(ctx, tokens[0])
+	if err != ErrInvalidActionToken {
+		t.Fatalf("reused verification link error = %v, want %v", err, ErrInvalidActionToken)
+	}
+
+	// Verified accounts aren't sent another link.
+	err = u.SendVerificationEmail(ctx, "new@example.com")
+	if err != nil {
+		t.Fatalf("SendVerificationEmail: %v", err)
+	}
+	if tokens := readTestMailTokens(t, directory, verifyEmailSubject); len(tokens) != 1 {
+		t.Fatalf("got %d verification emails after verifying, want 1", len(tokens))
+	}
+}
+
+func TestResetPasswordBurnsEveryResetLink(t *testing.T) {
+	u, _ := newTestUserImpl(t, nil)
+	directory := useTestFileMailer(t, u)
+	ctx := context.Background()
+
+	registerTestUser(t, u, "reset@example.com", true)
+
+	// Unknown addresses succeed without an email.
+	err := u.RequestPasswordReset(ctx, "nobody@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordReset for an unknown address: %v", err)
+	}
+	if tokens := readTestMailTokens(t, directory, resetPasswordSubject); len(tokens) != 0 {
+		t.Fatalf("got %d reset emails for an unknown address, want 0", len(tokens))
+	}
+
+	for range 2 {
+		err = u.RequestPasswordReset(ctx, "reset@example.com")
+		if err != nil {
+			t.Fatalf("RequestPasswordReset: %v", err)
+		}
+	}
+	tokens := readTestMailTokens(t, directory, resetPasswordSubject)
+	if len(tokens) != 2 {
+		t.Fatalf("got %d reset emails, want 2", len(tokens))
+	}
+
+	const newPassword = "N3wPassw0rd!x"
+	err = u.ResetPassword(ctx, tokens[0], newPassword)
+	if err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+
+	_, _, err = u.Login(ctx, "reset@example.com", newPassword, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Login with the new password: %v", err)
+	}
+	_, _, err = u.Login(ctx, "reset@example.com", testPassword, "test", "127.0.0.1")
+	if err != ErrInvalidCredentials {
+		t.Fatalf("Login with the old password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	for _, token := range tokens {
+		err = u.ResetPassword(ctx, token, "An0therPassw0rd!")
+		if err != ErrInvalidActionToken {
+			t.Fatalf("ResetPassword with a used link error = %v, want %v", err, ErrInvalidActionToken)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/auth"
+)
+
+const (
+	defaultUserSearchLimit = 50
+	maxUserSearchLimit     = 200
+
+	userDisabledAuditEvent        = "user_disabled"
+	userEnabledAuditEvent         = "user_enabled"
+	userSessionsRevokedAuditEvent = "user_sessions_revoked"
+	userRoleChangedAuditEvent     = "user_role_changed"
+	loginLockoutClearedAuditEvent = "login_lockout_cleared"
+)
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrInvalidRole      = errors.New("invalid role")
+	ErrCannotModifySelf = errors.New("cannot modify own account")
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers lists users whose email contains query, all of them when query
+// is empty.
+func (u *userImpl) SearchUsers(ctx context.Context, query string, limit, offset int) (*[]model.User, error) {
+	if limit <= 0 {
+		limit = defaultUserSearchLimit
+	}
+	if limit > maxUserSearchLimit {
+		limit = maxUserSearchLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return u.dbCrypto.SearchUsers(ctx, "%"+likeEscaper.Replace(query)+"%", limit, offset)
+}
+
+func (u *userImpl) GetUser(ctx context.Context, userId int) (*model.User, error) {
+	user, err := u.dbCrypto.GetUserById(ctx, userId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetUserDisabled disables or re-enables an account on behalf of an admin.
+// Disabling also signs the user out everywhere.
+func (u *userImpl) SetUserDisabled(ctx context.Context, adminId, userId int, disabled bool, userAgent, ipAddress string) error {
+	if adminId == userId {
+		return ErrCannotModifySelf
+	}
+
+	user, err := u.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	err = u.dbCrypto.UpdateUserDisabled(ctx, user.ID, disabled)
+	if err != nil {
+		return err
+	}
+
+	event := userEnabledAuditEvent
+	if disabled {
+		event = userDisabledAuditEvent
+
+		err = u.RevokeAllUserSessions(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return u.dbCrypto.InsertAuditEvent(ctx, model.AuditEvent{
+		UserId:    user.ID,
+		Event:     event,
+		Detail:    fmt.Sprintf("by user %d", adminId),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// ForceLogoutUser revokes every session of a user on behalf of an admin.
+func (u *userImpl) ForceLogoutUser(ctx context.Context, adminId, userId int, userAgent, ipAddress string) error {
+	user, err := u.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	err = u.RevokeAllUserSessions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return u.dbCrypto.InsertAuditEvent(ctx, model.AuditEvent{
+		UserId:    user.ID,
+		Event:     userSessionsRevokedAuditEvent,
+		Detail:    fmt.Sprintf("by user %d", adminId),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// AdminClearLoginLockout clears the login lockout of an email or IP address
+// on behalf of an admin. The event is recorded against the account the email
+// belongs to, or against the admin for IP addresses and unknown emails.
+func (u *userImpl) AdminClearLoginLockout(ctx context.Context, adminId int, target, userAgent, ipAddress string) error {
+	err := u.ClearLoginLockout(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	userId := adminId
+	user, err := u.dbCrypto.GetUserByEmail(ctx, target)
+	if err == nil {
+		userId = user.ID
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	return u.dbCrypto.InsertAuditEvent(ctx, model.AuditEvent{
+		UserId:    userId,
+		Event:     loginLockoutClearedAuditEvent,
+		Detail:    fmt.Sprintf("%s by user %d", target, adminId),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// SetUserRole changes the role of the account registered with email. The role
+// is carried in access tokens, so a change signs the user out everywhere
+// rather than letting tokens with the old role live on.
+func (u *userImpl) SetUserRole(ctx context.Context, email, role string) error {
+	if !auth.IsValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	user, err := u.dbCrypto.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	err = u.dbCrypto.UpdateUserRole(ctx, user.ID, role)
+	if err != nil {
+		return err
+	}
+
+	if role != user.Role {
+		err = u.RevokeAllUserSessions(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return u.dbCrypto.InsertAuditEvent(ctx, model.AuditEvent{
+		UserId:    user.ID,
+		Event:     userRoleChangedAuditEvent,
+		Detail:    fmt.Sprintf("from %s to %s", user.Role, role),
+		CreatedAt: time.Now().Unix(),
+	})
+}
@@ -0,0 +1,44 @@
+package user
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetUserRoleRevokesSessions(t *testing.T) {
+	u, _ := newTestUserImpl(t, nil)
+	ctx := context.Background()
+	email := "role@example.com"
+	registerTestUser(t, u, email, true)
+
+	accessToken, _, err := u.Login(ctx, email, testPassword, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	err = u.SetUserRole(ctx, email, "admin")
+	if err != nil {
+		t.Fatalf("SetUserRole: %v", err)
+	}
+
+	ok, err := u.tokenStore.Contains(ctx, *accessToken)
+	if err != nil || ok {
+		t.Fatalf("access token still valid after a role change (%v)", err)
+	}
+
+	// Setting the same role again must not sign the user out.
+	accessToken, _, err = u.Login(ctx, email, testPassword, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("second Login: %v", err)
+	}
+
+	err = u.SetUserRole(ctx, email, "admin")
+	if err != nil {
+		t.Fatalf("SetUserRole: %v", err)
+	}
+
+	ok, err = u.tokenStore.Contains(ctx, *accessToken)
+	if err != nil || !ok {
+		t.Fatalf("access token revoked although the role did not change (%v)", err)
+	}
+}
@@ -0,0 +1,153 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/auth"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/michaelwongycn/crypto-tracker/lib/random"
+)
+
+const (
+	// API keys look like ctk_<prefix>_<secret>. The prefix is stored in clear
+	// so users can tell their keys apart; only a hash of the full key is kept.
+	apiKeyMarker       = "ctk_"
+	apiKeyPrefixLength = 4
+	apiKeySecretLength = 24
+
+	// Recording every request would turn read-only scripts into a stream of
+	// writes, so last-used time is only refreshed once per interval.
+	apiKeyLastUsedInterval = 60
+
+	failedToUpdateAPIKeyLastUsedErrorMsg = "failed to update api key last used time"
+)
+
+var (
+	ErrInvalidAPIKey     = errors.New("invalid api key")
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrInvalidAPIKeyName = errors.New("invalid api key name")
+	ErrInvalidScope      = errors.New("invalid scope")
+)
+
+func IsAPIKey(key string) bool {
+	return strings.HasPrefix(key, apiKeyMarker)
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey returns the stored key together with its plaintext value, which
+// is not kept anywhere and cannot be shown again.
+func (u *userImpl) CreateAPIKey(ctx context.Context, userId int, name string, scopes []string, expiresInDays int) (*model.UserAPIKey, *string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil, ErrInvalidAPIKeyName
+	}
+
+	if len(scopes) == 0 {
+		scopes = []string{auth.ScopeAssetsRead}
+	}
+	for _, scope := range scopes {
+		if !auth.IsValidScope(scope) {
+			return nil, nil, ErrInvalidScope
+		}
+	}
+
+	prefix, err := random.Hex(apiKeyPrefixLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secret, err := random.Hex(apiKeySecretLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := apiKeyMarker + prefix + "_" + secret
+	currTime := time.Now()
+
+	apiKey := model.UserAPIKey{
+		UserId:    userId,
+		Name:      name,
+		Prefix:    apiKeyMarker + prefix,
+		KeyHash:   hashAPIKey(key),
+		Scopes:    scopes,
+		CreatedAt: currTime.Unix(),
+	}
+	if expiresInDays > 0 {
+		apiKey.ExpirationTime = currTime.AddDate(0, 0, expiresInDays).Unix()
+	}
+
+	apiKey.ID, err = u.dbCrypto.InsertUserAPIKey(ctx, apiKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &apiKey, &key, nil
+}
+
+func (u *userImpl) GetAPIKeys(ctx context.Context, userId int) (*[]model.UserAPIKey, error) {
+	return u.dbCrypto.GetUserAPIKeysByUserId(ctx, userId)
+}
+
+func (u *userImpl) RevokeAPIKey(ctx context.Context, userId, apiKeyId int) error {
+	revoked, err := u.dbCrypto.RevokeUserAPIKey(ctx, userId, apiKeyId, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey resolves key to its owner. Revoked, expired and unknown
+// keys, and keys of disabled accounts, all fail with ErrInvalidAPIKey.
+func (u *userImpl) AuthenticateAPIKey(ctx context.Context, key string) (*model.UserAPIKey, *model.User, error) {
+	if !IsAPIKey(key) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := u.dbCrypto.GetUserAPIKeyByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	currTime := time.Now().Unix()
+	if apiKey.RevokedAt != 0 || (apiKey.ExpirationTime != 0 && apiKey.ExpirationTime <= currTime) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := u.dbCrypto.GetUserById(ctx, apiKey.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.Disabled {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	if currTime-apiKey.LastUsedAt >= apiKeyLastUsedInterval {
+		if err := u.dbCrypto.UpdateUserAPIKeyLastUsed(ctx, apiKey.ID, currTime); err != nil {
+			log.PrintLogErr(ctx, failedToUpdateAPIKeyLastUsedErrorMsg, err)
+		} else {
+			apiKey.LastUsedAt = currTime
+		}
+	}
+
+	return apiKey, user, nil
+}
@@ -0,0 +1,89 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+)
+
+const (
+	exportManifestName = "export.json"
+
+	dataExportedAuditEvent = "data_exported"
+)
+
+type exportManifest struct {
+	UserId      int      `json:"user_id"`
+	GeneratedAt string   `json:"generated_at"`
+	Files       []string `json:"files"`
+}
+
+// ExportUserData returns a ZIP archive with one JSON file per exporter
+// registered in the repository, plus a manifest listing them.
+func (u *userImpl) ExportUserData(ctx context.Context, userId int, userAgent, ipAddress string) (*[]byte, error) {
+	files, err := u.dbCrypto.ExportUserData(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	currTime := time.Now()
+	manifest := exportManifest{
+		UserId:      userId,
+		GeneratedAt: currTime.UTC().Format(time.RFC3339),
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	for _, file := range *files {
+		name := file.Name + ".json"
+		err = writeJSONFile(archive, name, file.Data, currTime)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, name)
+	}
+
+	err = writeJSONFile(archive, exportManifestName, manifest, currTime)
+	if err != nil {
+		return nil, err
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.dbCrypto.InsertAuditEvent(ctx, model.AuditEvent{
+		UserId:    userId,
+		Event:     dataExportedAuditEvent,
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		CreatedAt: currTime.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := buf.Bytes()
+	return &data, nil
+}
+
+func writeJSONFile(archive *zip.Writer, name string, v interface{}, modified time.Time) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
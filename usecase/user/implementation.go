@@ -4,121 +4,625 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
 	"time"
 
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
 	"github.com/michaelwongycn/crypto-tracker/domain/model"
 	"github.com/michaelwongycn/crypto-tracker/lib/auth"
-	"github.com/michaelwongycn/crypto-tracker/lib/cache"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/michaelwongycn/crypto-tracker/lib/mailer"
+	"github.com/michaelwongycn/crypto-tracker/lib/oidc"
+	"github.com/michaelwongycn/crypto-tracker/lib/password"
+	"github.com/michaelwongycn/crypto-tracker/lib/random"
+	"github.com/michaelwongycn/crypto-tracker/lib/tokenstore"
 	"github.com/michaelwongycn/crypto-tracker/repository/cryptoDB"
 	"github.com/michaelwongycn/crypto-tracker/repository/cryptoREST"
+	"github.com/michaelwongycn/crypto-tracker/usecase/price"
 )
 
+const (
+	sessionIdLength      = 16
+	refreshTokenIdLength = 16
+
+	refreshTokenReuseAuditEvent = "refresh_token_reuse"
+
+	failedToRehashPasswordErrorMsg    = "failed to rehash password"
+	failedToSendEmailErrorMsg         = "failed to send email"
+	invalidActionTokenErrorMsg        = "action token already used or expired"
+	refreshTokenReuseDetectedErrorMsg = "refresh token reuse detected"
+)
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrInvalidEmail        = errors.New("invalid email")
+	ErrInvalidActionToken  = errors.New("invalid or expired token")
+	ErrInvalidMFACode      = errors.New("invalid two-factor authentication code")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnrolled      = errors.New("two-factor authentication not enrolled")
+	ErrAccountDisabled     = errors.New("account disabled")
+	ErrAssetNotFound       = errors.New("asset not found")
+	ErrCurrencyNotFound    = errors.New("currency not found")
+)
+
+// MFARequiredError is returned by Login when the password was correct but the
+// account has two-factor authentication enabled. MFAToken must be exchanged,
+// together with a code, through LoginMFA.
+type MFARequiredError struct {
+	MFAToken string
+}
+
+func (e *MFARequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
 type userImpl struct {
 	dbCrypto             cryptoDB.CryptoDBInterface
+	tokenStore           tokenstore.Store
 	restCrypto           cryptoREST.CryptoRESTInterface
+	pricePoller          *price.Poller
+	passwordHasher       password.Hasher
+	mailer               mailer.Mailer
+	accountConfig        config.AccountConfig
+	loginConfig          config.LoginConfig
+	oidcProvider         *oidc.Provider
+	oidcStateDuration    time.Duration
 	refreshTokenDuration time.Duration
 }
 
-func NewUserImpl(dbCrypto cryptoDB.CryptoDBInterface, restCrypto cryptoREST.CryptoRESTInterface, refreshTokenDuration time.Duration) UserUsecase {
+func NewUserImpl(dbCrypto cryptoDB.CryptoDBInterface, tokenStore tokenstore.Store, restCrypto cryptoREST.CryptoRESTInterface, pricePoller *price.Poller, passwordHasher password.Hasher, mailer mailer.Mailer, accountConfig config.AccountConfig, loginConfig config.LoginConfig, oidcProvider *oidc.Provider, oidcStateDuration time.Duration, refreshTokenDuration time.Duration) UserUsecase {
+	if oidcStateDuration == 0 {
+		oidcStateDuration = defaultOIDCStateDuration
+	}
+
 	return &userImpl{
 		dbCrypto:             dbCrypto,
+		tokenStore:           tokenStore,
 		restCrypto:           restCrypto,
+		pricePoller:          pricePoller,
+		passwordHasher:       passwordHasher,
+		mailer:               mailer,
+		accountConfig:        accountConfig,
+		loginConfig:          withLoginConfigDefaults(loginConfig),
+		oidcProvider:         oidcProvider,
+		oidcStateDuration:    oidcStateDuration,
 		refreshTokenDuration: refreshTokenDuration,
 	}
 }
 
-func (u *userImpl) Login(ctx context.Context, email, password string) (*string, *string, error) {
+func (u *userImpl) Login(ctx context.Context, email, password, userAgent, ipAddress string) (*string, *string, error) {
 	currTime := time.Now()
-	// TODO: encrypt Password
-	user, err := u.dbCrypto.GetUserByEmailAndPassword(ctx, email, password)
+	accountKey := accountLoginAttemptKey(email)
+	ipKey := ipLoginAttemptKey(ipAddress)
+
+	err := u.checkLoginLockout(ctx, currTime, accountKey, ipKey)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	oldUserToken, err := u.dbCrypto.GetUserToken(ctx, user.ID)
+	user, err := u.dbCrypto.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = u.recordLoginFailure(ctx, currTime, 0, accountKey, ipKey, userAgent, ipAddress)
+			if err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, ErrInvalidCredentials
+		}
+		return nil, nil, err
+	}
+
+	match, needsRehash, err := u.passwordHasher.Verify(password, user.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !match {
+		err = u.recordLoginFailure(ctx, currTime, user.ID, accountKey, ipKey, userAgent, ipAddress)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	if needsRehash {
+		u.rehashPassword(ctx, user.ID, password)
+	}
+
+	return u.completeLogin(ctx, currTime, *user, userAgent, ipAddress)
+}
+
+// completeLogin runs after the user proved who they are, either with their
+// password or through an OpenID Connect provider. Accounts with two-factor
+// authentication get an MFA token instead of a session.
+func (u *userImpl) completeLogin(ctx context.Context, currTime time.Time, user model.User, userAgent, ipAddress string) (*string, *string, error) {
+	if user.Disabled {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	mfa, err := u.dbCrypto.GetUserMFA(ctx, user.ID)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, nil, err
 	}
 
-	accessToken, refreshToken, err := auth.CreateToken(currTime, user.ID)
+	if mfa != nil && mfa.Enabled {
+		mfaToken, err := u.createMFAToken(ctx, currTime, user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, &MFARequiredError{MFAToken: mfaToken}
+	}
+
+	return u.createSession(ctx, currTime, user, userAgent, ipAddress)
+}
+
+// createSession starts a new session for a user whose credentials have been
+// fully verified and returns its first access and refresh token pair. Only
+// then are the account's failed logins forgotten, a correct password alone
+// must not reset the count while a second factor is still missing.
+func (u *userImpl) createSession(ctx context.Context, currTime time.Time, user model.User, userAgent, ipAddress string) (*string, *string, error) {
+	if user.Disabled {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	err := u.dbCrypto.DeleteLoginAttempt(ctx, accountLoginAttemptKey(user.Email))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = u.dbCrypto.DeleteExpiredUserSessions(ctx, user.ID, currTime.Unix())
 	if err != nil {
 		return nil, nil, err
 	}
 
-	err = u.dbCrypto.InsertUserToken(ctx, user.ID, accessToken, refreshToken, currTime.Add(time.Minute*u.refreshTokenDuration).Unix())
+	err = u.dbCrypto.DeleteExpiredRefreshTokens(ctx, user.ID, currTime.Unix())
 	if err != nil {
 		return nil, nil, err
 	}
 
-	if oldUserToken != nil {
-		cache.DeleteCache(oldUserToken.AccessToken)
+	sessionId, err := random.Hex(sessionIdLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshTokenId, err := random.Hex(refreshTokenIdLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessToken, refreshToken, err := auth.CreateToken(currTime, user, sessionId, refreshTokenId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expirationTime := currTime.Add(time.Minute * u.refreshTokenDuration).Unix()
+
+	err = u.dbCrypto.InsertUserSession(ctx, model.UserSession{
+		ID:               sessionId,
+		UserId:           user.ID,
+		AccessTokenHash:  tokenstore.Hash(accessToken),
+		RefreshTokenHash: tokenstore.Hash(refreshToken),
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+		CreatedAt:        currTime.Unix(),
+		LastUsedAt:       currTime.Unix(),
+		ExpirationTime:   expirationTime,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = u.dbCrypto.InsertRefreshToken(ctx, model.RefreshToken{
+		ID:             refreshTokenId,
+		FamilyId:       sessionId,
+		UserId:         user.ID,
+		IssuedAt:       currTime.Unix(),
+		ExpirationTime: expirationTime,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = u.tokenStore.Add(ctx, accessToken, user.ID, auth.AccessTokenExpiration(currTime))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	cache.SetCache(accessToken, refreshToken)
 	return &accessToken, &refreshToken, nil
 }
 
 func (u *userImpl) Register(ctx context.Context, email, password string) error {
-	// TODO: encrypt Password
-	return u.dbCrypto.InsertUser(ctx, email, password)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return ErrInvalidEmail
+	}
+
+	hashedPassword, err := u.passwordHasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	err = u.dbCrypto.InsertUser(ctx, email, hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.dbCrypto.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	// The account exists at this point, a lost email can be resent.
+	err = u.sendVerificationEmail(ctx, *user)
+	if err != nil {
+		log.PrintLogErr(ctx, failedToSendEmailErrorMsg, err)
+	}
+	return nil
 }
 
-func (u *userImpl) Logout(ctx context.Context, accessToken string, userId int) error {
-	cache.DeleteCache(accessToken)
-	return u.dbCrypto.DeleteUserToken(ctx, userId)
+// rehashPassword upgrades a stored password hash after a successful login. A
+// failure here must not block the login, the upgrade is retried next time.
+func (u *userImpl) rehashPassword(ctx context.Context, userId int, password string) {
+	hashedPassword, err := u.passwordHasher.Hash(password)
+	if err != nil {
+		log.PrintLogErr(ctx, failedToRehashPasswordErrorMsg, err)
+		return
+	}
+
+	err = u.dbCrypto.UpdateUserPassword(ctx, userId, hashedPassword)
+	if err != nil {
+		log.PrintLogErr(ctx, failedToRehashPasswordErrorMsg, err)
+	}
 }
 
-func (u *userImpl) RefreshToken(ctx context.Context, refreshToken string, userId int) (*string, *string, error) {
-	userToken, err := u.dbCrypto.GetUserToken(ctx, userId)
+func (u *userImpl) Logout(ctx context.Context, accessToken string, userId int, sessionId string) error {
+	err := u.tokenStore.Remove(ctx, tokenstore.Hash(accessToken))
+	if err != nil {
+		return err
+	}
+
+	err = u.RevokeUserSession(ctx, userId, sessionId)
+	if err != nil && err != ErrSessionNotFound {
+		return err
+	}
+	return nil
+}
+
+func (u *userImpl) RefreshToken(ctx context.Context, refreshToken string, userId int, userAgent, ipAddress string) (*string, *string, error) {
+	claims, err := auth.ParseToken(refreshToken)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	refreshTokenUserId, _ := claims["sub"].(float64)
+	sessionId, _ := claims["sid"].(string)
+	refreshTokenId, _ := claims["jti"].(string)
+	if int(refreshTokenUserId) != userId || sessionId == "" || refreshTokenId == "" {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	storedToken, err := u.dbCrypto.GetRefreshToken(ctx, refreshTokenId)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrInvalidRefreshToken
+		}
 		return nil, nil, err
 	}
 
+	if storedToken.UserId != userId || storedToken.FamilyId != sessionId || storedToken.RevokedAt != 0 {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
 	currTime := time.Now()
 
-	if refreshToken != userToken.RefreshToken || userToken.ExpirationTime < currTime.Unix() {
-		return nil, nil, errors.New("invalid refresh token")
+	if storedToken.RotatedAt != 0 {
+		return nil, nil, u.revokeReusedRefreshTokenFamily(ctx, storedToken, currTime, userAgent, ipAddress)
+	}
+
+	if storedToken.ExpirationTime < currTime.Unix() {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	session, err := u.dbCrypto.GetUserSession(ctx, sessionId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	user, err := u.dbCrypto.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.Disabled {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	newRefreshTokenId, err := random.Hex(refreshTokenIdLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newAccessToken, newRefreshToken, err := auth.CreateToken(currTime, *user, sessionId, newRefreshTokenId)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	newAccessToken, newRefreshToken, err := auth.CreateToken(currTime, userId)
+	rotated, err := u.dbCrypto.RotateRefreshToken(ctx, refreshTokenId, model.RefreshToken{
+		ID:             newRefreshTokenId,
+		FamilyId:       sessionId,
+		UserId:         userId,
+		IssuedAt:       currTime.Unix(),
+		ExpirationTime: currTime.Add(time.Minute * u.refreshTokenDuration).Unix(),
+	}, tokenstore.Hash(newAccessToken), tokenstore.Hash(newRefreshToken))
 	if err != nil {
 		return nil, nil, err
 	}
+	if !rotated {
+		// Another request rotated this token between the lookup and now.
+		return nil, nil, u.revokeReusedRefreshTokenFamily(ctx, storedToken, currTime, userAgent, ipAddress)
+	}
 
-	err = u.dbCrypto.InsertUserToken(ctx, userId, newAccessToken, newRefreshToken, currTime.Add(time.Minute*u.refreshTokenDuration).Unix())
+	err = u.tokenStore.Remove(ctx, session.AccessTokenHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = u.tokenStore.Add(ctx, newAccessToken, userId, auth.AccessTokenExpiration(currTime))
 	if err != nil {
 		return nil, nil, err
 	}
 
-	cache.DeleteCache(userToken.AccessToken)
-	cache.SetCache(newAccessToken, newRefreshToken)
 	return &newAccessToken, &newRefreshToken, nil
 }
 
-func (u *userImpl) GetUserAssetsByUserId(ctx context.Context, userId int) (*[]model.Asset, error) {
+// revokeReusedRefreshTokenFamily handles a refresh token that is presented
+// again after it was rotated. Either the legitimate client or an attacker holds
+// a stolen copy, so the whole family (the session) is revoked and the event is
+// recorded for investigation.
+func (u *userImpl) revokeReusedRefreshTokenFamily(ctx context.Context, token *model.RefreshToken, currTime time.Time, userAgent, ipAddress string) error {
+	err := u.dbCrypto.RevokeRefreshTokenFamily(ctx, token.FamilyId, currTime.Unix())
+	if err != nil {
+		return err
+	}
+
+	err = u.RevokeUserSession(ctx, token.UserId, token.FamilyId)
+	if err != nil && err != ErrSessionNotFound {
+		return err
+	}
+
+	err = u.dbCrypto.InsertAuditEvent(ctx, model.AuditEvent{
+		UserId:    token.UserId,
+		Event:     refreshTokenReuseAuditEvent,
+		Detail:    fmt.Sprintf("refresh token %s of family %s presented after rotation, family revoked", token.ID, token.FamilyId),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		CreatedAt: currTime.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	log.PrintLogErr(ctx, refreshTokenReuseDetectedErrorMsg, ErrRefreshTokenReused)
+	return ErrRefreshTokenReused
+}
+
+func (u *userImpl) GetUserSessions(ctx context.Context, userId int) (*[]model.UserSession, error) {
+	return u.dbCrypto.GetUserSessionsByUserId(ctx, userId, time.Now().Unix())
+}
+
+func (u *userImpl) RevokeUserSession(ctx context.Context, userId int, sessionId string) error {
+	session, err := u.dbCrypto.GetUserSession(ctx, sessionId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	if session.UserId != userId {
+		return ErrSessionNotFound
+	}
+
+	err = u.dbCrypto.DeleteUserSession(ctx, sessionId)
+	if err != nil {
+		return err
+	}
+
+	err = u.dbCrypto.RevokeRefreshTokenFamily(ctx, sessionId, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	return u.tokenStore.Remove(ctx, session.AccessTokenHash)
+}
+
+func (u *userImpl) RevokeAllUserSessions(ctx context.Context, userId int) error {
+	sessions, err := u.dbCrypto.GetUserSessionsByUserId(ctx, userId, 0)
+	if err != nil {
+		return err
+	}
+
+	err = u.dbCrypto.DeleteUserSessionsByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	currTime := time.Now()
+	for _, session := range *sessions {
+		err = u.dbCrypto.RevokeRefreshTokenFamily(ctx, session.ID, currTime.Unix())
+		if err != nil {
+			return err
+		}
+
+		err = u.tokenStore.Remove(ctx, session.AccessTokenHash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUserAssetsByUserId prices the user's assets in currency, or else in the
+// user's preferred currency. A preferred currency the price API no longer
+// has a rate for falls back to the target currency.
+func (u *userImpl) GetUserAssetsByUserId(ctx context.Context, userId int, currency string) (*[]model.Asset, error) {
+	rate, err := u.getUserCurrencyRate(ctx, userId, currency)
+	if err != nil {
+		return nil, err
+	}
+
 	userAssets, err := u.dbCrypto.GetUserAssetsByUserId(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
 
-	assetsPrice, err := u.restCrypto.GetAssetsPrice(ctx, userAssets)
+	assetIds := make([]string, 0, len(*userAssets))
+	for _, userAsset := range *userAssets {
+		assetIds = append(assetIds, userAsset.AssetId)
+	}
+
+	return u.pricePoller.GetPrices(ctx, assetIds, *rate)
+}
+
+func (u *userImpl) getUserCurrencyRate(ctx context.Context, userId int, currency string) (*model.CurrencyRate, error) {
+	if currency != "" {
+		rate, ok, err := u.restCrypto.LookupCurrency(ctx, currency)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrCurrencyNotFound
+		}
+		return rate, nil
+	}
+
+	user, err := u.dbCrypto.GetUserById(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
 
-	return assetsPrice, nil
+	if user.Currency != "" {
+		rate, ok, err := u.restCrypto.LookupCurrency(ctx, user.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return rate, nil
+		}
+	}
+
+	rate, ok, err := u.restCrypto.LookupCurrency(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrCurrencyNotFound
+	}
+	return rate, nil
 }
 
-func (u *userImpl) InsertUserAsset(ctx context.Context, userId int, assetId string) error {
-	_, err := u.restCrypto.IsValidAsset(ctx, assetId)
+// AmbiguousAssetError is returned when a symbol or name matches more than
+// one asset in the catalog. Candidates holds the matches by rank.
+type AmbiguousAssetError struct {
+	Candidates []model.AssetInfo
+}
+
+func (e *AmbiguousAssetError) Error() string {
+	return fmt.Sprintf("asset is ambiguous, %d assets match", len(e.Candidates))
+}
+
+// resolveAsset returns the id of the asset given by id, symbol or name. The
+// asset catalog is tried first, symbols ahead of names, and only ids the
+// catalog doesn't know are checked with the price API.
+func (u *userImpl) resolveAsset(ctx context.Context, asset string) (string, error) {
+	asset = strings.TrimSpace(asset)
+	if asset == "" {
+		return "", ErrAssetNotFound
+	}
+
+	_, err := u.dbCrypto.GetCatalogAsset(ctx, asset)
+	if err == nil {
+		return asset, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	assets, err := u.dbCrypto.FindCatalogAssets(ctx, asset)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	var bySymbol, byName []model.AssetInfo
+	for _, a := range *assets {
+		if strings.EqualFold(a.Symbol, asset) {
+			bySymbol = append(bySymbol, a)
+		} else {
+			byName = append(byName, a)
+		}
+	}
+
+	matches := bySymbol
+	if len(matches) == 0 {
+		matches = byName
+	}
+
+	switch len(matches) {
+	case 0:
+		valid, err := u.restCrypto.IsValidAsset(ctx, asset)
+		if err != nil {
+			return "", err
+		}
+		if !valid {
+			return "", ErrAssetNotFound
+		}
+		return asset, nil
+	case 1:
+		return matches[0].AssetId, nil
+	default:
+		return "", &AmbiguousAssetError{Candidates: matches}
+	}
+}
+
+// InsertUserAsset adds the asset given by id, symbol or name to the user's
+// portfolio and returns the id it was stored under.
+func (u *userImpl) InsertUserAsset(ctx context.Context, userId int, asset string) (*string, error) {
+	assetId, err := u.resolveAsset(ctx, asset)
+	if err != nil {
+		return nil, err
 	}
-	return u.dbCrypto.InsertUserAsset(ctx, userId, assetId)
+
+	err = u.dbCrypto.InsertUserAsset(ctx, userId, assetId)
+	if err != nil {
+		return nil, err
+	}
+	return &assetId, nil
 }
 
-func (u *userImpl) DeleteUserAsset(ctx context.Context, userId int, assetId string) error {
-	_, err := u.restCrypto.IsValidAsset(ctx, assetId)
+// DeleteUserAsset removes the asset given by id, symbol or name from the
+// user's portfolio. The ids the user holds are matched first, so an asset the
+// catalog no longer knows, or whose id is another asset's symbol, can still be
+// removed by id.
+func (u *userImpl) DeleteUserAsset(ctx context.Context, userId int, asset string) error {
+	userAssets, err := u.dbCrypto.GetUserAssetsByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	for _, userAsset := range *userAssets {
+		if userAsset.AssetId == strings.TrimSpace(asset) {
+			return u.dbCrypto.DeleteUserAsset(ctx, userId, userAsset.AssetId)
+		}
+	}
+
+	assetId, err := u.resolveAsset(ctx, asset)
 	if err != nil {
 		return err
 	}
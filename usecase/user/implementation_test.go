@@ -0,0 +1,156 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/auth"
+	"github.com/michaelwongycn/crypto-tracker/lib/tokenstore"
+)
+
+func TestSessionKeepsOnlyTokenHashes(t *testing.T) {
+	u, database := newTestUserImpl(t, nil)
+	ctx := context.Background()
+	email := "session@example.com"
+	userId := registerTestUser(t, u, email, true)
+
+	accessToken, refreshToken, err := u.Login(ctx, email, testPassword, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	var accessTokenHash, refreshTokenHash string
+	err = database.QueryRow("SELECT accessTokenHash, refreshTokenHash FROM user_sessions").Scan(&accessTokenHash, &refreshTokenHash)
+	if err != nil {
+		t.Fatalf("reading the session: %v", err)
+	}
+	if accessTokenHash != tokenstore.Hash(*accessToken) || refreshTokenHash != tokenstore.Hash(*refreshToken) {
+		t.Fatal("session does not hold the hashes of its tokens")
+	}
+
+	claims, err := auth.ParseToken(*accessToken)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	sessionId, _ := claims["sid"].(string)
+
+	err = u.RevokeUserSession(ctx, userId, sessionId)
+	if err != nil {
+		t.Fatalf("RevokeUserSession: %v", err)
+	}
+
+	ok, err := u.tokenStore.Contains(ctx, *accessToken)
+	if err != nil || ok {
+		t.Fatalf("access token still valid after its session was revoked (%v)", err)
+	}
+}
+
+func TestDeleteUserAssetMatchesHeldIdsFirst(t *testing.T) {
+	u, _ := newTestUserImpl(t, nil)
+	ctx := context.Background()
+	userId := registerTestUser(t, u, "holder@example.com", true)
+
+	// "ont" is an id the user holds that the catalog no longer knows, and
+	// also the symbol of another asset they hold.
+	err := u.dbCrypto.SyncAssetCatalog(ctx, []model.AssetInfo{{AssetId: "ontology", Symbol: "ONT", Name: "Ontology", Rank: 1}}, 1717200000)
+	if err != nil {
+		t.Fatalf("SyncAssetCatalog: %v", err)
+	}
+	for _, assetId := range []string{"ont", "ontology"} {
+		err = u.dbCrypto.InsertUserAsset(ctx, userId, assetId)
+		if err != nil {
+			t.Fatalf("InsertUserAsset(%s): %v", assetId, err)
+		}
+	}
+
+	held := func() []string {
+		t.Helper()
+		userAssets, err := u.dbCrypto.GetUserAssetsByUserId(ctx, userId)
+		if err != nil {
+			t.Fatalf("GetUserAssetsByUserId: %v", err)
+		}
+		assetIds := []string{}
+		for _, userAsset := range *userAssets {
+			assetIds = append(assetIds, userAsset.AssetId)
+		}
+		return assetIds
+	}
+
+	err = u.DeleteUserAsset(ctx, userId, "ont")
+	if err != nil {
+		t.Fatalf("DeleteUserAsset(ont): %v", err)
+	}
+	if got := held(); len(got) != 1 || got[0] != "ontology" {
+		t.Fatalf("user holds %v after deleting ont, want [ontology]", got)
+	}
+
+	// Symbols still resolve once no held id matches.
+	err = u.DeleteUserAsset(ctx, userId, "ONT")
+	if err != nil {
+		t.Fatalf("DeleteUserAsset(ONT): %v", err)
+	}
+	if got := held(); len(got) != 0 {
+		t.Fatalf("user holds %v after deleting ONT, want nothing", got)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	u, database := newTestUserImpl(t, nil)
+	ctx := context.Background()
+	email := "refresh@example.com"
+	userId := registerTestUser(t, u, email, true)
+
+	_, firstRefreshToken, err := u.Login(ctx, email, testPassword, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	otherAccessToken, otherRefreshToken, err := u.Login(ctx, email, testPassword, "other", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	_, secondRefreshToken, err := u.RefreshToken(ctx, *firstRefreshToken, userId, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	accessToken, latestRefreshToken, err := u.RefreshToken(ctx, *secondRefreshToken, userId, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+
+	_, _, err = u.RefreshToken(ctx, *firstRefreshToken, userId, "attacker", "10.0.0.1")
+	if err != ErrRefreshTokenReused {
+		t.Fatalf("replayed refresh token error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	// Every token of the family stops working, including the latest one.
+	_, _, err = u.RefreshToken(ctx, *latestRefreshToken, userId, "test", "127.0.0.1")
+	if err != ErrInvalidRefreshToken {
+		t.Fatalf("latest refresh token of a revoked family error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+	ok, err := u.tokenStore.Contains(ctx, *accessToken)
+	if err != nil || ok {
+		t.Fatalf("access token of a revoked family still valid (%v)", err)
+	}
+
+	var reuseEvents int
+	err = database.QueryRow("SELECT COUNT(*) FROM audit_events WHERE userId = ? AND event = ?", userId, refreshTokenReuseAuditEvent).Scan(&reuseEvents)
+	if err != nil || reuseEvents != 1 {
+		t.Fatalf("got %d reuse audit events (%v), want 1", reuseEvents, err)
+	}
+
+	// The user's other session is a different family and keeps working.
+	ok, err = u.tokenStore.Contains(ctx, *otherAccessToken)
+	if err != nil || !ok {
+		t.Fatalf("access token of another session was revoked (%v)", err)
+	}
+	sessions, err := u.GetUserSessions(ctx, userId)
+	if err != nil || len(*sessions) != 1 {
+		t.Fatalf("GetUserSessions = %v, %v, want only the other session", sessions, err)
+	}
+	_, _, err = u.RefreshToken(ctx, *otherRefreshToken, userId, "other", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("RefreshToken of another session: %v", err)
+	}
+}
@@ -7,11 +7,41 @@ import (
 )
 
 type UserUsecase interface {
-	Login(ctx context.Context, email, password string) (*string, *string, error)
+	Login(ctx context.Context, email, password, userAgent, ipAddress string) (*string, *string, error)
+	LoginMFA(ctx context.Context, mfaToken, code, userAgent, ipAddress string) (*string, *string, error)
+	StartOIDCLogin(ctx context.Context) (*string, *string, error)
+	LoginOIDC(ctx context.Context, state, code, userAgent, ipAddress string) (*string, *string, error)
+	ClearLoginLockout(ctx context.Context, target string) error
 	Register(ctx context.Context, email, password string) error
-	Logout(ctx context.Context, accessToken string, userId int) error
-	RefreshToken(ctx context.Context, refreshToken string, userId int) (*string, *string, error)
-	GetUserAssetsByUserId(ctx context.Context, userId int) (*[]model.Asset, error)
-	InsertUserAsset(ctx context.Context, userId int, assetId string) error
-	DeleteUserAsset(ctx context.Context, userId int, assetId string) error
+	SendVerificationEmail(ctx context.Context, email string) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	ChangePassword(ctx context.Context, userId int, sessionId, currentPassword, password, userAgent, ipAddress string) error
+	RequestEmailChange(ctx context.Context, userId int, password, email, userAgent, ipAddress string) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+	DeleteAccount(ctx context.Context, userId int, password, userAgent, ipAddress string) error
+	ExportUserData(ctx context.Context, userId int, userAgent, ipAddress string) (*[]byte, error)
+	Logout(ctx context.Context, accessToken string, userId int, sessionId string) error
+	RefreshToken(ctx context.Context, refreshToken string, userId int, userAgent, ipAddress string) (*string, *string, error)
+	GetUserSessions(ctx context.Context, userId int) (*[]model.UserSession, error)
+	RevokeUserSession(ctx context.Context, userId int, sessionId string) error
+	RevokeAllUserSessions(ctx context.Context, userId int) error
+	EnrollMFA(ctx context.Context, userId int) (*model.MFAEnrollment, error)
+	ConfirmMFA(ctx context.Context, userId int, code string) ([]string, error)
+	DisableMFA(ctx context.Context, userId int, code string) error
+	CreateAPIKey(ctx context.Context, userId int, name string, scopes []string, expiresInDays int) (*model.UserAPIKey, *string, error)
+	GetAPIKeys(ctx context.Context, userId int) (*[]model.UserAPIKey, error)
+	RevokeAPIKey(ctx context.Context, userId, apiKeyId int) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*model.UserAPIKey, *model.User, error)
+	SearchUsers(ctx context.Context, query string, limit, offset int) (*[]model.User, error)
+	GetUser(ctx context.Context, userId int) (*model.User, error)
+	SetUserDisabled(ctx context.Context, adminId, userId int, disabled bool, userAgent, ipAddress string) error
+	ForceLogoutUser(ctx context.Context, adminId, userId int, userAgent, ipAddress string) error
+	AdminClearLoginLockout(ctx context.Context, adminId int, target, userAgent, ipAddress string) error
+	SetUserRole(ctx context.Context, email, role string) error
+	SetUserCurrency(ctx context.Context, userId int, currency string) (*string, error)
+	GetUserAssetsByUserId(ctx context.Context, userId int, currency string) (*[]model.Asset, error)
+	InsertUserAsset(ctx context.Context, userId int, asset string) (*string, error)
+	DeleteUserAsset(ctx context.Context, userId int, asset string) error
 }
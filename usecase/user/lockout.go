@@ -0,0 +1,162 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+)
+
+const (
+	accountLoginAttemptPrefix = "account:"
+	ipLoginAttemptPrefix      = "ip:"
+
+	loginLockedAuditEvent = "login_locked"
+
+	defaultMaxAccountFailures = 5
+	defaultMaxIPFailures      = 20
+	defaultFailureWindow      = 15 * 60
+	defaultBackoffBase        = 1
+	defaultBackoffMax         = 60
+	defaultLockoutDuration    = 15 * 60
+)
+
+// LoginLockedError is returned while an account or client IP is throttled
+// after failed login attempts. RetryAfter is how long the caller must wait.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "too many failed login attempts"
+}
+
+func withLoginConfigDefaults(cfg config.LoginConfig) config.LoginConfig {
+	if cfg.MaxAccountFailures == 0 {
+		cfg.MaxAccountFailures = defaultMaxAccountFailures
+	}
+	if cfg.MaxIPFailures == 0 {
+		cfg.MaxIPFailures = defaultMaxIPFailures
+	}
+	if cfg.FailureWindow == 0 {
+		cfg.FailureWindow = defaultFailureWindow
+	}
+	if cfg.BackoffBase == 0 {
+		cfg.BackoffBase = defaultBackoffBase
+	}
+	if cfg.BackoffMax == 0 {
+		cfg.BackoffMax = defaultBackoffMax
+	}
+	if cfg.LockoutDuration == 0 {
+		cfg.LockoutDuration = defaultLockoutDuration
+	}
+	return cfg
+}
+
+func accountLoginAttemptKey(email string) string {
+	return accountLoginAttemptPrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipLoginAttemptKey(ipAddress string) string {
+	return ipLoginAttemptPrefix + ipAddress
+}
+
+// ClearLoginLockout forgets the failed attempts recorded for an email address
+// or client IP address.
+func (u *userImpl) ClearLoginLockout(ctx context.Context, target string) error {
+	err := u.dbCrypto.DeleteLoginAttempt(ctx, accountLoginAttemptKey(target))
+	if err != nil {
+		return err
+	}
+
+	return u.dbCrypto.DeleteLoginAttempt(ctx, ipLoginAttemptKey(target))
+}
+
+func (u *userImpl) checkLoginLockout(ctx context.Context, currTime time.Time, accountKey, ipKey string) error {
+	var retryAfter int64
+	for _, key := range []string{accountKey, ipKey} {
+		attempt, err := u.dbCrypto.GetLoginAttempt(ctx, key)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			return err
+		}
+
+		if wait := attempt.LockedUntil - currTime.Unix(); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: time.Duration(retryAfter) * time.Second}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed attempt against both the account and the
+// client IP. Every failure delays the next attempt exponentially, and reaching
+// the configured maximum locks the key out for the lockout duration.
+func (u *userImpl) recordLoginFailure(ctx context.Context, currTime time.Time, userId int, accountKey, ipKey, userAgent, ipAddress string) error {
+	limits := []struct {
+		key         string
+		maxFailures int
+	}{
+		{accountKey, u.loginConfig.MaxAccountFailures},
+		{ipKey, u.loginConfig.MaxIPFailures},
+	}
+
+	for _, limit := range limits {
+		attempt, err := u.dbCrypto.GetLoginAttempt(ctx, limit.key)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if attempt == nil || currTime.Unix()-attempt.LastFailureAt > int64(u.loginConfig.FailureWindow) {
+			attempt = &model.LoginAttempt{Key: limit.key}
+		}
+
+		attempt.Failures++
+		attempt.LastFailureAt = currTime.Unix()
+
+		if attempt.Failures >= limit.maxFailures {
+			attempt.LockedUntil = currTime.Add(time.Second * u.loginConfig.LockoutDuration).Unix()
+
+			err = u.dbCrypto.InsertAuditEvent(ctx, model.AuditEvent{
+				UserId:    userId,
+				Event:     loginLockedAuditEvent,
+				Detail:    fmt.Sprintf("%s locked after %d failed login attempts", limit.key, attempt.Failures),
+				UserAgent: userAgent,
+				IPAddress: ipAddress,
+				CreatedAt: currTime.Unix(),
+			})
+			if err != nil {
+				return err
+			}
+		} else {
+			attempt.LockedUntil = currTime.Add(u.backoffDelay(attempt.Failures)).Unix()
+		}
+
+		err = u.dbCrypto.UpsertLoginAttempt(ctx, *attempt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *userImpl) backoffDelay(failures int) time.Duration {
+	delay := time.Second * u.loginConfig.BackoffBase
+	maxDelay := time.Second * u.loginConfig.BackoffMax
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
@@ -0,0 +1,236 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/auth"
+	"github.com/michaelwongycn/crypto-tracker/lib/random"
+	"github.com/michaelwongycn/crypto-tracker/lib/totp"
+)
+
+const (
+	totpIssuer = "Crypto Tracker"
+	totpSkew   = 1
+
+	mfaLoginPurpose = "mfa_login"
+
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+func (u *userImpl) LoginMFA(ctx context.Context, mfaToken, code, userAgent, ipAddress string) (*string, *string, error) {
+	claims, err := auth.ParseMFAToken(mfaToken)
+	if err != nil {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	userId, ok := claims["sub"].(float64)
+	tokenId, _ := claims["jti"].(string)
+	if !ok || tokenId == "" {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	user, err := u.dbCrypto.GetUserById(ctx, int(userId))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	currTime := time.Now()
+	accountKey := accountLoginAttemptKey(user.Email)
+	ipKey := ipLoginAttemptKey(ipAddress)
+
+	err = u.checkLoginLockout(ctx, currTime, accountKey, ipKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = u.verifyMFACode(ctx, user.ID, code, currTime)
+	if err != nil {
+		if err == ErrInvalidMFACode {
+			recordErr := u.recordLoginFailure(ctx, currTime, user.ID, accountKey, ipKey, userAgent, ipAddress)
+			if recordErr != nil {
+				return nil, nil, recordErr
+			}
+		}
+		return nil, nil, err
+	}
+
+	used, err := u.dbCrypto.UseUserActionToken(ctx, tokenId, user.ID, mfaLoginPurpose, currTime.Unix())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !used {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	return u.createSession(ctx, currTime, *user, userAgent, ipAddress)
+}
+
+// createMFAToken records a single-use token for the second step of a login.
+// It is burned once a code is accepted, so one password check can't be turned
+// into more than one session.
+func (u *userImpl) createMFAToken(ctx context.Context, currTime time.Time, userId int) (string, error) {
+	tokenId, err := random.Hex(actionTokenIdLength)
+	if err != nil {
+		return "", err
+	}
+
+	mfaToken, err := auth.CreateMFAToken(currTime, userId, tokenId)
+	if err != nil {
+		return "", err
+	}
+
+	err = u.dbCrypto.InsertUserActionToken(ctx, model.UserActionToken{
+		ID:             tokenId,
+		UserId:         userId,
+		Purpose:        mfaLoginPurpose,
+		ExpirationTime: auth.MFATokenExpiration(currTime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return mfaToken, nil
+}
+
+// EnrollMFA generates a new TOTP secret for the user. Two-factor
+// authentication stays disabled until ConfirmMFA sees a code from it.
+func (u *userImpl) EnrollMFA(ctx context.Context, userId int) (*model.MFAEnrollment, error) {
+	mfa, err := u.dbCrypto.GetUserMFA(ctx, userId)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if mfa != nil && mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	user, err := u.dbCrypto.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.dbCrypto.InsertUserMFA(ctx, userId, secret, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA enables two-factor authentication once the user proves their
+// authenticator works, and returns the one-time recovery codes. They are only
+// stored hashed, so this is the only time they can be shown.
+func (u *userImpl) ConfirmMFA(ctx context.Context, userId int, code string) ([]string, error) {
+	mfa, err := u.dbCrypto.GetUserMFA(ctx, userId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(code, mfa.Secret, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	recoveryCodeHashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		recoveryCode, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		recoveryCodeHashes = append(recoveryCodeHashes, hashRecoveryCode(recoveryCode))
+	}
+
+	err = u.dbCrypto.EnableUserMFA(ctx, userId, step, recoveryCodeHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+func (u *userImpl) DisableMFA(ctx context.Context, userId int, code string) error {
+	err := u.verifyMFACode(ctx, userId, code, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return u.dbCrypto.DeleteUserMFA(ctx, userId)
+}
+
+// verifyMFACode accepts either a TOTP code, which can't be replayed within its
+// time step, or an unused recovery code, which is burned on use.
+func (u *userImpl) verifyMFACode(ctx context.Context, userId int, code string, currTime time.Time) error {
+	mfa, err := u.dbCrypto.GetUserMFA(ctx, userId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrMFANotEnrolled
+		}
+		return err
+	}
+	if !mfa.Enabled {
+		return ErrMFANotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(code, mfa.Secret, currTime, totpSkew); ok {
+		accepted, err := u.dbCrypto.UpdateUserMFALastUsedStep(ctx, userId, step)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := u.dbCrypto.UseRecoveryCode(ctx, userId, hashRecoveryCode(code), currTime.Unix())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func generateRecoveryCode() (string, error) {
+	b, err := random.Bytes(recoveryCodeLength)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+	return code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:recoveryCodeLength], nil
+}
+
+// hashRecoveryCode normalises the code the way users tend to retype it before
+// hashing. Recovery codes are random enough that a fast hash is sufficient.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(code, "-", ""), " ", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,238 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/auth"
+	"github.com/michaelwongycn/crypto-tracker/lib/totp"
+)
+
+// enableTestMFA enrolls the user and confirms it with the current code. It
+// returns the TOTP secret and the recovery codes.
+func enableTestMFA(t *testing.T, u *userImpl, userId int) (string, []string) {
+	t.Helper()
+
+	ctx := context.Background()
+	enrollment, err := u.EnrollMFA(ctx, userId)
+	if err != nil {
+		t.Fatalf("EnrollMFA: %v", err)
+	}
+
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+
+	recoveryCodes, err := u.ConfirmMFA(ctx, userId, code)
+	if err != nil {
+		t.Fatalf("ConfirmMFA: %v", err)
+	}
+	return enrollment.Secret, recoveryCodes
+}
+
+// loginForMFAToken logs in with the password and returns the MFA token.
+func loginForMFAToken(t *testing.T, u *userImpl, email string) string {
+	t.Helper()
+
+	_, _, err := u.Login(context.Background(), email, testPassword, "test", "127.0.0.1")
+	var mfaErr *MFARequiredError
+	if !errors.As(err, &mfaErr) {
+		t.Fatalf("Login error = %v, want MFA required", err)
+	}
+	return mfaErr.MFAToken
+}
+
+func TestLoginKeepsFailuresUntilMFASucceeds(t *testing.T) {
+	u, _ := newTestUserImpl(t, nil)
+	ctx := context.Background()
+	email := "mfa@example.com"
+	userId := registerTestUser(t, u, email, true)
+	_, recoveryCodes := enableTestMFA(t, u, userId)
+
+	accountKey := accountLoginAttemptKey(email)
+	err := u.dbCrypto.UpsertLoginAttempt(ctx, model.LoginAttempt{Key: accountKey, Failures: 3, LastFailureAt: time.Now().Unix()})
+	if err != nil {
+		t.Fatalf("UpsertLoginAttempt: %v", err)
+	}
+
+	// The password alone doesn't reset the count of failed second factors.
+	mfaToken := loginForMFAToken(t, u, email)
+	attempt, err := u.dbCrypto.GetLoginAttempt(ctx, accountKey)
+	if err != nil || attempt.Failures != 3 {
+		t.Fatalf("account attempts after the password = %+v, %v, want 3 failures", attempt, err)
+	}
+
+	_, _, err = u.LoginMFA(ctx, mfaToken, recoveryCodes[0], "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("LoginMFA: %v", err)
+	}
+	_, err = u.dbCrypto.GetLoginAttempt(ctx, accountKey)
+	if err != sql.ErrNoRows {
+		t.Fatalf("GetLoginAttempt error = %v, want the attempts cleared by the session", err)
+	}
+
+	// The MFA token was used up by the session.
+	_, _, err = u.LoginMFA(ctx, mfaToken, recoveryCodes[1], "test", "127.0.0.1")
+	if err != ErrInvalidCredentials {
+		t.Fatalf("LoginMFA with a used token error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+// clearTestLoginLockout forgets the failed attempts of email and the test
+// IP, so a rejected code doesn't delay the next attempt.
+func clearTestLoginLockout(t *testing.T, u *userImpl, email string) {
+	t.Helper()
+
+	for _, target := range []string{email, "127.0.0.1"} {
+		err := u.ClearLoginLockout(context.Background(), target)
+		if err != nil {
+			t.Fatalf("ClearLoginLockout: %v", err)
+		}
+	}
+}
+
+func TestConfirmMFAEnablesTwoFactorLogin(t *testing.T) {
+	u, _ := newTestUserImpl(t, nil)
+	ctx := context.Background()
+	email := "confirm@example.com"
+	userId := registerTestUser(t, u, email, true)
+
+	_, err := u.ConfirmMFA(ctx, userId, "123456")
+	if err != ErrMFANotEnrolled {
+		t.Fatalf("ConfirmMFA before enrolling error = %v, want %v", err, ErrMFANotEnrolled)
+	}
+
+	enrollment, err := u.EnrollMFA(ctx, userId)
+	if err != nil {
+		t.Fatalf("EnrollMFA: %v", err)
+	}
+
+	// An enrollment that isn't confirmed doesn't change the login.
+	_, _, err = u.Login(ctx, email, testPassword, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Login before confirming: %v", err)
+	}
+
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+	_, err = u.ConfirmMFA(ctx, userId, code)
+	if err != ErrInvalidMFACode {
+		t.Fatalf("ConfirmMFA with an old code error = %v, want %v", err, ErrInvalidMFACode)
+	}
+
+	code, err = totp.GenerateCode(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+	recoveryCodes, err := u.ConfirmMFA(ctx, userId, code)
+	if err != nil {
+		t.Fatalf("ConfirmMFA: %v", err)
+	}
+	if len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(recoveryCodes), recoveryCodeCount)
+	}
+
+	_, err = u.ConfirmMFA(ctx, userId, code)
+	if err != ErrMFAAlreadyEnabled {
+		t.Fatalf("ConfirmMFA twice error = %v, want %v", err, ErrMFAAlreadyEnabled)
+	}
+	_, err = u.EnrollMFA(ctx, userId)
+	if err != ErrMFAAlreadyEnabled {
+		t.Fatalf("EnrollMFA when enabled error = %v, want %v", err, ErrMFAAlreadyEnabled)
+	}
+
+	loginForMFAToken(t, u, email)
+}
+
+func TestLoginMFARejectsReusedCode(t *testing.T) {
+	u, _ := newTestUserImpl(t, nil)
+	ctx := context.Background()
+	email := "reuse@example.com"
+	userId := registerTestUser(t, u, email, true)
+	secret, _ := enableTestMFA(t, u, userId)
+
+	// The next step's code is within the allowed skew and newer than the
+	// one used to confirm.
+	code, err := totp.GenerateCode(secret, time.Now().Add(30*time.Second))
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+
+	accessToken, refreshToken, err := u.LoginMFA(ctx, loginForMFAToken(t, u, email), code, "test", "127.0.0.1")
+	if err != nil || accessToken == nil || refreshToken == nil {
+		t.Fatalf("LoginMFA = %v, %v, %v, want a session", accessToken, refreshToken, err)
+	}
+
+	_, _, err = u.LoginMFA(ctx, loginForMFAToken(t, u, email), code, "test", "127.0.0.1")
+	if err != ErrInvalidMFACode {
+		t.Fatalf("LoginMFA with a reused code error = %v, want %v", err, ErrInvalidMFACode)
+	}
+	clearTestLoginLockout(t, u, email)
+
+	// Codes of earlier steps are refused as well.
+	code, err = totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+	_, _, err = u.LoginMFA(ctx, loginForMFAToken(t, u, email), code, "test", "127.0.0.1")
+	if err != ErrInvalidMFACode {
+		t.Fatalf("LoginMFA with an older code error = %v, want %v", err, ErrInvalidMFACode)
+	}
+}
+
+func TestRecoveryCodeWorksOnce(t *testing.T) {
+	u, _ := newTestUserImpl(t, nil)
+	ctx := context.Background()
+	email := "recovery@example.com"
+	userId := registerTestUser(t, u, email, true)
+	_, recoveryCodes := enableTestMFA(t, u, userId)
+
+	// Recovery codes are accepted however the user retypes them.
+	_, _, err := u.LoginMFA(ctx, loginForMFAToken(t, u, email), " "+strings.ToUpper(recoveryCodes[0])+" ", "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("LoginMFA with a recovery code: %v", err)
+	}
+
+	_, _, err = u.LoginMFA(ctx, loginForMFAToken(t, u, email), recoveryCodes[0], "test", "127.0.0.1")
+	if err != ErrInvalidMFACode {
+		t.Fatalf("LoginMFA with a used recovery code error = %v, want %v", err, ErrInvalidMFACode)
+	}
+	clearTestLoginLockout(t, u, email)
+
+	_, _, err = u.LoginMFA(ctx, loginForMFAToken(t, u, email), recoveryCodes[1], "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("LoginMFA with another recovery code: %v", err)
+	}
+}
+
+func TestLoginMFARequiresValidToken(t *testing.T) {
+	u, _ := newTestUserImpl(t, nil)
+	ctx := context.Background()
+	email := "token@example.com"
+	userId := registerTestUser(t, u, email, true)
+	_, recoveryCodes := enableTestMFA(t, u, userId)
+
+	mfaToken := loginForMFAToken(t, u, email)
+
+	_, _, err := u.LoginMFA(ctx, "not-a-token", recoveryCodes[0], "test", "127.0.0.1")
+	if err != ErrInvalidCredentials {
+		t.Fatalf("LoginMFA with an invalid token error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	accessToken, _, err := u.LoginMFA(ctx, mfaToken, recoveryCodes[0], "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("LoginMFA: %v", err)
+	}
+	claims, err := auth.ParseToken(*accessToken)
+	if err != nil || int(claims["sub"].(float64)) != userId {
+		t.Fatalf("access token claims = %v, %v, want user %d", claims, err, userId)
+	}
+}
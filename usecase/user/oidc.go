@@ -0,0 +1,202 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/michaelwongycn/crypto-tracker/lib/random"
+)
+
+const (
+	oidcStateLength        = 16
+	oidcNonceLength        = 16
+	oidcCodeVerifierLength = 32
+	oidcPasswordLength     = 32
+
+	defaultOIDCStateDuration = 10
+
+	identityLinkedAuditEvent = "identity_linked"
+
+	oidcLoginFailedErrorMsg = "oidc login failed"
+)
+
+var (
+	ErrOIDCDisabled           = errors.New("oidc login is not configured")
+	ErrInvalidOIDCState       = errors.New("invalid or expired oidc state")
+	ErrOIDCLoginFailed        = errors.New("oidc login failed")
+	ErrOIDCEmailNotVerified   = errors.New("oidc provider did not return a verified email")
+	ErrOIDCAccountNotLinkable = errors.New("account email is not verified")
+)
+
+// StartOIDCLogin begins an authorization code flow with PKCE. It returns the
+// provider URL to send the user to and the state that will come back with
+// the code.
+func (u *userImpl) StartOIDCLogin(ctx context.Context) (*string, *string, error) {
+	if u.oidcProvider == nil {
+		return nil, nil, ErrOIDCDisabled
+	}
+
+	currTime := time.Now()
+
+	err := u.dbCrypto.DeleteExpiredOIDCStates(ctx, currTime.Unix())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state, err := random.Hex(oidcStateLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce, err := random.Hex(oidcNonceLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	codeVerifier, err := random.Hex(oidcCodeVerifierLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	authURL, err := u.oidcProvider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		log.PrintLogErr(ctx, oidcLoginFailedErrorMsg, err)
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	err = u.dbCrypto.InsertOIDCState(ctx, model.OIDCState{
+		State:          state,
+		Nonce:          nonce,
+		CodeVerifier:   codeVerifier,
+		ExpirationTime: currTime.Add(time.Minute * u.oidcStateDuration).Unix(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &authURL, &state, nil
+}
+
+// LoginOIDC finishes the flow started by StartOIDCLogin. The identity is
+// matched to an account by issuer and subject, or on first sign-in by a
+// verified email address, and then logs in like a password would.
+func (u *userImpl) LoginOIDC(ctx context.Context, state, code, userAgent, ipAddress string) (*string, *string, error) {
+	if u.oidcProvider == nil {
+		return nil, nil, ErrOIDCDisabled
+	}
+
+	currTime := time.Now()
+
+	oidcState, err := u.dbCrypto.UseOIDCState(ctx, state, currTime.Unix())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrInvalidOIDCState
+		}
+		return nil, nil, err
+	}
+
+	rawIDToken, err := u.oidcProvider.Exchange(ctx, code, oidcState.CodeVerifier)
+	if err != nil {
+		log.PrintLogErr(ctx, oidcLoginFailedErrorMsg, err)
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	idToken, err := u.oidcProvider.VerifyIDToken(ctx, rawIDToken, oidcState.Nonce)
+	if err != nil {
+		log.PrintLogErr(ctx, oidcLoginFailedErrorMsg, err)
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	identity := model.UserIdentity{
+		Issuer:    idToken.Issuer,
+		Subject:   idToken.Subject,
+		Email:     idToken.Email,
+		CreatedAt: currTime.Unix(),
+	}
+
+	user, err := u.getOrLinkIdentityUser(ctx, identity, idToken.EmailVerified, userAgent, ipAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return u.completeLogin(ctx, currTime, *user, userAgent, ipAddress)
+}
+
+// getOrLinkIdentityUser returns the account an identity belongs to, linking
+// it to the account with the same email or creating one if it is new. Only
+// an email the provider has verified is trusted, and only accounts whose
+// owner has verified the email themselves are linked, otherwise whoever
+// registered the address first would gain access to the provider's user.
+func (u *userImpl) getOrLinkIdentityUser(ctx context.Context, identity model.UserIdentity, emailVerified bool, userAgent, ipAddress string) (*model.User, error) {
+	existing, err := u.dbCrypto.GetUserIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		return u.dbCrypto.GetUserById(ctx, existing.UserId)
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if identity.Email == "" || !emailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	address, err := mail.ParseAddress(identity.Email)
+	if err != nil || address.Address != identity.Email {
+		return nil, ErrInvalidEmail
+	}
+
+	user, err := u.dbCrypto.GetUserByEmail(ctx, identity.Email)
+	if err == sql.ErrNoRows {
+		// The account has no password anyone knows, one can be set through
+		// the password reset flow.
+		password, err := random.Hex(oidcPasswordLength)
+		if err != nil {
+			return nil, err
+		}
+
+		hashedPassword, err := u.passwordHasher.Hash(password)
+		if err != nil {
+			return nil, err
+		}
+
+		userId, err := u.dbCrypto.InsertUserWithIdentity(ctx, identity.Email, hashedPassword, identity)
+		if err != nil {
+			return nil, err
+		}
+
+		return u.dbCrypto.GetUserById(ctx, userId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.EmailVerified {
+		return nil, ErrOIDCAccountNotLinkable
+	}
+
+	identity.UserId = user.ID
+	err = u.dbCrypto.InsertUserIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.dbCrypto.InsertAuditEvent(ctx, model.AuditEvent{
+		UserId:    user.ID,
+		Event:     identityLinkedAuditEvent,
+		Detail:    fmt.Sprintf("linked %s subject %s", identity.Issuer, identity.Subject),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		CreatedAt: identity.CreatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,251 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/lib/oidc"
+	"github.com/michaelwongycn/crypto-tracker/lib/oidc/oidctest"
+)
+
+const (
+	testOIDCClientId    = "crypto-tracker"
+	testOIDCRedirectURL = "http://app.test/oidc/callback"
+)
+
+type oidcTestEnv struct {
+	usecase  *userImpl
+	db       *sql.DB
+	provider *oidctest.Provider
+	client   *http.Client
+}
+
+// newOIDCTestEnv runs the mock provider on an httptest server, configure can
+// change it before it serves any request.
+func newOIDCTestEnv(t *testing.T, configure func(p *oidctest.Provider)) *oidcTestEnv {
+	t.Helper()
+
+	provider, err := oidctest.NewProvider("", testOIDCClientId)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	if configure != nil {
+		configure(provider)
+	}
+
+	server := httptest.NewServer(provider)
+	t.Cleanup(server.Close)
+	provider.Issuer = server.URL
+
+	oidcProvider, err := oidc.NewProvider(config.OIDCConfig{
+		Issuer:      server.URL,
+		ClientID:    testOIDCClientId,
+		RedirectURL: testOIDCRedirectURL,
+	})
+	if err != nil {
+		t.Fatalf("oidc.NewProvider: %v", err)
+	}
+
+	usecase, database := newTestUserImpl(t, oidcProvider)
+
+	return &oidcTestEnv{
+		usecase:  usecase,
+		db:       database,
+		provider: provider,
+		client: &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// authorize starts a login and follows the provider's authorization endpoint
+// the way a browser would, returning what comes back to the redirect URL.
+func (e *oidcTestEnv) authorize(t *testing.T, loginHint string) (state, code string) {
+	t.Helper()
+
+	authURL, startState, err := e.usecase.StartOIDCLogin(context.Background())
+	if err != nil {
+		t.Fatalf("StartOIDCLogin: %v", err)
+	}
+
+	parsed, err := url.Parse(*authURL)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", *authURL, err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" || query.Get("nonce") == "" {
+		t.Fatalf("authorization URL %q is missing PKCE or nonce", *authURL)
+	}
+	if loginHint != "" {
+		query.Set("login_hint", loginHint)
+		parsed.RawQuery = query.Encode()
+	}
+
+	resp, err := e.client.Get(parsed.String())
+	if err != nil {
+		t.Fatalf("GET authorization endpoint: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorization endpoint returned status %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), testOIDCRedirectURL) {
+		t.Fatalf("authorization endpoint redirected to %q", resp.Header.Get("Location"))
+	}
+
+	state, code = location.Query().Get("state"), location.Query().Get("code")
+	if state != *startState {
+		t.Fatalf("state came back as %q, want %q", state, *startState)
+	}
+	return state, code
+}
+
+func (e *oidcTestEnv) login(state, code string) (*string, *string, error) {
+	return e.usecase.LoginOIDC(context.Background(), state, code, "test", "127.0.0.1")
+}
+
+func (e *oidcTestEnv) identityUserId(t *testing.T, email string) int {
+	t.Helper()
+
+	var userId int
+	err := e.db.QueryRow("SELECT userId FROM user_identities WHERE email = ?", email).Scan(&userId)
+	if err != nil {
+		t.Fatalf("identity of %s: %v", email, err)
+	}
+	return userId
+}
+
+func TestLoginOIDCCreatesAndReusesAccount(t *testing.T) {
+	e := newOIDCTestEnv(t, nil)
+	email := "new@example.com"
+
+	accessToken, refreshToken, err := e.login(e.authorize(t, email))
+	if err != nil {
+		t.Fatalf("first LoginOIDC: %v", err)
+	}
+	if *accessToken == "" || *refreshToken == "" {
+		t.Fatal("first LoginOIDC returned empty tokens")
+	}
+
+	user, err := e.usecase.dbCrypto.GetUserByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatalf("account was not created: %v", err)
+	}
+	if got := e.identityUserId(t, email); got != user.ID {
+		t.Fatalf("identity belongs to user %d, want %d", got, user.ID)
+	}
+
+	_, _, err = e.login(e.authorize(t, email))
+	if err != nil {
+		t.Fatalf("second LoginOIDC: %v", err)
+	}
+
+	var users int
+	err = e.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&users)
+	if err != nil || users != 1 {
+		t.Fatalf("%d users after signing in twice (%v), want 1", users, err)
+	}
+}
+
+func TestLoginOIDCLinksVerifiedAccount(t *testing.T) {
+	e := newOIDCTestEnv(t, nil)
+	email := "linked@example.com"
+	userId := registerTestUser(t, e.usecase, email, true)
+
+	_, _, err := e.login(e.authorize(t, email))
+	if err != nil {
+		t.Fatalf("LoginOIDC: %v", err)
+	}
+
+	if got := e.identityUserId(t, email); got != userId {
+		t.Fatalf("identity linked to user %d, want %d", got, userId)
+	}
+
+	var events int
+	err = e.db.QueryRow("SELECT COUNT(*) FROM audit_events WHERE userId = ? AND event = ?", userId, identityLinkedAuditEvent).Scan(&events)
+	if err != nil || events != 1 {
+		t.Fatalf("%d %s audit events (%v), want 1", events, identityLinkedAuditEvent, err)
+	}
+}
+
+func TestLoginOIDCDoesNotLinkUnverifiedAccount(t *testing.T) {
+	e := newOIDCTestEnv(t, nil)
+	email := "unverified@example.com"
+	registerTestUser(t, e.usecase, email, false)
+
+	_, _, err := e.login(e.authorize(t, email))
+	if !errors.Is(err, ErrOIDCAccountNotLinkable) {
+		t.Fatalf("LoginOIDC error = %v, want %v", err, ErrOIDCAccountNotLinkable)
+	}
+}
+
+func TestLoginOIDCRequiresVerifiedProviderEmail(t *testing.T) {
+	e := newOIDCTestEnv(t, func(p *oidctest.Provider) {
+		p.EmailVerified = false
+	})
+
+	_, _, err := e.login(e.authorize(t, "unverified-at-provider@example.com"))
+	if !errors.Is(err, ErrOIDCEmailNotVerified) {
+		t.Fatalf("LoginOIDC error = %v, want %v", err, ErrOIDCEmailNotVerified)
+	}
+}
+
+func TestLoginOIDCRejectsUnknownAndReusedState(t *testing.T) {
+	e := newOIDCTestEnv(t, nil)
+
+	state, code := e.authorize(t, "state@example.com")
+
+	_, _, err := e.login("unknown-state", code)
+	if !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("LoginOIDC with an unknown state error = %v, want %v", err, ErrInvalidOIDCState)
+	}
+
+	_, _, err = e.login(state, code)
+	if err != nil {
+		t.Fatalf("LoginOIDC: %v", err)
+	}
+
+	_, _, err = e.login(state, code)
+	if !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("LoginOIDC with a used state error = %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
+
+func TestLoginOIDCChecksNonceAndPKCE(t *testing.T) {
+	tests := []struct {
+		name   string
+		column string
+		want   string
+	}{
+		{name: "nonce", column: "nonce", want: "nonce mismatch"},
+		{name: "code verifier", column: "codeVerifier", want: "code_verifier does not match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newOIDCTestEnv(t, nil)
+			state, code := e.authorize(t, "tampered@example.com")
+
+			_, err := e.db.Exec("UPDATE oidc_states SET "+tt.column+" = ? WHERE state = ?", "tampered", state)
+			if err != nil {
+				t.Fatalf("tampering with the stored %s: %v", tt.column, err)
+			}
+
+			_, _, err = e.login(state, code)
+			if !errors.Is(err, ErrOIDCLoginFailed) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoginOIDC error = %v, want %v with %q", err, ErrOIDCLoginFailed, tt.want)
+			}
+		})
+	}
+}
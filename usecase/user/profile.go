@@ -0,0 +1,263 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/model"
+	"github.com/michaelwongycn/crypto-tracker/lib/auth"
+	"github.com/michaelwongycn/crypto-tracker/lib/log"
+	"github.com/michaelwongycn/crypto-tracker/lib/mailer"
+)
+
+const (
+	changeEmailPurpose = "change_email"
+
+	passwordChangedAuditEvent = "password_changed"
+	emailChangedAuditEvent    = "email_changed"
+
+	changeEmailSubject = "Confirm your new Crypto Tracker email address"
+	changeEmailBody    = `Hi,
+
+Someone asked to use this address for their Crypto Tracker account. Open the link below to confirm it:
+
+%s
+
+The link expires in %d minutes. If it wasn't you, you can ignore this email.
+`
+	emailChangedSubject = "Your Crypto Tracker email address was changed"
+	emailChangedBody    = `Hi,
+
+The email address of your Crypto Tracker account was changed to %s. If it wasn't you, please contact us right away.
+`
+)
+
+var ErrEmailAlreadyRegistered = errors.New("email already registered")
+
+// verifyCurrentPassword guards account changes made from a signed in session.
+// Wrong guesses count towards the same lockout as failed logins, so a stolen
+// access token can't be used to brute force the password.
+func (u *userImpl) verifyCurrentPassword(ctx context.Context, user model.User, password, userAgent, ipAddress string) error {
+	currTime := time.Now()
+	accountKey := accountLoginAttemptKey(user.Email)
+	ipKey := ipLoginAttemptKey(ipAddress)
+
+	err := u.checkLoginLockout(ctx, currTime, accountKey, ipKey)
+	if err != nil {
+		return err
+	}
+
+	match, _, err := u.passwordHasher.Verify(password, user.Password)
+	if err != nil {
+		return err
+	}
+	if !match {
+		err = u.recordLoginFailure(ctx, currTime, user.ID, accountKey, ipKey, userAgent, ipAddress)
+		if err != nil {
+			return err
+		}
+		return ErrInvalidCredentials
+	}
+
+	return u.dbCrypto.DeleteLoginAttempt(ctx, accountKey)
+}
+
+// ChangePassword replaces the password and signs out every session except
+// the one making the change.
+func (u *userImpl) ChangePassword(ctx context.Context, userId int, sessionId, currentPassword, password, userAgent, ipAddress string) error {
+	user, err := u.dbCrypto.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	err = u.verifyCurrentPassword(ctx, *user, currentPassword, userAgent, ipAddress)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := u.passwordHasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	err = u.dbCrypto.UpdateUserPassword(ctx, user.ID, hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := u.dbCrypto.GetUserSessionsByUserId(ctx, user.ID, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range *sessions {
+		if session.ID == sessionId {
+			continue
+		}
+
+		err = u.RevokeUserSession(ctx, user.ID, session.ID)
+		if err != nil && err != ErrSessionNotFound {
+			return err
+		}
+	}
+
+	return u.dbCrypto.InsertAuditEvent(ctx, model.AuditEvent{
+		UserId:    user.ID,
+		Event:     passwordChangedAuditEvent,
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// RequestEmailChange emails a confirmation link to the new address. The
+// account keeps its current email until the link is opened.
+func (u *userImpl) RequestEmailChange(ctx context.Context, userId int, password, email, userAgent, ipAddress string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return ErrInvalidEmail
+	}
+
+	user, err := u.dbCrypto.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if user.Email == email {
+		return ErrInvalidEmail
+	}
+
+	err = u.verifyCurrentPassword(ctx, *user, password, userAgent, ipAddress)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.dbCrypto.GetUserByEmail(ctx, email)
+	if err == nil {
+		return ErrEmailAlreadyRegistered
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	link, err := u.createActionLink(ctx, *user, changeEmailPurpose, email, u.accountConfig.VerifyEmailTokenDuration, "/change-email")
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: changeEmailSubject,
+		Body:    fmt.Sprintf(changeEmailBody, link, u.accountConfig.VerifyEmailTokenDuration),
+	})
+}
+
+// ConfirmEmailChange moves the account to the address the token was sent to
+// and lets the previous address know.
+func (u *userImpl) ConfirmEmailChange(ctx context.Context, token string) error {
+	claims, err := auth.ParseActionToken(token, changeEmailPurpose)
+	if err != nil {
+		return ErrInvalidActionToken
+	}
+
+	email, _ := claims["new_email"].(string)
+	if email == "" {
+		return ErrInvalidActionToken
+	}
+
+	user, err := u.useActionToken(ctx, token, changeEmailPurpose)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.dbCrypto.GetUserByEmail(ctx, email)
+	if err == nil {
+		return ErrEmailAlreadyRegistered
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	err = u.dbCrypto.UpdateUserEmail(ctx, user.ID, email)
+	if err != nil {
+		return err
+	}
+
+	err = u.dbCrypto.InsertAuditEvent(ctx, model.AuditEvent{
+		UserId:    user.ID,
+		Event:     emailChangedAuditEvent,
+		Detail:    fmt.Sprintf("from %s to %s", user.Email, email),
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: emailChangedSubject,
+		Body:    fmt.Sprintf(emailChangedBody, email),
+	})
+	if err != nil {
+		log.PrintLogErr(ctx, failedToSendEmailErrorMsg, err)
+	}
+	return nil
+}
+
+// DeleteAccount removes the user and everything stored about them.
+func (u *userImpl) DeleteAccount(ctx context.Context, userId int, password, userAgent, ipAddress string) error {
+	user, err := u.dbCrypto.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	err = u.verifyCurrentPassword(ctx, *user, password, userAgent, ipAddress)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := u.dbCrypto.GetUserSessionsByUserId(ctx, user.ID, 0)
+	if err != nil {
+		return err
+	}
+
+	err = u.dbCrypto.DeleteUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range *sessions {
+		err = u.tokenStore.Remove(ctx, session.AccessTokenHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return u.dbCrypto.DeleteLoginAttempt(ctx, accountLoginAttemptKey(user.Email))
+}
+
+// SetUserCurrency stores the currency the user's assets are priced in by
+// default, given by id or symbol, and returns its id. An empty currency goes
+// back to the target currency.
+func (u *userImpl) SetUserCurrency(ctx context.Context, userId int, currency string) (*string, error) {
+	if currency != "" {
+		rate, ok, err := u.restCrypto.LookupCurrency(ctx, currency)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrCurrencyNotFound
+		}
+		currency = rate.Id
+	}
+
+	err := u.dbCrypto.UpdateUserCurrency(ctx, userId, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &currency, nil
+}
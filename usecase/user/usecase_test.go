@@ -0,0 +1,89 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michaelwongycn/crypto-tracker/domain/config"
+	"github.com/michaelwongycn/crypto-tracker/lib/auth"
+	"github.com/michaelwongycn/crypto-tracker/lib/db"
+	"github.com/michaelwongycn/crypto-tracker/lib/mailer"
+	"github.com/michaelwongycn/crypto-tracker/lib/oidc"
+	"github.com/michaelwongycn/crypto-tracker/lib/password"
+	"github.com/michaelwongycn/crypto-tracker/repository/cryptoDB"
+)
+
+const testPassword = "Passw0rd!x"
+
+// newTestDB migrates a fresh SQLite database in a temporary directory.
+// db.Connect opens a path relative to the working directory.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	name, err := filepath.Rel(wd, filepath.Join(t.TempDir(), "crypto"))
+	if err != nil {
+		t.Fatalf("Rel: %v", err)
+	}
+
+	database, err := db.Connect(5, name)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// newTestUserImpl builds the user usecase on a fresh database, with a cheap
+// password hasher and emails written to the log. oidcProvider may be nil.
+func newTestUserImpl(t *testing.T, oidcProvider *oidc.Provider) (*userImpl, *sql.DB) {
+	t.Helper()
+
+	err := auth.SetAuthConfig(config.JWTConfig{SecretKey: "test-secret", AccessTokenDuration: 15, RefreshTokenDuration: 60})
+	if err != nil {
+		t.Fatalf("SetAuthConfig: %v", err)
+	}
+
+	hasher, err := password.NewHasher(config.PasswordConfig{Algorithm: password.Bcrypt, Bcrypt: config.BcryptConfig{Cost: 4}})
+	if err != nil {
+		t.Fatalf("NewHasher: %v", err)
+	}
+
+	logMailer, err := mailer.NewMailer(config.MailerConfig{Driver: mailer.Log})
+	if err != nil {
+		t.Fatalf("NewMailer: %v", err)
+	}
+
+	database := newTestDB(t)
+	dbCrypto := cryptoDB.NewCryptoDBImpl(5, database)
+	usecase := NewUserImpl(dbCrypto, cryptoDB.NewTokenStore(5, database), nil, nil, hasher, logMailer, config.AccountConfig{}, config.LoginConfig{}, oidcProvider, 0, 60)
+	return usecase.(*userImpl), database
+}
+
+func registerTestUser(t *testing.T, u *userImpl, email string, verified bool) int {
+	t.Helper()
+
+	ctx := context.Background()
+	err := u.Register(ctx, email, testPassword)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	user, err := u.dbCrypto.GetUserByEmail(ctx, email)
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if verified {
+		err = u.dbCrypto.UpdateUserEmailVerified(ctx, user.ID, true)
+		if err != nil {
+			t.Fatalf("UpdateUserEmailVerified: %v", err)
+		}
+	}
+	return user.ID
+}